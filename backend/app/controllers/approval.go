@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetApprovals(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	return c.Status(200).JSON(utils.ApprovalQueue(db, tokenMeta))
+}
+
+func GetPersonApprovals(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	approvals := []models.Approval{}
+	db.Where("person_id = ?", person.ID).Order("id DESC").Find(&approvals)
+	return c.Status(200).JSON(approvals)
+}
+
+// PostApproval approves the conclusion or returns it to the officer. A
+// returned conclusion needs a comment.
+func PostApproval(c *fiber.Ctx) error {
+	decision := c.Params("decision")
+	if decision != "approve" && decision != "return" {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	payload := struct {
+		Comment string `json:"comment"`
+	}{}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	payload.Comment = strings.TrimSpace(payload.Comment)
+	if decision == "return" && payload.Comment == "" {
+		return validationError(c, utils.FieldErrors{"comment": "не указана причина возврата"})
+	}
+
+	db := database.OpenDb()
+	var approval models.Approval
+	db.First(&approval, c.Params("id"))
+	if approval.ID == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := utils.DecideApproval(db, &approval, decision == "approve", payload.Comment, tokenMeta)
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	utils.NotifyWatchers(db, approval.PersonID, "approval", decision, tokenMeta)
+	return c.Status(200).JSON(approval)
+}
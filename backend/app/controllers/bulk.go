@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func PostBulk(c *fiber.Ctx) error {
+	var request utils.BulkRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	db := database.OpenDb()
+	if errs := utils.ValidateBulk(db, request); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	ids, err := utils.BulkTargets(db, request, tokenMeta)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+
+	params, _ := json.Marshal(request)
+	job := models.BulkJob{
+		Action:   request.Action,
+		Params:   string(params),
+		State:    "queued",
+		Total:    len(ids),
+		UserID:   tokenMeta.UserID,
+		UserName: tokenMeta.FullName,
+	}
+	db.Create(&job)
+
+	if len(ids) > utils.BulkSyncLimit() {
+		background := job
+		go utils.RunBulkBackground(database.OpenDb(), &background, request, ids, tokenMeta)
+		return c.Status(202).JSON(job)
+	}
+	results := utils.RunBulk(db, &job, request, ids, tokenMeta)
+	return c.Status(200).JSON(fiber.Map{"job": job, "results": results})
+}
+
+func GetBulkJob(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var job models.BulkJob
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	db.Where("id = ? AND user_id = ?", c.Params("job_id"), tokenMeta.UserID).First(&job)
+	if job.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	return c.Status(200).JSON(job)
+}
+
+func GetFilters(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var filters []models.SavedFilter
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	db.Where("user_id = ?", tokenMeta.UserID).Order("name").Find(&filters)
+	return c.Status(200).JSON(filters)
+}
+
+func PostFilter(c *fiber.Ctx) error {
+	var filter models.SavedFilter
+	if err := c.BodyParser(&filter); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	if filter.Name == "" {
+		return validationError(c, utils.FieldErrors{"name": "не указано название"})
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	filter.ID = 0
+	filter.UserID = tokenMeta.UserID
+
+	db := database.OpenDb()
+	db.Create(&filter)
+	return c.Status(201).JSON(filter)
+}
+
+func DeleteFilter(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	db.
+		Where("id = ? AND user_id = ?", c.Params("filter_id"), tokenMeta.UserID).
+		Delete(&models.SavedFilter{})
+	return c.Status(204).JSON("Filter deleted")
+}
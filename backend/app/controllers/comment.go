@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+// commentTarget resolves the person and the check of the thread. Trashed
+// persons are not found, so their threads are closed like their dossiers.
+func commentTarget(c *fiber.Ctx) (models.Person, uint, bool) {
+	db := database.OpenDb()
+	var person models.Person
+	var checkID uint
+
+	switch c.Params("item") {
+	case "person":
+		db.First(&person, c.Params("item_id"))
+	case "check":
+		var check models.Check
+		db.First(&check, c.Params("item_id"))
+		if check.ID == 0 {
+			return person, 0, false
+		}
+		checkID = check.ID
+		db.First(&person, check.PersonID)
+	}
+	return person, checkID, person.ID != 0
+}
+
+func GetComments(c *fiber.Ctx) error {
+	person, checkID, ok := commentTarget(c)
+	if !ok {
+		return c.Status(404).JSON("Not found")
+	}
+
+	db := database.OpenDb()
+	var comments []models.Comment
+	db.
+		Where("person_id = ? AND check_id = ?", person.ID, checkID).
+		Order("created_at").
+		Find(&comments)
+	return c.Status(200).JSON(comments)
+}
+
+func PostComment(c *fiber.Ctx) error {
+	person, checkID, ok := commentTarget(c)
+	if !ok {
+		return c.Status(404).JSON("Not found")
+	}
+
+	payload := struct {
+		Text string `json:"text" form:"text"`
+	}{}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	files := []string{}
+	form, err := c.MultipartForm()
+	if err == nil {
+		for _, file := range form.File["files"] {
+			files = append(files, file.Filename)
+		}
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" && len(files) == 0 {
+		return validationError(c, utils.FieldErrors{"text": "пустой комментарий"})
+	}
+
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	comment := models.Comment{
+		PersonID: person.ID,
+		CheckID:  checkID,
+		Text:     payload.Text,
+		UserID:   tokenMeta.UserID,
+		UserName: tokenMeta.FullName,
+	}
+	db.Create(&comment)
+
+	if len(files) > 0 {
+		if person.PathToDocs == "" {
+			person.PathToDocs = makeFolder(person.FullName, person.ID)
+			db.Model(&person).Update("path_to_docs", person.PathToDocs)
+		}
+		commentPath := utils.CommentPath(person, comment.ID)
+		if err := os.MkdirAll(commentPath, 0755); err != nil {
+			return c.Status(500).JSON(err)
+		}
+		names := []string{}
+		for _, file := range form.File["files"] {
+			name := filepath.Base(file.Filename)
+			if err := c.SaveFile(file, filepath.Join(commentPath, name)); err != nil {
+				return c.Status(500).JSON(err)
+			}
+			names = append(names, name)
+		}
+		attachments, _ := json.Marshal(names)
+		comment.Attachments = string(attachments)
+		db.Model(&comment).Update("attachments", comment.Attachments)
+	}
+
+	utils.NotifyMentions(db, comment, "", person)
+	utils.NotifyWatchers(db, person.ID, "comment", "create", tokenMeta)
+	return c.Status(201).JSON(comment)
+}
+
+func PatchComment(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var comment models.Comment
+	var person models.Person
+
+	db.First(&comment, c.Params("comment_id"))
+	db.First(&person, comment.PersonID)
+	if comment.ID == 0 || person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if !utils.CanEditComment(comment, tokenMeta) {
+		return c.Status(403).JSON(fiber.Map{"error": true, "msg": "чужой комментарий"})
+	}
+
+	payload := struct {
+		Text string `json:"text"`
+	}{}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	if payload.Text == "" && comment.Attachments == "" {
+		return validationError(c, utils.FieldErrors{"text": "пустой комментарий"})
+	}
+
+	previous := comment.Text
+	comment.Text = payload.Text
+	db.Model(&comment).Update("text", comment.Text)
+	utils.NotifyMentions(db, comment, previous, person)
+	utils.NotifyWatchers(db, person.ID, "comment", "update", tokenMeta)
+	return c.Status(200).JSON(comment)
+}
+
+func DeleteComment(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var comment models.Comment
+	var person models.Person
+
+	db.First(&comment, c.Params("comment_id"))
+	db.First(&person, comment.PersonID)
+	if comment.ID == 0 || person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if !utils.CanEditComment(comment, tokenMeta) {
+		return c.Status(403).JSON(fiber.Map{"error": true, "msg": "чужой комментарий"})
+	}
+
+	if err := utils.DeleteComment(db, comment, person); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	utils.NotifyWatchers(db, person.ID, "comment", "delete", tokenMeta)
+	return c.Status(204).JSON("Comment deleted")
+}
+
+func GetCommentFile(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var comment models.Comment
+	var person models.Person
+
+	db.First(&comment, c.Params("comment_id"))
+	db.First(&person, comment.PersonID)
+	if comment.ID == 0 || person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	file, err := url.PathUnescape(c.Params("file"))
+	if err != nil {
+		return c.Status(404).JSON("Not found")
+	}
+	for _, name := range utils.CommentAttachments(comment) {
+		if name == file {
+			return c.Download(filepath.Join(utils.CommentPath(person, comment.ID), name))
+		}
+	}
+	return c.Status(404).JSON("Not found")
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetPersonConflicts(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	return c.Status(200).JSON(utils.PersonConflicts(db, person.ID))
+}
+
+func GetConflictReport(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	return c.Status(200).JSON(utils.ConflictReport(db, c.QueryBool("staff")))
+}
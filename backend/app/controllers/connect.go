@@ -7,6 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"backend/app/models"
+	"backend/pkg/utils"
 	"backend/platform/database"
 )
 
@@ -68,6 +69,7 @@ func PostConnect(c *fiber.Ctx) error {
 	}
 
 	db.Create(&connect)
+	utils.IndexConnection(db, connect.ID)
 
 	return c.Status(200).JSON("Created")
 }
@@ -84,6 +86,9 @@ func PatchConnect(c *fiber.Ctx) error {
 		Model(&connect).
 		Where("id = ?", c.Params("item_id")).
 		Updates(&connect)
+	if id, err := strconv.ParseUint(c.Params("item_id"), 10, 64); err == nil {
+		utils.IndexConnection(db, uint(id))
+	}
 
 	return c.Status(200).JSON("Updated")
 }
@@ -93,6 +98,9 @@ func DeleteConnect(c *fiber.Ctx) error {
 	var connect models.Connection
 
 	db.Delete(&connect, c.Params("item_id"))
+	if id, err := strconv.ParseUint(c.Params("item_id"), 10, 64); err == nil {
+		utils.IndexConnection(db, uint(id))
+	}
 
 	return c.Status(200).JSON("Deleted")
 }
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetDocumentReport(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	status := c.Query("status")
+	switch status {
+	case "", utils.DocumentInvalid, utils.DocumentExpiring, utils.DocumentUnknown:
+	default:
+		return validationError(c, utils.FieldErrors{"status": "неизвестное состояние документа"})
+	}
+	officer := c.Query("officer", tokenMeta.FullName)
+	if officer == "all" {
+		officer = ""
+	}
+
+	alerts := []utils.DocumentAlert{}
+	for _, alert := range utils.DocumentAlerts(db) {
+		if (status == "" || alert.Validity == status) && (officer == "" || alert.Officer == officer) {
+			alerts = append(alerts, alert)
+		}
+	}
+	return c.Status(200).JSON(alerts)
+}
+
+func GetDocumentRules(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var rules []models.DocumentRule
+	db.Order("view").Find(&rules)
+	return c.Status(200).JSON(rules)
+}
+
+func PostDocumentRule(c *fiber.Ctx) error {
+	var rule models.DocumentRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	if errs := utils.ValidateDocumentRule(&rule); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	db := database.OpenDb()
+	rule.ID = 0
+	err := db.
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "view"}}, UpdateAll: true}).
+		Create(&rule).
+		Error
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(rule)
+}
+
+func DeleteDocumentRule(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	db.Delete(&models.DocumentRule{}, c.Params("id"))
+	return c.Status(204).JSON("Rule deleted")
+}
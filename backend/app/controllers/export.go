@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetAnalytics(c *fiber.Ctx) error {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	err := utils.ExportAnalytics(database.OpenDb(), func(name string) (io.Writer, error) {
+		return archive.Create(name)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	if err := archive.Close(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(
+		"attachment; filename=\"analytics-%s.zip\"", time.Now().Format("2006-01-02"),
+	))
+	return c.Status(200).Send(buf.Bytes())
+}
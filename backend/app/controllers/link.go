@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetPersonLinks(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	return c.Status(200).JSON(utils.PersonLinks(db, person.ID))
+}
+
+func GetLinkReport(c *fiber.Ctx) error {
+	threshold := c.QueryInt("more", utils.LinkReportThreshold())
+	if threshold < 1 {
+		return validationError(c, utils.FieldErrors{"more": "должно быть не меньше 1"})
+	}
+	db := database.OpenDb()
+	return c.Status(200).JSON(utils.LinkReport(db, threshold))
+}
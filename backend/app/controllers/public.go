@@ -11,11 +11,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 
 	"backend/app/models"
 	"backend/pkg/middlewares"
 	"backend/pkg/utils"
 	"backend/platform/database"
+	"backend/platform/encryption"
 )
 
 func GetClasses(c *fiber.Ctx) error {
@@ -37,6 +39,7 @@ func GetClasses(c *fiber.Ctx) error {
 func PostIndex(c *fiber.Ctx) error {
 	payload := struct {
 		Search string `json:"search"`
+		Tags   []uint `json:"tags"`
 	}{}
 	if err := c.BodyParser(&payload); err != nil {
 		log.Println(err)
@@ -48,6 +51,7 @@ func PostIndex(c *fiber.Ctx) error {
 	}
 
 	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
 	var persons []models.Person
 	var pagination = 16
 	var hasPrev, hasNext bool
@@ -58,6 +62,7 @@ func PostIndex(c *fiber.Ctx) error {
 		statusUpd := models.Status{}.GetID(utils.Statuses["update"])
 		statusRep := models.Status{}.GetID(utils.Statuses["repeat"])
 		db.
+			Scopes(utils.TaggedWith(payload.Tags, tokenMeta)).
 			Where("status_id = ? OR status_id = ? OR status_id = ?", statusNew, statusUpd, statusRep).
 			Find(&persons).
 			Limit(pagination).
@@ -77,11 +82,28 @@ func PostIndex(c *fiber.Ctx) error {
 			Where(models.Check{Officer: "current"}).
 			Find(&checks)
 	case "search":
+		digits := utils.NormalizeDigits(payload.Search)
+		snilsIndex, err := encryption.BlindIndex("snils", digits)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
+		innIndex, err := encryption.BlindIndex("inn", digits)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
 		db.
-			Where("fullname LIKE ?", "%"+payload.Search+"%").
-			Find(&persons).Limit(10).
+			Scopes(utils.TaggedWith(payload.Tags, tokenMeta)).
+			Where(
+				"search_name LIKE ? OR snils_index = ? OR inn_index = ? OR id IN (?)",
+				"%"+utils.SearchKey(payload.Search)+"%",
+				snilsIndex,
+				innIndex,
+				utils.PreviousNameQuery(db, payload.Search),
+			).
+			Order("search_name, birth_date").
+			Limit(10).
 			Offset(10 * (intPage - 1)).
-			Order("created_at desc")
+			Find(&persons)
 	default:
 		return nil
 	}
@@ -92,6 +114,9 @@ func PostIndex(c *fiber.Ctx) error {
 	if len(persons) == pagination {
 		hasNext = true
 	}
+	for i := range persons {
+		persons[i].Tags = utils.PersonTags(db, persons[i].ID, tokenMeta)
+	}
 	result, err := json.Marshal(persons)
 	if err != nil {
 		return c.Status(500).JSON(err)
@@ -104,18 +129,21 @@ func GetResume(c *fiber.Ctx) error {
 	var person models.Person
 
 	db.Where("id = ?", c.Params("person_id")).First(&person)
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
 
 	switch c.Params("action") {
 	case "status":
-		person.StatusID = models.Status{}.GetID(utils.Statuses["update"])
-		db.Save(&person)
+		err := utils.ChangeStatus(db, &person, "update", tokenMeta, "Статус сброшен")
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
 		return c.Status(200).JSON(person)
 
 	case "send":
-		status := models.Status{}
-		if person.StatusID == status.GetID(utils.Statuses["new"]) ||
-			person.StatusID == status.GetID(utils.Statuses["update"]) ||
-			person.StatusID == status.GetID(utils.Statuses["repeat"]) {
+		if utils.CanTransit(utils.StatusKey(person.StatusID), "finish") {
 
 			docs := models.Document{}
 			db.
@@ -155,521 +183,113 @@ func GetResume(c *fiber.Ctx) error {
 			if statusCode != 200 {
 				return c.Status(500).JSON(err)
 			} else {
-				person.StatusID = models.Status{}.GetID(utils.Statuses["finish"])
-				db.Save(&person)
+				err = utils.ChangeStatus(db, &person, "finish", tokenMeta, "Анкета отправлена")
+				if err != nil {
+					return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+				}
 				return c.Status(200).JSON(person)
 			}
 		}
 	}
+	person.Tags = utils.PersonTags(db, person.ID, tokenMeta)
+	person.Documents = utils.PersonDocuments(db, person)
 	return c.Status(200).JSON(person)
 }
 
-func DeleteResume(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var person models.Person
-
-	db.Where("id = ?", c.Params("person_id")).First(&person)
-	err := os.RemoveAll(person.PathToDocs)
-	if err != nil {
-		log.Println(err)
-	}
-	db.Delete(&person)
-
-	return c.Status(204).JSON("Person deleted")
-}
-
-func PostResume(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var person models.Person
-	var resume models.Person
-
-	err := c.BodyParser(&resume)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Where("fullname = ?, birthday = ?", c.Params("person_id"), c.Params("birthday")).
-		First(&person)
-
-	if person.ID == 0 {
-		resume.StatusID = models.Status{}.GetID(utils.Statuses["new"])
-		db.Create(&resume)
-		person.ID = resume.ID
-	} else {
-		resume.StatusID = models.Status{}.GetID(utils.Statuses["update"])
-		db.Save(&resume)
-	}
-	person.PathToDocs = makeFolder(person.FullName, person.ID)
-	db.Save(&person)
-
-	return c.Status(200).JSON(person.ID)
-}
-
-func makeFolder(fullname string, person_id uint) string {
-	path := filepath.Join(strings.ToUpper(string(fullname[0])), fmt.Sprintf("%d-%s", person_id, fullname))
-	basePath := os.Getenv("BASE_PATH")
-	url := filepath.Join(basePath, path)
-	stat, err := os.Stat(url)
-	if err == nil && !stat.IsDir() {
-		os.Mkdir(url, 0755)
-	}
-	return path
-}
-
-// Staffs Handlers
-func GetStaffs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var staffs []models.Staff
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&staffs)
-
-	return c.Status(200).JSON(staffs)
-}
-
-func PostStaffs(c *fiber.Ctx) error {
-
-	db := database.OpenDb()
-	var staff models.Staff
-
-	err := c.BodyParser(&staff)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	staff.PersonID = uint(itemID)
-	db.Create(&staff)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchStaffs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var staff models.Staff
-
-	err := c.BodyParser(&staff)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&staff).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&staff)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteStaffs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var staff models.Staff
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&staff)
-
-	db.Delete(&staff)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Documents Handlers
-func GetDocs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var documents []models.Document
-
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&documents)
-
-	return c.Status(200).JSON(documents)
-}
-
-func PostDocs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var document models.Document
-
-	err := c.BodyParser(&document)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	document.PersonID = uint(itemID)
-
-	db.Create(&document)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchDocs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var document models.Document
-
-	err := c.BodyParser(&document)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&document).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&document)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteDocs(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var document models.Document
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&document)
-
-	db.Delete(&document)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Address Handlers
-func GetAddress(c *fiber.Ctx) error {
-
-	db := database.OpenDb()
-	var address []models.Address
-
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&address)
-
-	return c.Status(200).JSON(address)
-}
-
-func PostAddress(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var address models.Address
-
-	err := c.BodyParser(&address)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	address.PersonID = uint(itemID)
-
-	db.Create(&address)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchAddress(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var address models.Address
-
-	err := c.BodyParser(&address)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&address).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&address)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteAddress(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var address models.Address
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&address)
-
-	db.Delete(&address)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Contact Handlers
-func GetContact(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var contact []models.Contact
-
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&contact)
-
-	return c.Status(200).JSON(contact)
-}
-
-func PostContact(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var contact models.Contact
-
-	err := c.BodyParser(&contact)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	contact.PersonID = uint(itemID)
-
-	db.Create(&contact)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchContact(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var contact models.Contact
-
-	err := c.BodyParser(&contact)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&contact).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&contact)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteContact(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var contact models.Contact
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&contact)
-
-	db.Delete(&contact)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Work Handlers
-func GetWorkplace(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var workplace []models.Workplace
-
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&workplace)
-
-	return c.Status(200).JSON(workplace)
-}
-
-func PostWorkplace(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var workplace models.Workplace
-
-	err := c.BodyParser(&workplace)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	workplace.PersonID = uint(itemID)
-
-	db.Create(&workplace)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchWorkplace(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var workplace models.Workplace
-
-	err := c.BodyParser(&workplace)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&workplace).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&workplace)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteWorkplace(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var workplace models.Workplace
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&workplace)
-
-	db.Delete(&workplace)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Affiliations Handlers
-func GetAffilation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var affilation []models.Affilation
-
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&affilation)
-
-	return c.Status(200).JSON(affilation)
-}
-
-func PostAffilation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var affilation models.Affilation
-
-	err := c.BodyParser(&affilation)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	affilation.PersonID = uint(itemID)
-
-	db.Create(&affilation)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchAffilation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var affilation models.Affilation
-
-	err := c.BodyParser(&affilation)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	db.
-		Model(&affilation).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&affilation)
-
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteAffilation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var affilation models.Affilation
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&affilation)
-
-	db.Delete(&affilation)
-
-	return c.Status(200).JSON("Deleted")
-}
-
-// Relationships Handlers
-func GetRelation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var relation []models.Relation
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		Find(&relation)
-
-	return c.Status(200).JSON(relation)
-}
-
-func PostRelation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var relation models.Relation
-
-	err := c.BodyParser(&relation)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	relation.PersonID = uint(itemID)
-	db.Create(&relation)
+func DeleteResume(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
 
-	reRelation := models.Relation{
-		View:     relation.View,
-		Relation: relation.ID,
-		PersonID: relation.PersonID,
+	db.Where("id = ?", c.Params("person_id")).First(&person)
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
 	}
 
-	db.Create(&reRelation)
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := utils.TrashPerson(db, &person, tokenMeta)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
 
-	return c.Status(201).JSON("Created")
+	return c.Status(204).JSON("Person deleted")
 }
 
-func PatchRelation(c *fiber.Ctx) error {
+func PostResume(c *fiber.Ctx) error {
 	db := database.OpenDb()
-	var relation models.Relation
+	var person models.Person
+	var resume models.Person
 
-	err := c.BodyParser(&relation)
+	err := c.BodyParser(&resume)
 	if err != nil {
 		return c.Status(500).JSON(err)
 	}
+	if errs := utils.ValidatePerson(&resume); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+	utils.NormalizePersonName(&resume)
+	person, err = utils.FindDuplicate(db, &resume)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
 
-	db.
-		Model(&relation).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&relation)
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if person.ID == 0 {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&resume).Error; err != nil {
+				return err
+			}
+			if err := utils.RecordVersion(tx, "person", nil, &resume, tokenMeta); err != nil {
+				return err
+			}
+			return utils.ChangeStatus(tx, &resume, "new", tokenMeta, "Анкета создана")
+		})
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
+	} else {
+		resume.ID = person.ID
+		resume.StatusID = person.StatusID
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&resume).Error; err != nil {
+				return err
+			}
+			if err := utils.RecordVersion(tx, "person", &person, &resume, tokenMeta); err != nil {
+				return err
+			}
+			return utils.ChangeStatus(tx, &resume, "update", tokenMeta, "Анкета обновлена")
+		})
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
+	}
+	person = resume
+	person.PathToDocs = makeFolder(person.FullName, person.ID)
+	db.Save(&person)
 
-	return c.Status(200).JSON("Updated")
+	return c.Status(200).JSON(person.ID)
 }
 
-func DeleteRelation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var relation models.Relation
-
-	db.
-		Where("item_id = ?", c.Params("item_id")).
-		First(&relation)
-
-	db.Delete(&relation)
+func validationError(c *fiber.Ctx, errs utils.FieldErrors) error {
+	return c.Status(400).JSON(fiber.Map{
+		"error":  true,
+		"msg":    "validation failed",
+		"fields": errs,
+	})
+}
 
-	return c.Status(200).JSON("Deleted")
+func makeFolder(fullname string, person_id uint) string {
+	letter := "_"
+	if runes := []rune(fullname); len(runes) > 0 {
+		letter = strings.ToUpper(string(runes[0]))
+	}
+	path := filepath.Join(letter, fmt.Sprintf("%d-%s", person_id, fullname))
+	basePath := os.Getenv("BASE_PATH")
+	url := filepath.Join(basePath, path)
+	_, err := os.Stat(url)
+	if os.IsNotExist(err) {
+		os.MkdirAll(url, 0755)
+	}
+	return path
 }
 
 // Check Handlers
@@ -683,23 +303,22 @@ func GetCheck(c *fiber.Ctx) error {
 
 		var person models.Person
 		db.First(&person, c.Params("item_id"))
-		model := models.Status{}
-		if person.StatusID == model.GetID(utils.Statuses["new"]) ||
-			person.StatusID == model.GetID(utils.Statuses["update"]) ||
-			person.StatusID == model.GetID(utils.Statuses["repeat"]) {
-
-			person.StatusID = model.GetID(utils.Statuses["manual"])
-			db.Save(&person)
-
-			itemIDStr := c.Params("item_id")
-			itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
+		if person.ID == 0 {
+			return c.Status(404).JSON("Not found")
+		}
+		if utils.StatusKey(person.StatusID) != "manual" {
+			check.Officer = tokenMeta.FullName
+			check.PersonID = person.ID
+			check.Deadline = utils.Deadline(db, "check", check.PersonID, time.Now())
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := utils.ChangeStatus(tx, &person, "manual", tokenMeta, "Начата проверка"); err != nil {
+					return err
+				}
+				return createCheck(tx, &check, tokenMeta)
+			})
 			if err != nil {
-				return c.Status(500).JSON(err)
+				return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
 			}
-
-			check.Officer = tokenMeta.FullName
-			check.PersonID = uint(itemID)
-			db.Create(&check)
 		}
 		return c.Status(200).JSON("Added")
 
@@ -725,10 +344,29 @@ func GetCheck(c *fiber.Ctx) error {
 		db.
 			Where("person_id = ?", c.Params("item_id")).
 			Find(&checks)
+		ids := []uint{}
+		for _, check := range checks {
+			ids = append(ids, check.ID)
+		}
+		items := utils.CheckItems(db, ids)
+		for i := range checks {
+			checks[i].Items = items[checks[i].ID]
+		}
 	}
 	return c.Status(404).JSON(&checks)
 }
 
+// createCheck stores a new check with the items of the current template.
+func createCheck(tx *gorm.DB, check *models.Check, tokenMeta *middlewares.TokenMetadata) error {
+	if err := tx.Create(check).Error; err != nil {
+		return err
+	}
+	if err := utils.SnapshotCheckTemplate(tx, check); err != nil {
+		return err
+	}
+	return utils.RecordVersion(tx, "check", nil, check, tokenMeta)
+}
+
 func PatchCheck(c *fiber.Ctx) error {
 	db := database.OpenDb()
 	var check models.Check
@@ -743,7 +381,13 @@ func PatchCheck(c *fiber.Ctx) error {
 		}
 		check.PersonID = uint(itemID)
 		check.Officer = tokenMeta.FullName
-		db.Create(&check)
+		check.Deadline = utils.Deadline(db, "check", check.PersonID, time.Now())
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return createCheck(tx, &check, tokenMeta)
+		})
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
 
 	} else {
 		newCheck := models.Check{}
@@ -752,23 +396,75 @@ func PatchCheck(c *fiber.Ctx) error {
 			return c.Status(500).JSON(err)
 		}
 		db.First(&check, c.Params("item_id"))
-		db.
-			Model(&check).
-			Where("item_id = ?", c.Params("item_id")).
-			Updates(&newCheck)
+		if check.ID == 0 {
+			return c.Status(404).JSON("Not found")
+		}
+
+		// Answers are saved to the items, the check keeps the other fields. A
+		// check without a conclusion is in progress and keeps the status of
+		// the person, a check that finishes needs answers to all required items.
+		conclusionID := newCheck.ConclusionID
+		if conclusionID == 0 {
+			conclusionID = check.ConclusionID
+		}
+		conclusion := utils.ConclusionKey(conclusionID)
+		if conclusionID != 0 && conclusion == "" {
+			return validationError(c, utils.FieldErrors{"conclusion_id": "неизвестное заключение"})
+		}
+		answers, errs := utils.ValidateCheckAnswers(db, check, newCheck.Items, !utils.InterimConclusion(conclusion))
+		if len(errs) > 0 {
+			return validationError(c, errs)
+		}
+		newCheck.Items = nil
 
+		// The status follows the conclusion. Denials and VIP conclusions wait
+		// for a second reviewer before finish.
 		var person models.Person
 		db.First(&person, check.PersonID)
+		status := ""
+		switch conclusion {
+		case "":
+		case "saved":
+			status = "save"
+		case "pfo":
+			status = "poligraf"
+		default:
+			status = "finish"
+		}
+		if status != "" && !utils.CanTransit(utils.StatusKey(person.StatusID), status) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": true,
+				"msg":   fmt.Sprintf("transition from %q to %q is not allowed", utils.StatusKey(person.StatusID), status),
+			})
+		}
 
-		model := models.Conclusion{}
-		if check.ConclusionID == model.GetID(utils.Conclusions["saved"]) {
-			person.StatusID = model.GetID(utils.Statuses["save"])
-		} else if check.ConclusionID == model.GetID(utils.Conclusions["pfo"]) {
-			person.StatusID = model.GetID(utils.Statuses["poligraf"])
-		} else {
-			person.StatusID = model.GetID(utils.Statuses["finish"])
+		tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			before := check
+			if err := tx.Model(&check).Updates(&newCheck).Error; err != nil {
+				return err
+			}
+			if err := utils.SaveCheckAnswers(tx, check, answers); err != nil {
+				return err
+			}
+			if err := tx.First(&check, before.ID).Error; err != nil {
+				return err
+			}
+			if err := utils.RecordVersion(tx, "check", &before, &check, tokenMeta); err != nil {
+				return err
+			}
+
+			switch status {
+			case "":
+				return nil
+			case "finish":
+				return utils.FinishCheck(tx, &person, tokenMeta, "Проверка обновлена")
+			}
+			return utils.ChangeStatus(tx, &person, status, tokenMeta, "Проверка обновлена")
+		})
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
 		}
-		db.Save(&person)
 	}
 	return c.Status(200).JSON("Updated")
 }
@@ -778,15 +474,31 @@ func DeleteCheck(c *fiber.Ctx) error {
 	var check models.Check
 
 	db.First(&check, c.Params("item_id"))
-
+	if check.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
 	var person models.Person
 	db.First(&person, check.PersonID)
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
 
-	db.Delete(&check)
-
-	var status models.Status
-	person.StatusID = status.GetID(utils.Statuses["update"])
-	db.Save(&person)
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("check_id = ?", check.ID).Delete(&models.CheckItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&check).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordVersion(tx, "check", &check, nil, tokenMeta); err != nil {
+			return err
+		}
+		return utils.ChangeStatus(tx, &person, "update", tokenMeta, "Проверка удалена")
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
 
 	return c.Status(204).JSON("Check deleted")
 }
@@ -817,8 +529,7 @@ func PostRobot(c *fiber.Ctx) error {
 
 	message := models.Message{}
 
-	status := models.Status{}
-	if cand.StatusID == status.GetID(utils.Statuses["robot"]) {
+	if utils.StatusKey(cand.StatusID) == "robot" {
 		var robot models.Robot
 
 		err := c.BodyParser(&robot)
@@ -831,7 +542,18 @@ func PostRobot(c *fiber.Ctx) error {
 			return c.Status(500).JSON(err)
 		}
 		robot.PersonID = uint(persId)
-		db.Create(&robot)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&robot).Error; err != nil {
+				return err
+			}
+			if err := utils.RecordVersion(tx, "robot", nil, &robot, tokenMeta); err != nil {
+				return err
+			}
+			return utils.ChangeStatus(tx, &cand, "reply", tokenMeta, "Получен результат робота")
+		})
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
 
 		robotPath := "/robots/" + cand.FullName + time.Now().Format("2006-01-02")
 		stat, err := os.Stat(robotPath)
@@ -850,9 +572,6 @@ func PostRobot(c *fiber.Ctx) error {
 		message.UserID = tokenMeta.UserID
 		db.Create(&message)
 
-		cand.StatusID = status.GetID(utils.Statuses["reply"])
-		db.Save(&cand)
-
 		return c.Status(200).JSON("Created")
 	} else {
 		message.MessageContent = "Результат проверки {candidate.fullname} не может быть записан"
@@ -862,181 +581,6 @@ func PostRobot(c *fiber.Ctx) error {
 	}
 }
 
-// Investigations Handlers
-func GetInvestigation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var investigations []models.Investigation
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&investigations)
-	return c.Status(200).JSON(investigations)
-}
-
-func PostInvestigation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var investigation models.Investigation
-	err := c.BodyParser(&investigation)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	investigation.PersonID = uint(itemID)
-	investigation.Officer = tokenMeta.FullName
-
-	db.Create(&investigation)
-	return c.Status(200).JSON("Created")
-}
-
-func PatchInvestigation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var investigation models.Investigation
-	db.First(&investigation, c.Params("item_id"))
-	newInvestigation := models.Investigation{}
-	err := c.BodyParser(&newInvestigation)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	db.
-		Model(&investigation).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&newInvestigation)
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteInvestigation(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var investigation models.Investigation
-	db.First(&investigation, c.Params("item_id"))
-	return c.Status(200).JSON("Deleted")
-}
-
-// Poligraf Handlers
-func GetPoligraf(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var poligraf []models.Poligraf
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&poligraf)
-	return c.Status(200).JSON(poligraf)
-}
-
-func PostPoligraf(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var poligraf models.Poligraf
-	err := c.BodyParser(&poligraf)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	poligraf.PersonID = uint(itemID)
-	poligraf.Officer = tokenMeta.FullName
-
-	db.Create(&poligraf)
-
-	var person models.Person
-	db.First(&person, poligraf.PersonID)
-
-	var status models.Status
-	if person.StatusID == status.GetID(utils.Statuses["poligraf"]) {
-		person.StatusID = status.GetID(utils.Statuses["finish"])
-		db.Save(&person)
-	}
-	return c.Status(200).JSON("Created")
-}
-
-func PatchPoligraf(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var poligraf models.Poligraf
-	db.First(&poligraf, c.Params("item_id"))
-	newPoligraf := models.Poligraf{}
-	err := c.BodyParser(&newPoligraf)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	db.
-		Model(&poligraf).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&newPoligraf)
-	return c.Status(200).JSON("Updated")
-}
-
-func DeletePoligraf(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var poligraf models.Poligraf
-	db.First(&poligraf, c.Params("item_id"))
-	return c.Status(200).JSON("Deleted")
-}
-
-// Inquiry Handlers
-func GetInquiry(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var inquiry []models.Inquiry
-	db.
-		Where("person_id = ?", c.Params("item_id")).
-		Find(&inquiry)
-	return c.Status(200).JSON(inquiry)
-}
-
-func PostInquiry(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var inquiry models.Inquiry
-	err := c.BodyParser(&inquiry)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-
-	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
-
-	itemIDStr := c.Params("item_id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 64)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	inquiry.PersonID = uint(itemID)
-	inquiry.Officer = tokenMeta.FullName
-
-	db.Create(&inquiry)
-
-	return c.Status(200).JSON("Created")
-}
-
-func PatchInquiry(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var inquiry models.Inquiry
-	db.First(&inquiry, c.Params("item_id"))
-	newInquiry := models.Inquiry{}
-	err := c.BodyParser(&newInquiry)
-	if err != nil {
-		return c.Status(500).JSON(err)
-	}
-	db.
-		Model(&inquiry).
-		Where("item_id = ?", c.Params("item_id")).
-		Updates(&newInquiry)
-	return c.Status(200).JSON("Updated")
-}
-
-func DeleteInquiry(c *fiber.Ctx) error {
-	db := database.OpenDb()
-	var inquiry models.Inquiry
-	db.First(&inquiry, c.Params("item_id"))
-	return c.Status(200).JSON("Deleted")
-}
-
 // Info Handler
 func PostInformation(c *fiber.Ctx) error {
 	type ResponseInformationBody struct {
@@ -1097,47 +641,15 @@ func PostFile(c *fiber.Ctx) error {
 		}
 
 		anketa := utils.JsonParse(tempPath)
-
-		db.
-			Where("fullname = ?, birthday = ?", anketa.Resume["fullname"], anketa.Resume["birthday"]).
-			First(&person)
-
-		if person.ID == 0 {
-			db.Table("people").Create(&anketa.Resume)
-			person.StatusID = models.Status{}.GetID(utils.Statuses["new"])
-
-		} else {
-			person.StatusID = models.Status{}.GetID(utils.Statuses["update"])
-			db.
-				Table("people").
-				Where("id = ?", person.ID).
-				Updates(&anketa.Resume)
-		}
-
-		anketa.Staff["person_id"] = string(rune(person.ID))
-		db.Table("staff").Create(&anketa.Staff)
-
-		anketa.Document["person_id"] = string(rune(person.ID))
-		db.Table("documents").Create(&anketa.Document)
-
-		for _, address := range anketa.Addresses {
-			address["person_id"] = string(rune(person.ID))
-			db.Table("addresses").Create(&address)
-		}
-
-		for _, workplace := range anketa.Workplaces {
-			workplace["person_id"] = string(rune(person.ID))
-			db.Table("workplaces").Create(&workplace)
-		}
-
-		for _, contact := range anketa.Contacts {
-			contact["person_id"] = string(rune(person.ID))
-			db.Table("contacts").Create(&contact)
+		if errs := utils.ValidateAnketa(anketa); len(errs) > 0 {
+			os.Remove(tempPath)
+			return validationError(c, errs)
 		}
 
-		for _, affiliation := range anketa.Affilations {
-			affiliation["person_id"] = string(rune(person.ID))
-			db.Table("affilations").Create(&affiliation)
+		tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+		person, status, err := importAnketa(db, anketa, tokenMeta)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
 		}
 
 		person.PathToDocs = makeFolder(person.FullName, person.ID)
@@ -1148,6 +660,12 @@ func PostFile(c *fiber.Ctx) error {
 		}
 		db.Save(&person)
 
+		err = utils.ChangeStatus(db, &person, status, tokenMeta, "Загружена анкета")
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+		}
+		return c.Status(200).JSON(person.ID)
+
 	} else {
 		form, err := c.MultipartForm()
 		if err != nil {
@@ -1157,7 +675,7 @@ func PostFile(c *fiber.Ctx) error {
 
 		db.Table("people").Where("id = ?", c.Params("item_id")).First(&person)
 		actionPath := filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs, c.Params("action"))
-		err = os.Mkdir(actionPath, 0755)
+		err = os.MkdirAll(actionPath, 0755)
 		if err != nil {
 			return c.Status(500).JSON(err)
 		}
@@ -1174,7 +692,183 @@ func PostFile(c *fiber.Ctx) error {
 				}
 			}
 		}
+		tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+		utils.NotifyWatchers(db, person.ID, "file", "create", tokenMeta)
 	}
 
 	return c.Status(200).JSON(person.ID)
 }
+
+// importAnketa stores the parsed anketa as a new person or as an update of the
+// same person found by SNILS, INN or name and birth date.
+func importAnketa(db *gorm.DB, anketa utils.Anketa, tokenMeta *middlewares.TokenMetadata) (models.Person, string, error) {
+	var person models.Person
+	resume := models.Person{
+		Surname:          anketa.Resume["surname"],
+		FirstName:        anketa.Resume["firstname"],
+		Patronymic:       anketa.Resume["patronymic"],
+		PreviousFullName: anketa.Resume["previous"],
+		BirthPlace:       anketa.Resume["birthplace"],
+		Citizen:          anketa.Resume["citizen"],
+		ExCitizen:        anketa.Resume["exCitizen"],
+		MaritalStatus:    anketa.Resume["marital"],
+		Inn:              anketa.Resume["inn"],
+		Snils:            anketa.Resume["snils"],
+	}
+	birthday, _ := utils.ParseDate(anketa.Resume["birthday"])
+	resume.BirthDate = models.NewDate(birthday)
+	utils.NormalizePersonName(&resume)
+
+	nameChanges := []*models.NameChange{}
+	previous := []string{}
+	for _, change := range anketa.NameChanges {
+		record := &models.NameChange{
+			Surname:   change.LastNameBeforeChange,
+			FirstName: change.FirstNameBeforeChange,
+			Year:      change.YearOfChange,
+			Document:  change.NameChangeDocument,
+			Reason:    change.Reason,
+		}
+		if !change.HasNoMidNameBeforeChange {
+			record.Patronymic = resume.Patronymic
+		}
+		if record.Surname == "" {
+			record.Surname = resume.Surname
+		}
+		if record.FirstName == "" {
+			record.FirstName = resume.FirstName
+		}
+		utils.NormalizeNameChange(record)
+		nameChanges = append(nameChanges, record)
+		previous = append(previous, record.SearchName)
+	}
+	person, err := utils.FindDuplicate(db, &resume, previous...)
+	if err != nil {
+		return person, "", err
+	}
+
+	status := "update"
+	if person.ID == 0 {
+		status = "new"
+		if err := db.Create(&resume).Error; err != nil {
+			return person, status, err
+		}
+		person = resume
+		if err := utils.RecordVersion(db, "person", nil, &person, tokenMeta); err != nil {
+			return person, status, err
+		}
+	} else {
+		before := person
+		if err := db.Model(&person).Updates(&resume).Error; err != nil {
+			return person, status, err
+		}
+		if err := db.First(&person, person.ID).Error; err != nil {
+			return person, status, err
+		}
+		if err := utils.RecordVersion(db, "person", &before, &person, tokenMeta); err != nil {
+			return person, status, err
+		}
+	}
+
+	type anketaRecord struct {
+		item  string
+		value interface{}
+	}
+	records := []anketaRecord{
+		{"staff", &models.Staff{
+			Position:   anketa.Staff["position"],
+			Department: anketa.Staff["department"],
+			PersonID:   person.ID,
+		}},
+	}
+
+	issue, _ := utils.ParseDate(anketa.Document["issue"])
+	records = append(records, anketaRecord{"document", &models.Document{
+		View:     anketa.Document["view"],
+		Series:   anketa.Document["series"],
+		Number:   anketa.Document["number"],
+		Agency:   anketa.Document["agency"],
+		Issue:    issue,
+		PersonID: person.ID,
+	}})
+
+	for _, address := range anketa.Addresses {
+		record := &models.Address{
+			View:     address["view"],
+			Address:  address["address"],
+			PersonID: person.ID,
+		}
+		utils.NormalizeAddressRecord(db, record)
+		records = append(records, anketaRecord{"address", record})
+	}
+
+	for _, workplace := range anketa.Workplaces {
+		start, _ := utils.ParsePeriodDate(workplace.BeginDate, false)
+		end, _ := utils.ParsePeriodDate(workplace.EndDate, true)
+		record := &models.Workplace{
+			Workplace: workplace.Name,
+			Address:   workplace.Address,
+			Position:  workplace.Position,
+			Reason:    workplace.FireReason,
+			StartDate: models.NewDate(start),
+			EndDate:   models.NewDate(end),
+			Current:   workplace.CurrentJob,
+			PersonID:  person.ID,
+		}
+		utils.ValidateWorkplace(record)
+		records = append(records, anketaRecord{"workplace", record})
+	}
+
+	for _, change := range nameChanges {
+		var count int64
+		db.
+			Model(&models.NameChange{}).
+			Where("person_id = ? AND search_name = ?", person.ID, change.SearchName).
+			Count(&count)
+		if count == 0 {
+			change.PersonID = person.ID
+			records = append(records, anketaRecord{"name_change", change})
+		}
+	}
+
+	for _, education := range anketa.Educations {
+		record := &models.Education{
+			View:        education.EducationType,
+			Institution: education.InstitutionName,
+			Specialty:   education.Specialty,
+			BeginYear:   education.BeginYear,
+			EndYear:     education.EndYear,
+			PersonID:    person.ID,
+		}
+		utils.ValidateEducation(record)
+		records = append(records, anketaRecord{"education", record})
+	}
+
+	for _, contact := range anketa.Contacts {
+		records = append(records, anketaRecord{"contact", &models.Contact{
+			View:     contact["view"],
+			Contact:  contact["contact"],
+			PersonID: person.ID,
+		}})
+	}
+
+	for _, affilation := range anketa.Affilations {
+		records = append(records, anketaRecord{"affilation", &models.Affilation{
+			View:     affilation["view"],
+			Name:     affilation["name"],
+			Position: affilation["position"],
+			Inn:      affilation["inn"],
+			PersonID: person.ID,
+		}})
+	}
+
+	for _, record := range records {
+		if err := db.Create(record.value).Error; err != nil {
+			return person, status, err
+		}
+		if err := utils.RecordVersion(db, record.item, nil, record.value, tokenMeta); err != nil {
+			return person, status, err
+		}
+	}
+	return person, status, nil
+}
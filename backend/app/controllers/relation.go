@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+var Relations = Resource[models.Relation]{
+	Item: "relation",
+	Validate: func(relation *models.Relation) utils.FieldErrors {
+		return utils.ValidateRelation(database.OpenDb(), relation)
+	},
+	Merge: func(stored *models.Relation, update *models.Relation) {
+		// The record is validated as a whole, so a duplicate check skips it.
+		update.ID = stored.ID
+		update.PersonID = stored.PersonID
+		update.PairID = stored.PairID
+		if update.Kind == "" {
+			update.Kind = stored.Kind
+		}
+		if update.RelatedID == 0 {
+			update.RelatedID = stored.RelatedID
+		}
+	},
+	Created: utils.PairRelation,
+	Updated: func(db *gorm.DB, before *models.Relation, relation *models.Relation, user *middlewares.TokenMetadata) error {
+		if before.RelatedID != relation.RelatedID && before.PairID != 0 {
+			if err := utils.UnpairRelation(db, before, user); err != nil {
+				return err
+			}
+			relation.PairID = 0
+		}
+		return utils.PairRelation(db, relation, user)
+	},
+	Deleted: utils.UnpairRelation,
+}
+
+func GetGraph(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	depth, err := strconv.Atoi(c.Query("depth", "2"))
+	if err != nil || depth < 1 || depth > utils.MaxGraphDepth {
+		return validationError(c, utils.FieldErrors{
+			"depth": "глубина должна быть от 1 до " + strconv.Itoa(utils.MaxGraphDepth),
+		})
+	}
+	return c.Status(200).JSON(utils.RelationGraph(db, person.ID, depth))
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetReportTemplates(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var templates []models.ReportTemplate
+	db.Order("id DESC").Find(&templates)
+	return c.Status(200).JSON(fiber.Map{
+		"templates":    templates,
+		"placeholders": utils.ReportPlaceholders(),
+	})
+}
+
+// PostReportTemplate stores the uploaded DOCX template under its checksum.
+func PostReportTemplate(c *fiber.Ctx) error {
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return validationError(c, utils.FieldErrors{"name": "не указано название шаблона"})
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		return validationError(c, utils.FieldErrors{"file": "не выбран файл шаблона"})
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return c.Status(500).JSON(err)
+	}
+	if _, err := utils.DocxParagraphs(content); err != nil {
+		return validationError(c, utils.FieldErrors{"file": "файл не является документом DOCX"})
+	}
+
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	if err := os.MkdirAll(utils.ReportTemplatesPath(), 0755); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	fileName := checksum + ".docx"
+	if err := os.WriteFile(filepath.Join(utils.ReportTemplatesPath(), fileName), content, 0644); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	template := models.ReportTemplate{
+		Name:       name,
+		FileName:   fileName,
+		Checksum:   checksum,
+		UploadedBy: tokenMeta.FullName,
+	}
+	if err := db.Create(&template).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(template)
+}
+
+// DeleteReportTemplate keeps the file while another template uses it.
+func DeleteReportTemplate(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var template models.ReportTemplate
+	db.First(&template, c.Params("id"))
+	if template.ID == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	db.Delete(&template)
+
+	var count int64
+	db.Model(&models.ReportTemplate{}).Where("file_name = ?", template.FileName).Count(&count)
+	if count == 0 {
+		os.Remove(filepath.Join(utils.ReportTemplatesPath(), template.FileName))
+	}
+	return c.Status(204).JSON("Template deleted")
+}
+
+func GetReports(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	var reports []models.Report
+	db.Where("person_id = ?", person.ID).Order("id DESC").Find(&reports)
+	return c.Status(200).JSON(reports)
+}
+
+// PostReport generates the report on the latest concluded check of the person
+// in the formats of the format query, both DOCX and PDF by default.
+func PostReport(c *fiber.Ctx) error {
+	formats := []string{}
+	for _, format := range strings.Split(c.Query("format", utils.ReportDocx+","+utils.ReportPdf), ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format != utils.ReportDocx && format != utils.ReportPdf {
+			return validationError(c, utils.FieldErrors{"format": "формат должен быть docx или pdf"})
+		}
+		if !slices.Contains(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+	templateID := c.QueryInt("template", 0)
+	if templateID < 0 {
+		return validationError(c, utils.FieldErrors{"template": "неверный шаблон"})
+	}
+
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	if person.PathToDocs == "" {
+		person.PathToDocs = makeFolder(person.FullName, person.ID)
+		db.Model(&person).Update("path_to_docs", person.PathToDocs)
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	reports, err := utils.GenerateReport(db, person, uint(templateID), formats, tokenMeta.FullName)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	utils.NotifyWatchers(db, person.ID, "report", "create", tokenMeta)
+	return c.Status(201).JSON(reports)
+}
+
+func GetReportFile(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var report models.Report
+	var person models.Person
+	db.Where("id = ? AND person_id = ?", c.Params("id"), c.Params("person_id")).First(&report)
+	db.First(&person, report.PersonID)
+	if report.ID == 0 || person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	return c.Download(filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs, report.FileName))
+}
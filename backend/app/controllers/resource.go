@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+// Resource serves the CRUD routes of a record that belongs to a person. List
+// and Create take the person id as item_id, Update and Delete take the id of
+// the record. T must have ID and PersonID fields.
+type Resource[T any] struct {
+	// Item is the name of the record in the version history.
+	Item string
+	// Validate checks and normalizes the record before it is saved.
+	Validate func(item *T) utils.FieldErrors
+	// Normalize fills the fields derived from the sent ones, like the parts
+	// of an address, before a record is saved.
+	Normalize func(db *gorm.DB, item *T)
+	// Prepare fills server side fields of a new record.
+	Prepare func(db *gorm.DB, item *T, user *middlewares.TokenMetadata)
+	// Merge adjusts the update, a copy of the stored record with the sent
+	// fields applied, before it is validated.
+	Merge func(stored *T, update *T)
+	// Created runs side effects after a record was created.
+	Created func(db *gorm.DB, item *T, user *middlewares.TokenMetadata) error
+	// Updated runs side effects after a record was updated.
+	Updated func(db *gorm.DB, before *T, item *T, user *middlewares.TokenMetadata) error
+	// Deleted runs side effects after a record was deleted.
+	Deleted func(db *gorm.DB, item *T, user *middlewares.TokenMetadata) error
+	// Loaded fills computed fields of the listed records.
+	Loaded func(db *gorm.DB, person models.Person, items []T)
+}
+
+// Register adds the resource routes to the router.
+func (r Resource[T]) Register(router fiber.Router) {
+	router.Get("/", r.List)
+	router.Post("/", r.Create)
+	router.Patch("/", r.Update)
+	router.Delete("/", r.Delete)
+}
+
+func (r Resource[T]) List(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("item_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	items := []T{}
+	db.Where("person_id = ?", person.ID).Order("id").Find(&items)
+	if r.Loaded != nil {
+		r.Loaded(db, person, items)
+	}
+	return c.Status(200).JSON(items)
+}
+
+func (r Resource[T]) Create(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("item_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	item := new(T)
+	if err := c.BodyParser(item); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	setResourceField(item, "ID", 0)
+	setResourceField(item, "PersonID", person.ID)
+	if r.Validate != nil {
+		if errs := r.Validate(item); len(errs) > 0 {
+			return validationError(c, errs)
+		}
+	}
+	if r.Normalize != nil {
+		r.Normalize(db, item)
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if r.Prepare != nil {
+		r.Prepare(db, item, tokenMeta)
+	}
+	// The side effects run in the same transaction, so a rejected status
+	// change does not leave the record behind.
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordVersion(tx, r.Item, nil, item, tokenMeta); err != nil {
+			return err
+		}
+		if r.Created != nil {
+			return r.Created(tx, item, tokenMeta)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(item)
+}
+
+func (r Resource[T]) Update(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	stored, ok := r.find(db, c.Params("item_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	// The body is parsed over a copy of the stored record, so the fields left
+	// out keep their values and the ones sent may be cleared.
+	update := new(T)
+	*update = *stored
+	if err := c.BodyParser(update); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	setResourceField(update, "ID", resourceField(stored, "ID"))
+	setResourceField(update, "PersonID", resourceField(stored, "PersonID"))
+	if r.Merge != nil {
+		r.Merge(stored, update)
+	}
+	if r.Validate != nil {
+		if errs := r.Validate(update); len(errs) > 0 {
+			return validationError(c, errs)
+		}
+	}
+	if r.Normalize != nil {
+		r.Normalize(db, update)
+	}
+
+	before := *stored
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(stored).
+			Select("*").
+			Omit("id", "person_id", "created_at", clause.Associations).
+			Updates(update).
+			Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(stored, resourceField(&before, "ID")).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordVersion(tx, r.Item, &before, stored, tokenMeta); err != nil {
+			return err
+		}
+		if r.Updated != nil {
+			return r.Updated(tx, &before, stored, tokenMeta)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(stored)
+}
+
+func (r Resource[T]) Delete(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	stored, ok := r.find(db, c.Params("item_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(stored).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordVersion(tx, r.Item, stored, nil, tokenMeta); err != nil {
+			return err
+		}
+		if r.Deleted != nil {
+			return r.Deleted(tx, stored, tokenMeta)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(204).JSON("Deleted")
+}
+
+// find loads the record if its person is still in the registry.
+func (r Resource[T]) find(db *gorm.DB, id string) (*T, bool) {
+	item := new(T)
+	if err := db.First(item, id).Error; err != nil {
+		return nil, false
+	}
+	personID := strconv.FormatUint(uint64(resourceField(item, "PersonID")), 10)
+	if _, ok := resourcePerson(db, personID); !ok {
+		return nil, false
+	}
+	return item, true
+}
+
+// resourcePerson loads the owner of the records. Trashed persons are not found.
+func resourcePerson(db *gorm.DB, id string) (models.Person, bool) {
+	var person models.Person
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return person, false
+	}
+	db.First(&person, id)
+	return person, person.ID != 0
+}
+
+func resourceField(item interface{}, name string) uint {
+	return uint(reflect.ValueOf(item).Elem().FieldByName(name).Uint())
+}
+
+func setResourceField(item interface{}, name string, value uint) {
+	reflect.ValueOf(item).Elem().FieldByName(name).SetUint(uint64(value))
+}
+
+var Staffs = Resource[models.Staff]{Item: "staff"}
+
+var Documents = Resource[models.Document]{
+	Item:     "document",
+	Validate: utils.ValidateDocument,
+	Merge: func(stored *models.Document, update *models.Document) {
+		if update.View == "" {
+			update.View = stored.View
+		}
+	},
+	Loaded: func(db *gorm.DB, person models.Person, documents []models.Document) {
+		utils.SetDocumentValidity(db, documents, person.BirthDate.Time)
+	},
+}
+
+var Addresses = Resource[models.Address]{
+	Item:      "address",
+	Normalize: utils.NormalizeAddressRecord,
+}
+
+var Contacts = Resource[models.Contact]{Item: "contact"}
+
+var Workplaces = Resource[models.Workplace]{
+	Item:     "workplace",
+	Validate: utils.ValidateWorkplace,
+}
+
+var NameChanges = Resource[models.NameChange]{
+	Item:     "name_change",
+	Validate: utils.ValidateNameChange,
+	Merge: func(stored *models.NameChange, update *models.NameChange) {
+		if update.Surname == "" && update.FirstName == "" && update.Patronymic == "" {
+			update.Surname, update.FirstName, update.Patronymic = stored.Surname, stored.FirstName, stored.Patronymic
+		}
+	},
+}
+
+var Educations = Resource[models.Education]{
+	Item:     "education",
+	Validate: utils.ValidateEducation,
+}
+
+var Affilations = Resource[models.Affilation]{
+	Item:     "affilation",
+	Validate: utils.ValidateAffilation,
+}
+
+var Investigations = Resource[models.Investigation]{
+	Item: "investigation",
+	Prepare: func(db *gorm.DB, item *models.Investigation, user *middlewares.TokenMetadata) {
+		item.Officer = user.FullName
+		if item.Deadline == nil {
+			item.Deadline = utils.Deadline(db, "investigation", item.PersonID, time.Now())
+		}
+	},
+}
+
+var Poligrafs = Resource[models.Poligraf]{
+	Item: "poligraf",
+	Prepare: func(db *gorm.DB, item *models.Poligraf, user *middlewares.TokenMetadata) {
+		item.Officer = user.FullName
+		if item.Deadline == nil {
+			item.Deadline = utils.Deadline(db, "poligraf", item.PersonID, time.Now())
+		}
+	},
+	Created: func(db *gorm.DB, item *models.Poligraf, user *middlewares.TokenMetadata) error {
+		var person models.Person
+		db.First(&person, item.PersonID)
+		if utils.StatusKey(person.StatusID) == "poligraf" {
+			return utils.FinishCheck(db, &person, user, "Проведено ПФО")
+		}
+		return nil
+	},
+}
+
+var Inquiries = Resource[models.Inquiry]{
+	Item: "inquiry",
+	Prepare: func(db *gorm.DB, item *models.Inquiry, user *middlewares.TokenMetadata) {
+		item.Officer = user.FullName
+		if item.Deadline == nil {
+			item.Deadline = utils.Deadline(db, "inquiry", item.PersonID, time.Now())
+		}
+	},
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database/databasetest"
+)
+
+// contactsResource records the order of the hook calls.
+func contactsResource(calls *[]string) Resource[models.Contact] {
+	return Resource[models.Contact]{
+		Item: "contact",
+		Validate: func(item *models.Contact) utils.FieldErrors {
+			*calls = append(*calls, "validate")
+			if item.View == "" {
+				return utils.FieldErrors{"view": "не указан вид"}
+			}
+			return nil
+		},
+		Normalize: func(db *gorm.DB, item *models.Contact) {
+			*calls = append(*calls, "normalize")
+			item.Contact = strings.TrimSpace(item.Contact)
+		},
+		Prepare: func(db *gorm.DB, item *models.Contact, user *middlewares.TokenMetadata) {
+			*calls = append(*calls, "prepare "+user.FullName)
+		},
+		Merge: func(stored *models.Contact, update *models.Contact) {
+			*calls = append(*calls, "merge")
+		},
+		Created: func(db *gorm.DB, item *models.Contact, user *middlewares.TokenMetadata) error {
+			*calls = append(*calls, "created")
+			return nil
+		},
+		Updated: func(db *gorm.DB, before *models.Contact, item *models.Contact, user *middlewares.TokenMetadata) error {
+			*calls = append(*calls, "updated "+before.Contact+" -> "+item.Contact)
+			return nil
+		},
+	}
+}
+
+// resourceApp serves the resource on the contact routes and opens a fake
+// database with person 5 in the registry.
+func resourceApp(t *testing.T, resource Resource[models.Contact]) (*fiber.App, *databasetest.Fake) {
+	t.Setenv("ENCRYPTION_KEYS", "test:secret")
+	t.Setenv("ENCRYPTION_KEY_ID", "test")
+	t.Setenv("BLIND_INDEX_KEY", "blind")
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	t.Setenv("JWT_SECRET_KEY_EXPIRE_MINUTES_COUNT", "15")
+	t.Setenv("REDIS_HOST", "127.0.0.1")
+	t.Setenv("REDIS_PORT", "1")
+
+	_, fake := databasetest.New(t)
+	fake.AnswerFunc(`FROM "people" WHERE "people"."id" = \$1`, []string{"id", "full_name"},
+		func(args []driver.Value) [][]driver.Value {
+			if args[0] == "5" || args[0] == int64(5) {
+				return [][]driver.Value{{int64(5), "Иванов Иван"}}
+			}
+			return nil
+		})
+
+	app := fiber.New()
+	resource.Register(app.Group("/contact/:action/:item_id"))
+	return app, fake
+}
+
+func resourceRequest(t *testing.T, app *fiber.App, method string, url string, body string) (int, string) {
+	token, err := utils.GenerateNewAccessToken(&models.User{ID: 3, FullName: "Проверяющий"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(response.Body)
+	return response.StatusCode, string(data)
+}
+
+func TestResourceCreate(t *testing.T) {
+	calls := []string{}
+	app, fake := resourceApp(t, contactsResource(&calls))
+
+	status, body := resourceRequest(t, app, "POST", "/contact/add/5", `{"id": 9, "view": "Телефон", "contact": " 111 ", "PersonID": 7}`)
+	if status != 201 {
+		t.Fatalf("Create() = %d %s, want 201", status, body)
+	}
+	want := []string{"validate", "normalize", "prepare Проверяющий", "created"}
+	if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+		t.Errorf("hooks ran as %v, want %v", calls, want)
+	}
+	inserts := fake.Statements(`^INSERT INTO "contacts"`)
+	if len(inserts) != 1 {
+		t.Fatalf("Create() inserted %d contacts, want 1", len(inserts))
+	}
+	if personID, _ := inserts[0].Arg("person_id"); personID != int64(5) {
+		t.Errorf("person_id = %v, want the person of the route", personID)
+	}
+	if _, ok := inserts[0].Arg("id"); ok {
+		t.Errorf("Create() kept the sent id: %s", inserts[0].SQL)
+	}
+	created := models.Contact{}
+	json.Unmarshal([]byte(body), &created)
+	if created.Contact != "111" {
+		t.Errorf("created contact = %q, want it normalized", created.Contact)
+	}
+	if len(fake.Statements(`^INSERT INTO "versions"`)) != 1 || len(fake.Statements(`^COMMIT`)) != 1 {
+		t.Errorf("Create() did not record the version in its transaction")
+	}
+}
+
+func TestResourceCreateRejected(t *testing.T) {
+	calls := []string{}
+	resource := contactsResource(&calls)
+	app, fake := resourceApp(t, resource)
+
+	if status, _ := resourceRequest(t, app, "POST", "/contact/add/5", `{"contact": "111"}`); status != 400 {
+		t.Errorf("Create() of an invalid contact = %d, want 400", status)
+	}
+	if status, _ := resourceRequest(t, app, "POST", "/contact/add/6", `{"view": "Телефон"}`); status != 404 {
+		t.Errorf("Create() for a missing person = %d, want 404", status)
+	}
+	if len(fake.Statements(`^INSERT`)) != 0 {
+		t.Fatalf("rejected requests stored a record")
+	}
+
+	// A failed side effect rolls the record back.
+	resource.Created = func(db *gorm.DB, item *models.Contact, user *middlewares.TokenMetadata) error {
+		return errors.New("status change is not allowed")
+	}
+	app, fake = resourceApp(t, resource)
+	status, body := resourceRequest(t, app, "POST", "/contact/add/5", `{"view": "Телефон"}`)
+	if status != 409 || !strings.Contains(body, "status change is not allowed") {
+		t.Errorf("Create() = %d %s, want 409 with the error", status, body)
+	}
+	if len(fake.Statements(`^ROLLBACK`)) != 1 {
+		t.Errorf("Create() did not roll back")
+	}
+}
+
+func TestResourceUpdate(t *testing.T) {
+	calls := []string{}
+	app, fake := resourceApp(t, contactsResource(&calls))
+	loaded := 0
+	fake.AnswerFunc(`FROM "contacts" WHERE "contacts"."id" = \$1`, []string{"id", "view", "contact", "person_id"},
+		func(args []driver.Value) [][]driver.Value {
+			// The second lookup reads the record back after the update.
+			contact := "111"
+			if loaded++; loaded > 1 {
+				contact = "222"
+			}
+			return [][]driver.Value{{int64(8), "Телефон", contact, int64(5)}}
+		})
+
+	status, body := resourceRequest(t, app, "PATCH", "/contact/edit/8", `{"id": 1, "contact": "222 ", "PersonID": 7}`)
+	if status != 200 {
+		t.Fatalf("Update() = %d %s, want 200", status, body)
+	}
+	want := []string{"merge", "validate", "normalize", "updated 111 -> 222"}
+	if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+		t.Errorf("hooks ran as %v, want %v", calls, want)
+	}
+
+	updates := fake.Statements(`^UPDATE "contacts"`)
+	if len(updates) != 1 {
+		t.Fatalf("Update() updated %d contacts, want 1", len(updates))
+	}
+	if view, _ := updates[0].Arg("view"); view != "Телефон" {
+		t.Errorf("view = %v, want the stored value kept", view)
+	}
+	if _, ok := updates[0].Arg("person_id"); ok {
+		t.Errorf("Update() changed the person: %s", updates[0].SQL)
+	}
+	if !strings.HasSuffix(updates[0].SQL, `WHERE "id" = $4`) || updates[0].Args[3] != int64(8) {
+		t.Errorf("Update() = %s %v, want contact 8 updated", updates[0].SQL, updates[0].Args)
+	}
+	if len(fake.Statements(`^INSERT INTO "versions"`)) != 1 {
+		t.Errorf("Update() did not record the version")
+	}
+}
+
+func TestResourceDelete(t *testing.T) {
+	calls := []string{}
+	app, fake := resourceApp(t, contactsResource(&calls))
+	fake.AnswerFunc(`FROM "contacts" WHERE "contacts"."id" = \$1`, []string{"id", "view", "person_id"},
+		func(args []driver.Value) [][]driver.Value {
+			// Contact 9 belongs to a person in the trash.
+			if args[0] == "9" {
+				return [][]driver.Value{{int64(9), "Телефон", int64(6)}}
+			}
+			return [][]driver.Value{{int64(8), "Телефон", int64(5)}}
+		})
+
+	if status, _ := resourceRequest(t, app, "DELETE", "/contact/delete/9", ""); status != 404 {
+		t.Errorf("Delete() of a trashed person's contact = %d, want 404", status)
+	}
+	if len(fake.Statements(`^DELETE`)) != 0 {
+		t.Fatalf("Delete() removed a contact of a trashed person")
+	}
+	if status, body := resourceRequest(t, app, "DELETE", "/contact/delete/8", ""); status != 204 {
+		t.Fatalf("Delete() = %d %s, want 204", status, body)
+	}
+	deletes := fake.Statements(`^DELETE FROM "contacts"`)
+	if len(deletes) != 1 || deletes[0].Args[0] != int64(8) {
+		t.Errorf("Delete() = %v, want contact 8 deleted", deletes)
+	}
+	if versions := fake.Statements(`^INSERT INTO "versions"`); len(versions) != 1 {
+		t.Errorf("Delete() recorded %d versions, want 1", len(versions))
+	} else if action, _ := versions[0].Arg("action"); action != "delete" {
+		t.Errorf("version action = %v, want delete", action)
+	}
+}
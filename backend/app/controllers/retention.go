@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetRetentionRules(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var rules []models.RetentionRule
+	db.Order("id").Find(&rules)
+	return c.Status(200).JSON(rules)
+}
+
+func PostRetentionRule(c *fiber.Ctx) error {
+	var rule models.RetentionRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	errs := utils.FieldErrors{}
+	if _, ok := utils.RetentionActions[rule.Action]; !ok {
+		errs["action"] = "неизвестное действие"
+	}
+	if rule.Months <= 0 {
+		errs["months"] = "срок хранения должен быть положительным"
+	}
+	if rule.CategoryID == 0 {
+		errs["category_id"] = "не указана категория"
+	}
+	if rule.StatusID == 0 {
+		errs["status_id"] = "не указан статус"
+	}
+	if len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	db := database.OpenDb()
+	db.Create(&rule)
+	return c.Status(201).JSON(rule)
+}
+
+func DeleteRetentionRule(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	db.Delete(&models.RetentionRule{}, c.Params("id"))
+	return c.Status(204).JSON("Rule deleted")
+}
+
+func GetRetentionRuns(c *fiber.Ctx) error {
+	intPage, err := strconv.Atoi(c.Params("page"))
+	if err != nil {
+		intPage = 1
+	}
+
+	db := database.OpenDb()
+	var runs []models.RetentionRun
+	var pagination = 16
+	var hasPrev, hasNext bool
+
+	db.
+		Order("created_at desc").
+		Limit(pagination).
+		Offset(pagination * (intPage - 1)).
+		Find(&runs)
+
+	if intPage > 1 {
+		hasPrev = true
+	}
+	if len(runs) == pagination {
+		hasNext = true
+	}
+	return c.JSON(fiber.Map{"result": runs, "hasNext": hasNext, "hasPrev": hasPrev})
+}
+
+func PostRetentionRun(c *fiber.Ctx) error {
+	payload := struct {
+		DryRun bool `json:"dry_run"`
+	}{}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	run, err := utils.RunRetention(database.OpenDb(), payload.DryRun, tokenMeta.FullName)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(run)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetSla(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	before := time.Now()
+	switch c.Params("action") {
+	case "overdue":
+	case "soon":
+		before = before.AddDate(0, 0, utils.SlaRemindDays())
+	default:
+		return c.Status(404).JSON("Not found")
+	}
+
+	officer := c.Query("officer", tokenMeta.FullName)
+	if officer == "all" {
+		officer = ""
+	}
+	items, err := utils.DueItems(db, before, officer)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(items)
+}
+
+func GetSlaRules(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var rules []models.SlaRule
+	db.Order("kind, category_id, region_id").Find(&rules)
+	return c.Status(200).JSON(rules)
+}
+
+func PostSlaRule(c *fiber.Ctx) error {
+	var rule models.SlaRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	errs := utils.FieldErrors{}
+	if _, ok := utils.SlaKinds[rule.Kind]; !ok {
+		errs["kind"] = "неизвестный вид работы"
+	}
+	if rule.Days <= 0 {
+		errs["days"] = "срок должен быть положительным"
+	}
+	if len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	db := database.OpenDb()
+	rule.ID = 0
+	db.Create(&rule)
+	if _, err := utils.RecalculateDeadlines(db); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(rule)
+}
+
+func DeleteSlaRule(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	db.Delete(&models.SlaRule{}, c.Params("id"))
+	return c.Status(204).JSON("Rule deleted")
+}
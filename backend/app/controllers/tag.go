@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetTags(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	return c.Status(200).JSON(utils.AvailableTags(db, tokenMeta))
+}
+
+func PostTag(c *fiber.Ctx) error {
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	db := database.OpenDb()
+	if errs := utils.ValidateTag(db, &tag); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+	tag.ID = 0
+	if err := db.Create(&tag).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(tag)
+}
+
+func PatchTag(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var tag models.Tag
+
+	db.First(&tag, c.Params("tag_id"))
+	if tag.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	var newTag models.Tag
+	if err := c.BodyParser(&newTag); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	newTag.ID = tag.ID
+	if errs := utils.ValidateTag(db, &newTag); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+	if err := db.Select("name", "color", "group_id").Updates(&newTag).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(newTag)
+}
+
+func DeleteTag(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var tag models.Tag
+
+	db.First(&tag, c.Params("tag_id"))
+	if tag.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var personTags []models.PersonTag
+		tx.Where("tag_id = ?", tag.ID).Find(&personTags)
+		for i := range personTags {
+			if err := tx.Delete(&personTags[i]).Error; err != nil {
+				return err
+			}
+			if err := utils.RecordVersion(tx, "person_tag", &personTags[i], nil, tokenMeta); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(204).JSON("Tag deleted")
+}
+
+func GetPersonTags(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	personID, err := strconv.ParseUint(c.Params("person_id"), 10, 64)
+	if err != nil {
+		return c.Status(404).JSON("Not found")
+	}
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	return c.Status(200).JSON(utils.PersonTags(db, uint(personID), tokenMeta))
+}
+
+func PostPersonTag(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+
+	db.First(&person, c.Params("person_id"))
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	tagID, err := strconv.ParseUint(c.Params("tag_id"), 10, 64)
+	if err != nil {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if err := utils.AttachTag(db, person.ID, uint(tagID), tokenMeta); err != nil {
+		return c.Status(403).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(utils.PersonTags(db, person.ID, tokenMeta))
+}
+
+func DeletePersonTag(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+
+	db.First(&person, c.Params("person_id"))
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+	tagID, err := strconv.ParseUint(c.Params("tag_id"), 10, 64)
+	if err != nil {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if err := utils.DetachTag(db, person.ID, uint(tagID), tokenMeta); err != nil {
+		return c.Status(403).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(utils.PersonTags(db, person.ID, tokenMeta))
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetCheckTemplates(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var templates []models.CheckTemplate
+	db.
+		Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Order("category_id, region_id").
+		Find(&templates)
+	return c.Status(200).JSON(templates)
+}
+
+func PostCheckTemplate(c *fiber.Ctx) error {
+	var template models.CheckTemplate
+	if err := c.BodyParser(&template); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	if errs := utils.ValidateCheckTemplate(&template); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	db := database.OpenDb()
+	template.ID = 0
+	if err := db.Create(&template).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(201).JSON(template)
+}
+
+// PatchCheckTemplate replaces the template with its items. Checks already
+// started keep their copy of the old items.
+func PatchCheckTemplate(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var stored models.CheckTemplate
+	db.First(&stored, c.Params("id"))
+	if stored.ID == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+
+	var template models.CheckTemplate
+	if err := c.BodyParser(&template); err != nil {
+		return c.Status(500).JSON(err)
+	}
+	if errs := utils.ValidateCheckTemplate(&template); len(errs) > 0 {
+		return validationError(c, errs)
+	}
+
+	template.ID, template.CreatedAt = stored.ID, stored.CreatedAt
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", stored.ID).Delete(&models.CheckTemplateItem{}).Error; err != nil {
+			return err
+		}
+		items := template.Items
+		template.Items = nil
+		if err := tx.Save(&template).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].TemplateID = template.ID
+		}
+		template.Items = items
+		return tx.Create(&template.Items).Error
+	})
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(template)
+}
+
+func DeleteCheckTemplate(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	db.Where("template_id = ?", c.Params("id")).Delete(&models.CheckTemplateItem{})
+	db.Delete(&models.CheckTemplate{}, c.Params("id"))
+	return c.Status(204).JSON("Template deleted")
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetTimeline(c *fiber.Ctx) error {
+	gapDays := c.QueryInt("gap", utils.EmploymentGapDays())
+	shortDays := c.QueryInt("short", utils.EmploymentShortDays())
+	if gapDays < 0 || shortDays < 0 {
+		return validationError(c, utils.FieldErrors{"gap": "срок не может быть отрицательным"})
+	}
+
+	db := database.OpenDb()
+	person, ok := resourcePerson(db, c.Params("person_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": true, "msg": "Not found"})
+	}
+	return c.Status(200).JSON(utils.PersonTimeline(db, person.ID, gapDays, shortDays))
+}
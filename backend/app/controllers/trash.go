@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetTrash(c *fiber.Ctx) error {
+	intPage, err := strconv.Atoi(c.Params("page"))
+	if err != nil {
+		intPage = 1
+	}
+
+	db := database.OpenDb()
+	var persons []models.Person
+	var pagination = 16
+	var hasPrev, hasNext bool
+
+	db.
+		Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc").
+		Limit(pagination).
+		Offset(pagination * (intPage - 1)).
+		Find(&persons)
+
+	if intPage > 1 {
+		hasPrev = true
+	}
+	if len(persons) == pagination {
+		hasNext = true
+	}
+	return c.JSON(fiber.Map{"result": persons, "hasNext": hasNext, "hasPrev": hasPrev})
+}
+
+func GetRestore(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+
+	db.
+		Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", c.Params("person_id")).
+		First(&person)
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	if err := utils.RestorePerson(db, &person, tokenMeta); err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(person)
+}
+
+func DeletePurge(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+
+	db.
+		Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", c.Params("person_id")).
+		First(&person)
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	if err := utils.PurgePerson(db, &person); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(204).JSON("Person purged")
+}
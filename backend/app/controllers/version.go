@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetVersions(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var versions []models.Version
+
+	query := db.Order("created_at desc")
+	if c.Params("action") == "person" {
+		query = query.Where("person_id = ?", c.Params("item_id"))
+	} else {
+		if _, ok := utils.VersionModels[c.Params("action")]; !ok {
+			return c.Status(404).JSON("Not found")
+		}
+		query = query.Where("item = ? AND record_id = ?", c.Params("action"), c.Params("item_id"))
+	}
+	query.Find(&versions)
+
+	return c.Status(200).JSON(versions)
+}
+
+func PostRevert(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var version models.Version
+
+	db.First(&version, c.Params("item_id"))
+	if version.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := utils.RevertVersion(db, version, tokenMeta)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON("Reverted")
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/platform/database"
+)
+
+func GetWatches(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var persons []models.Person
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	db.
+		Joins("JOIN watches ON watches.person_id = people.id").
+		Where("watches.user_id = ?", tokenMeta.UserID).
+		Order("people.search_name").
+		Find(&persons)
+	return c.Status(200).JSON(persons)
+}
+
+func PostWatch(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+
+	db.First(&person, c.Params("person_id"))
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	var watch models.Watch
+	db.Where("user_id = ? AND person_id = ?", tokenMeta.UserID, person.ID).First(&watch)
+	if watch.ID == 0 {
+		watch = models.Watch{UserID: tokenMeta.UserID, PersonID: person.ID}
+		db.Create(&watch)
+	}
+	return c.Status(200).JSON(watch)
+}
+
+func DeleteWatch(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+
+	db.
+		Where("user_id = ? AND person_id = ?", tokenMeta.UserID, c.Params("person_id")).
+		Delete(&models.Watch{})
+	return c.Status(204).JSON("Watch deleted")
+}
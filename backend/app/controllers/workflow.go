@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/pkg/utils"
+	"backend/platform/database"
+)
+
+func GetWorkflow(c *fiber.Ctx) error {
+	db := database.OpenDb()
+	var person models.Person
+	db.First(&person, c.Params("item_id"))
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	switch c.Params("action") {
+	case "actions":
+		return c.Status(200).JSON(fiber.Map{
+			"status":  utils.StatusKey(person.StatusID),
+			"actions": utils.NextStatuses(person),
+		})
+	case "history":
+		var history []models.StatusHistory
+		db.
+			Where("person_id = ?", person.ID).
+			Order("created_at desc").
+			Find(&history)
+		return c.Status(200).JSON(history)
+	}
+	return c.Status(404).JSON("Not found")
+}
+
+func PostWorkflow(c *fiber.Ctx) error {
+	payload := struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}{}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(500).JSON(err)
+	}
+
+	db := database.OpenDb()
+	var person models.Person
+	db.First(&person, c.Params("item_id"))
+	if person.ID == 0 {
+		return c.Status(404).JSON("Not found")
+	}
+
+	tokenMeta, _ := middlewares.ExtractTokenMetadata(c)
+	err := utils.ChangeStatus(db, &person, payload.Status, tokenMeta, payload.Reason)
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": true, "msg": err.Error()})
+	}
+	return c.Status(200).JSON(person)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date stored in a date column and sent as YYYY-MM-DD.
+type Date struct {
+	time.Time
+}
+
+func NewDate(t time.Time) Date {
+	if t.IsZero() {
+		return Date{}
+	}
+	return Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (Date) GormDataType() string {
+	return "date"
+}
+
+func (date Date) String() string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(DateLayout)
+}
+
+func (date Date) MarshalJSON() ([]byte, error) {
+	if date.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + date.Format(DateLayout) + `"`), nil
+}
+
+func (date *Date) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		date.Time = time.Time{}
+		return nil
+	}
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid date %q", value)
+		}
+	}
+	*date = NewDate(t)
+	return nil
+}
+
+func (date Date) Value() (driver.Value, error) {
+	if date.IsZero() {
+		return nil, nil
+	}
+	return date.Format(DateLayout), nil
+}
+
+func (date *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		date.Time = time.Time{}
+	case time.Time:
+		*date = NewDate(v)
+	case string:
+		return date.UnmarshalJSON([]byte(v))
+	case []byte:
+		return date.UnmarshalJSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	"backend/platform/encryption"
+)
+
+// Encrypted fields are stored with the "encrypted" serializer and can't be
+// compared in SQL. Exact match lookups use the blind indexes kept next to
+// them, which the hooks below refresh on every save.
+
+func DocumentIndex(series string, number string) (string, error) {
+	return encryption.BlindIndex("document", series+number)
+}
+
+func (person *Person) SetIndexes() (err error) {
+	if person.SnilsIndex, err = encryption.BlindIndex("snils", person.Snils); err != nil {
+		return err
+	}
+	person.InnIndex, err = encryption.BlindIndex("inn", person.Inn)
+	return err
+}
+
+func (person *Person) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Person); ok && dest != person {
+		if err := dest.SetIndexes(); err != nil {
+			return err
+		}
+	}
+	return person.SetIndexes()
+}
+
+func (document *Document) SetIndexes() (err error) {
+	document.DocumentIndex, err = DocumentIndex(document.Series, document.Number)
+	return err
+}
+
+func (document *Document) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Document); ok && dest != document {
+		if err := dest.SetIndexes(); err != nil {
+			return err
+		}
+	}
+	return document.SetIndexes()
+}
+
+func (contact *Contact) SetIndexes() (err error) {
+	contact.ContactIndex, err = encryption.BlindIndex("contact", contact.Contact)
+	return err
+}
+
+func (contact *Contact) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Contact); ok && dest != contact {
+		if err := dest.SetIndexes(); err != nil {
+			return err
+		}
+	}
+	return contact.SetIndexes()
+}
+
+func (anketa *Anketa) SetIndexes() (err error) {
+	if anketa.SnilsIndex, err = encryption.BlindIndex("snils", anketa.Snils); err != nil {
+		return err
+	}
+	if anketa.InnIndex, err = encryption.BlindIndex("inn", anketa.Inn); err != nil {
+		return err
+	}
+	anketa.DocumentIndex, err = DocumentIndex(anketa.Series, anketa.Number)
+	return err
+}
+
+func (anketa *Anketa) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Anketa); ok && dest != anketa {
+		if err := dest.SetIndexes(); err != nil {
+			return err
+		}
+	}
+	return anketa.SetIndexes()
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestDocumentIndexFromSavedValues(t *testing.T) {
+	t.Setenv("BLIND_INDEX_KEY", "blind")
+	stored := &Document{ID: 1, Series: "4510", Number: "123456"}
+	tests := []struct {
+		name   string
+		update Document
+	}{
+		{"both changed", Document{Series: "4511", Number: "654321"}},
+		{"series cleared", Document{Series: "", Number: "123456"}},
+		{"both cleared", Document{}},
+	}
+	for _, test := range tests {
+		dest := test.update
+		tx := &gorm.DB{Statement: &gorm.Statement{Dest: &dest}}
+		if err := stored.BeforeSave(tx); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := DocumentIndex(test.update.Series, test.update.Number)
+		if dest.DocumentIndex != want {
+			t.Errorf("%s: index %q, want the index of the saved values %q", test.name, dest.DocumentIndex, want)
+		}
+	}
+}
+
+func TestPersonIndexes(t *testing.T) {
+	t.Setenv("BLIND_INDEX_KEY", "blind")
+	person := &Person{Snils: "112-233-445 95", Inn: "7707083893"}
+	dest := &Person{Snils: "12345678964"}
+	tx := &gorm.DB{Statement: &gorm.Statement{Dest: dest}}
+	if err := person.BeforeSave(tx); err != nil {
+		t.Fatal(err)
+	}
+	if person.SnilsIndex == "" || person.InnIndex == "" {
+		t.Errorf("BeforeSave() left the indexes of the person empty")
+	}
+	if dest.SnilsIndex == person.SnilsIndex || dest.InnIndex != "" {
+		t.Errorf("BeforeSave() indexes of the update = %q, %q, want its own values", dest.SnilsIndex, dest.InnIndex)
+	}
+
+	t.Setenv("BLIND_INDEX_KEY", "")
+	if err := (&Person{Inn: "7707083893"}).SetIndexes(); err == nil {
+		t.Errorf("SetIndexes() returned no error without BLIND_INDEX_KEY")
+	}
+}
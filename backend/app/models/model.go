@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"gorm.io/gorm"
+
 	"backend/platform/database"
 )
 
@@ -76,6 +78,23 @@ func (status Status) GetID(name string) uint {
 	return statusId
 }
 
+func (status Status) GetName(id uint) string {
+	db := database.OpenDb()
+	db.First(&status, id)
+	return status.NameStatus
+}
+
+type StatusHistory struct {
+	ID         uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	FromStatus string    `gorm:"size(256)" json:"from" serialize:"json"`
+	ToStatus   string    `gorm:"size(256)" json:"to" serialize:"json"`
+	Reason     string    `json:"reason" serialize:"json"`
+	UserID     uint      `json:"user_id" serialize:"json"`
+	UserName   string    `gorm:"size(256)" json:"user" serialize:"json"`
+	CreatedAt  time.Time `json:"created" serialize:"json"`
+	PersonID   uint
+}
+
 type Region struct {
 	ID         uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
 	NameRegion string `gorm:"size(256)" json:"region" serialize:"json"`
@@ -93,27 +112,38 @@ func (region Region) GetID(name string) uint {
 }
 
 type Person struct {
-	ID               uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	CategoryID       uint      `json:"category_id" serialize:"json"`
-	RegionID         uint      `json:"region_id" serialize:"json"`
-	FullName         string    `gorm:"not null; index" json:"fullname" serialize:"json"`
-	PreviousFullName string    `json:"previous" serialize:"json"`
-	BirthDate        string    `gorm:"not null" json:"birthday" serialize:"json"`
-	BirthPlace       string    `json:"birth_place" serialize:"json"`
-	Citizen          string    `gorm:"size(256)" json:"country" serialize:"json"`
-	ExCitizen        string    `gorm:"size(256)" json:"ex_citizen" serialize:"json"`
-	Snils            string    `gorm:"size(11)" json:"snils" serialize:"json"`
-	Inn              string    `gorm:"size(12)" json:"inn" serialize:"json"`
-	Education        string    `json:"education" serialize:"json"`
-	MaritalStatus    string    `gorm:"son:marital" serialize:"json"`
-	AdditionalInfo   string    `json:"addition" serialize:"json"`
-	PathToDocs       string    `json:"path" serialize:"json"`
-	StatusID         uint      `json:"status_id" serialize:"json"`
-	CreatedAt        time.Time `json:"created" serialize:"json"`
-	UpdatedAt        time.Time `json:"updated" serialize:"json"`
+	ID               uint           `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	CategoryID       uint           `json:"category_id" serialize:"json"`
+	RegionID         uint           `json:"region_id" serialize:"json"`
+	FullName         string         `gorm:"not null; index" json:"fullname" serialize:"json"`
+	Surname          string         `gorm:"size(256)" json:"surname" serialize:"json"`
+	FirstName        string         `gorm:"size(256)" json:"firstname" serialize:"json"`
+	Patronymic       string         `gorm:"size(256)" json:"patronymic" serialize:"json"`
+	SearchName       string         `gorm:"index" json:"search_name" serialize:"json"`
+	PreviousFullName string         `json:"previous" serialize:"json"`
+	BirthDate        Date           `gorm:"index" json:"birthday" serialize:"json"`
+	BirthPlace       string         `json:"birth_place" serialize:"json"`
+	Citizen          string         `gorm:"size(256)" json:"country" serialize:"json"`
+	ExCitizen        string         `gorm:"size(256)" json:"ex_citizen" serialize:"json"`
+	Snils            string         `gorm:"serializer:encrypted" json:"snils" serialize:"json"`
+	SnilsIndex       string         `gorm:"size(64); index" json:"-"`
+	Inn              string         `gorm:"serializer:encrypted" json:"inn" serialize:"json"`
+	InnIndex         string         `gorm:"size(64); index" json:"-"`
+	MaritalStatus    string         `gorm:"son:marital" serialize:"json"`
+	AdditionalInfo   string         `json:"addition" serialize:"json"`
+	PathToDocs       string         `json:"path" serialize:"json"`
+	StatusID         uint           `json:"status_id" serialize:"json"`
+	CreatedAt        time.Time      `json:"created" serialize:"json"`
+	UpdatedAt        time.Time      `json:"updated" serialize:"json"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted" serialize:"json"`
+	DeletedBy        string         `gorm:"size(256)" json:"deleted_by" serialize:"json"`
+	TrashPath        string         `json:"trash_path" serialize:"json"`
+	AnonymizedAt     *time.Time     `json:"anonymized" serialize:"json"`
 	Documents        []Document
 	Addresses        []Address
 	Workplaces       []Workplace
+	Educations       []Education
+	NameChanges      []NameChange
 	Contacts         []Contact
 	Staffs           []Staff
 	Affiliations     []Affilation
@@ -123,6 +153,8 @@ type Person struct {
 	Investigations   []Investigation
 	Robots           []Robot
 	Poligrafs        []Poligraf
+	StatusHistories  []StatusHistory
+	Tags             []Tag `gorm:"-" json:"tags"`
 }
 
 type Staff struct {
@@ -133,55 +165,96 @@ type Staff struct {
 }
 
 type Document struct {
-	ID       uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	View     string    `gorm:"size(256)" json:"view" serialize:"json"`
-	Series   string    `gorm:"size(256)" json:"series" serialize:"json"`
-	Number   string    `gorm:"size(256)" json:"number" serialize:"json"`
-	Agency   string    `json:"agency" serialize:"json"`
-	Issue    time.Time `json:"issue" serialize:"json"`
-	PersonID uint
+	ID            uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	View          string     `gorm:"size(256)" json:"view" serialize:"json"`
+	Series        string     `gorm:"serializer:encrypted" json:"series" serialize:"json"`
+	Number        string     `gorm:"serializer:encrypted" json:"number" serialize:"json"`
+	DocumentIndex string     `gorm:"size(64); index" json:"-"`
+	Agency        string     `json:"agency" serialize:"json"`
+	Code          string     `gorm:"size(256)" json:"code" serialize:"json"`
+	Issue         time.Time  `json:"issue" serialize:"json"`
+	Expiry        *time.Time `json:"expiry" serialize:"json"`
+	ValidUntil    *time.Time `gorm:"-" json:"valid_until"`
+	Validity      string     `gorm:"-" json:"validity"`
+	PersonID      uint
 }
 
 type Address struct {
 	ID       uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
 	View     string `gorm:"size(256)" json:"view" serialize:"json"`
 	Region   string `gorm:"size(256)" json:"region" serialize:"json"`
+	City     string `gorm:"size(256)" json:"city" serialize:"json"`
+	Street   string `gorm:"size(256)" json:"street" serialize:"json"`
+	House    string `gorm:"size(64)" json:"house" serialize:"json"`
+	Flat     string `gorm:"size(64)" json:"flat" serialize:"json"`
+	FiasGUID string `gorm:"size(36); index" json:"fias_guid" serialize:"json"`
 	Address  string `json:"address" serialize:"json"`
 	PersonID uint
 }
 
 type Contact struct {
-	ID       uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	View     string `gorm:"size(256)" json:"view" serialize:"json"`
-	Contact  string `gorm:"size(256)" json:"contact" serialize:"json"`
-	PersonID uint
+	ID           uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	View         string `gorm:"size(256)" json:"view" serialize:"json"`
+	Contact      string `gorm:"serializer:encrypted" json:"contact" serialize:"json"`
+	ContactIndex string `gorm:"size(64); index" json:"-"`
+	PersonID     uint
 }
 
 type Workplace struct {
 	ID        uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
 	View      string `json:"view" serialize:"json"`
 	Workplace string `json:"workplace" serialize:"json"`
+	NameKey   string `gorm:"size(256); index" json:"-"`
 	Address   string `json:"address" serialize:"json"`
 	Position  string `json:"position" serialize:"json"`
 	Reason    string `json:"reason" serialize:"json"`
+	StartDate Date   `json:"start_date" serialize:"json"`
+	EndDate   Date   `json:"end_date" serialize:"json"`
+	Current   bool   `json:"current" serialize:"json"`
 	PersonID  uint
 }
 
+type NameChange struct {
+	ID         uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Surname    string `gorm:"size(256)" json:"surname" serialize:"json"`
+	FirstName  string `gorm:"size(256)" json:"firstname" serialize:"json"`
+	Patronymic string `gorm:"size(256)" json:"patronymic" serialize:"json"`
+	FullName   string `json:"fullname" serialize:"json"`
+	SearchName string `gorm:"index" json:"search_name" serialize:"json"`
+	Year       int    `json:"year" serialize:"json"`
+	Document   string `json:"document" serialize:"json"`
+	Reason     string `json:"reason" serialize:"json"`
+	PersonID   uint
+}
+
+type Education struct {
+	ID          uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	View        string `gorm:"size(256)" json:"view" serialize:"json"`
+	Institution string `json:"institution" serialize:"json"`
+	Specialty   string `json:"specialty" serialize:"json"`
+	BeginYear   int    `json:"begin_year" serialize:"json"`
+	EndYear     int    `json:"end_year" serialize:"json"`
+	PersonID    uint
+}
+
 type Affilation struct {
 	ID       uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
 	View     string    `gorm:"size(256)" json:"view" serialize:"json"`
 	Name     string    `gorm:"size(256)" json:"name" serialize:"json"`
-	Inn      string    `gorm:"size(12)" json:"inn" serialize:"json"`
+	Inn      string    `gorm:"size(12); index" json:"inn" serialize:"json"`
+	NameKey  string    `gorm:"size(256); index" json:"-"`
 	Position string    `json:"position" serialize:"json"`
 	Deadline time.Time `gorm:"autoCreateTime; autoUpdateTime" json:"deadline" serialize:"json"`
 	PersonID uint
 }
 
 type Relation struct {
-	ID       uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	View     string `gorm:"size(256)" json:"relation" serialize:"json"`
-	Relation uint   `gorm:"foreignKey:PersonID" json:"relation_id" serialize:"json"`
-	PersonID uint
+	ID        uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	View      string `gorm:"size(256)" json:"relation" serialize:"json"`
+	Kind      string `gorm:"size(32); index" json:"kind" serialize:"json"`
+	RelatedID uint   `gorm:"index" json:"related_id" serialize:"json"`
+	PairID    uint   `json:"pair_id" serialize:"json"`
+	PersonID  uint
 }
 
 type Conclusion struct {
@@ -200,27 +273,37 @@ func (conclusion Conclusion) GetID(name string) uint {
 	return conclusionId
 }
 
+func (conclusion Conclusion) GetName(id uint) string {
+	db := database.OpenDb()
+	db.First(&conclusion, id)
+	return conclusion.Conclusion
+}
+
 type Check struct {
-	ID             uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	CheckWorkplace string    `json:"workplace" serialize:"json"`
-	CheckEmployee  string    `json:"employee" serialize:"json"`
-	CheckDocument  string    `json:"document" serialize:"json"`
-	CheckInn       string    `json:"inn" serialize:"json"`
-	Debt           string    `json:"debt" serialize:"json"`
-	Bankruptcy     string    `json:"bankruptcy" serialize:"json"`
-	BKI            string    `json:"bki" serialize:"json"`
-	Courts         string    `json:"courts" serialize:"json"`
-	Affiliation    string    `json:"affiliation" serialize:"json"`
-	Terrorist      string    `json:"terrorist" serialize:"json"`
-	MVD            string    `json:"mvd" serialize:"json"`
-	Internet       string    `json:"internet" serialize:"json"`
-	Cronos         string    `json:"cronos" serialize:"json"`
-	CROS           string    `json:"cros" serialize:"json"`
-	Comments       string    `json:"comments" serialize:"json"`
-	Addition       string    `json:"addition" serialize:"json"`
-	ConclusionID   uint      `gorm:"foreignKey:ConclusionID" serialize:"json" json:"conclusion_id"`
-	Officer        string    `json:"officer" serialize:"json"`
-	Deadline       time.Time `gorm:"autoCreateTime; autoUpdateTime" json:"deadline" serialize:"json"`
+	ID             uint        `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	CheckWorkplace string      `json:"workplace" serialize:"json"`
+	CheckEmployee  string      `json:"employee" serialize:"json"`
+	CheckDocument  string      `json:"document" serialize:"json"`
+	CheckInn       string      `json:"inn" serialize:"json"`
+	Debt           string      `json:"debt" serialize:"json"`
+	Bankruptcy     string      `json:"bankruptcy" serialize:"json"`
+	BKI            string      `json:"bki" serialize:"json"`
+	Courts         string      `json:"courts" serialize:"json"`
+	Affiliation    string      `json:"affiliation" serialize:"json"`
+	Terrorist      string      `json:"terrorist" serialize:"json"`
+	MVD            string      `json:"mvd" serialize:"json"`
+	Internet       string      `json:"internet" serialize:"json"`
+	Cronos         string      `json:"cronos" serialize:"json"`
+	CROS           string      `json:"cros" serialize:"json"`
+	Comments       string      `json:"comments" serialize:"json"`
+	Addition       string      `json:"addition" serialize:"json"`
+	ConclusionID   uint        `gorm:"foreignKey:ConclusionID" serialize:"json" json:"conclusion_id"`
+	Officer        string      `json:"officer" serialize:"json"`
+	Deadline       *time.Time  `gorm:"index" json:"deadline" serialize:"json"`
+	TemplateID     uint        `json:"template_id" serialize:"json"`
+	Items          []CheckItem `json:"items"`
+	CreatedAt      time.Time   `json:"created" serialize:"json"`
+	UpdatedAt      time.Time   `json:"updated" serialize:"json"`
 	PersonID       uint
 }
 
@@ -239,30 +322,36 @@ type Robot struct {
 }
 
 type Poligraf struct {
-	ID       uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	Theme    string    `gorm:"size(256)" json:"theme" serialize:"json"`
-	Results  string    `json:"results" serialize:"json"`
-	Officer  string    `gorm:"size(256)" json:"officer" serialize:"json"`
-	Deadline time.Time `gorm:"autoCreateTime; autoUpdateTime" json:"deadline" serialize:"json"`
-	PersonID uint
+	ID        uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Theme     string     `gorm:"size(256)" json:"theme" serialize:"json"`
+	Results   string     `json:"results" serialize:"json"`
+	Officer   string     `gorm:"size(256)" json:"officer" serialize:"json"`
+	Deadline  *time.Time `gorm:"index" json:"deadline" serialize:"json"`
+	Closed    bool       `gorm:"default:false" json:"closed" serialize:"json"`
+	CreatedAt time.Time  `json:"created" serialize:"json"`
+	PersonID  uint
 }
 
 type Investigation struct {
-	ID       uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	Theme    string    `gorm:"size(256)" json:"theme" serialize:"json"`
-	Info     string    `json:"info" serialize:"json"`
-	Officer  string    `gorm:"size(256)" json:"officer" serialize:"json"`
-	Deadline time.Time `gorm:"autoCreateTime; autoUpdateTime" json:"deadline" serialize:"json"`
-	PersonID uint
+	ID        uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Theme     string     `gorm:"size(256)" json:"theme" serialize:"json"`
+	Info      string     `json:"info" serialize:"json"`
+	Officer   string     `gorm:"size(256)" json:"officer" serialize:"json"`
+	Deadline  *time.Time `gorm:"index" json:"deadline" serialize:"json"`
+	Closed    bool       `gorm:"default:false" json:"closed" serialize:"json"`
+	CreatedAt time.Time  `json:"created" serialize:"json"`
+	PersonID  uint
 }
 
 type Inquiry struct {
-	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	Info      string    `json:"info" serialize:"json"`
-	Initiator string    `gorm:"size(256)" json:"initiator" serialize:"json"`
-	Source    string    `gorm:"size(256)" json:"source" serialize:"json"`
-	Officer   string    `gorm:"size(256)" json:"officer" serialize:"json"`
-	Deadline  time.Time `gorm:"autoCreateTime; autoUpdateTime" json:"deadline" serialize:"json"`
+	ID        uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Info      string     `json:"info" serialize:"json"`
+	Initiator string     `gorm:"size(256)" json:"initiator" serialize:"json"`
+	Source    string     `gorm:"size(256)" json:"source" serialize:"json"`
+	Officer   string     `gorm:"size(256)" json:"officer" serialize:"json"`
+	Deadline  *time.Time `gorm:"index" json:"deadline" serialize:"json"`
+	Closed    bool       `gorm:"default:false" json:"closed" serialize:"json"`
+	CreatedAt time.Time  `json:"created" serialize:"json"`
 	PersonID  uint
 }
 
@@ -280,15 +369,255 @@ type Connection struct {
 }
 
 type Anketa struct {
+	ID            uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Fullname      string `gorm:"size(256)" json:"fullname" serialize:"json"`
+	Birthday      string `gorm:"size(256)" json:"birthday" serialize:"json"`
+	Birthplace    string `gorm:"size(256)" json:"birthplace" serialize:"json"`
+	Snils         string `gorm:"serializer:encrypted" json:"snils" serialize:"json"`
+	SnilsIndex    string `gorm:"size(64); index" json:"-"`
+	Inn           string `gorm:"serializer:encrypted" json:"inn" serialize:"json"`
+	InnIndex      string `gorm:"size(64); index" json:"-"`
+	Series        string `gorm:"serializer:encrypted" json:"series" serialize:"json"`
+	Number        string `gorm:"serializer:encrypted" json:"number" serialize:"json"`
+	DocumentIndex string `gorm:"size(64); index" json:"-"`
+	Agency        string `gorm:"size(256)" json:"agency" serialize:"json"`
+	Issue         string `gorm:"size(256)" json:"issue" serialize:"json"`
+	Address       string `gorm:"size(256)" json:"address" serialize:"json"`
+}
+
+type Version struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Item      string    `gorm:"size(256); index" json:"item" serialize:"json"`
+	RecordID  uint      `gorm:"index" json:"record_id" serialize:"json"`
+	Action    string    `gorm:"size(256)" json:"action" serialize:"json"`
+	Diff      string    `gorm:"serializer:encrypted" json:"diff" serialize:"json"`
+	Snapshot  string    `gorm:"serializer:encrypted" json:"snapshot" serialize:"json"`
+	UserID    uint      `json:"user_id" serialize:"json"`
+	UserName  string    `gorm:"size(256)" json:"user" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+	PersonID  uint      `gorm:"index" json:"person_id" serialize:"json"`
+}
+
+type RetentionRule struct {
+	ID           uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	CategoryID   uint      `json:"category_id" serialize:"json"`
+	StatusID     uint      `json:"status_id" serialize:"json"`
+	ConclusionID uint      `json:"conclusion_id" serialize:"json"`
+	Months       int       `json:"months" serialize:"json"`
+	Action       string    `gorm:"size(256)" json:"action" serialize:"json"`
+	CreatedAt    time.Time `json:"created" serialize:"json"`
+}
+
+type RetentionRun struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	DryRun    bool      `json:"dry_run" serialize:"json"`
+	Initiator string    `gorm:"size(256)" json:"initiator" serialize:"json"`
+	Persons   int       `json:"persons" serialize:"json"`
+	Failed    int       `json:"failed" serialize:"json"`
+	Report    string    `json:"report" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+}
+
+type SavedFilter struct {
+	ID         uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Name       string    `gorm:"size(256)" json:"name" serialize:"json"`
+	Search     string    `gorm:"size(256)" json:"search" serialize:"json"`
+	StatusID   uint      `json:"status_id" serialize:"json"`
+	RegionID   uint      `json:"region_id" serialize:"json"`
+	CategoryID uint      `json:"category_id" serialize:"json"`
+	Officer    string    `gorm:"size(256)" json:"officer" serialize:"json"`
+	UserID     uint      `gorm:"index" json:"user_id" serialize:"json"`
+	CreatedAt  time.Time `json:"created" serialize:"json"`
+}
+
+type BulkJob struct {
+	ID         uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Action     string     `gorm:"size(256)" json:"action" serialize:"json"`
+	Params     string     `json:"params" serialize:"json"`
+	State      string     `gorm:"size(256)" json:"state" serialize:"json"`
+	Total      int        `json:"total" serialize:"json"`
+	Done       int        `json:"done" serialize:"json"`
+	Failed     int        `json:"failed" serialize:"json"`
+	Results    string     `json:"results" serialize:"json"`
+	UserID     uint       `gorm:"index" json:"user_id" serialize:"json"`
+	UserName   string     `gorm:"size(256)" json:"user" serialize:"json"`
+	CreatedAt  time.Time  `json:"created" serialize:"json"`
+	FinishedAt *time.Time `json:"finished" serialize:"json"`
+}
+
+type Tag struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Name      string    `gorm:"size(256); unique" json:"name" serialize:"json"`
+	Color     string    `gorm:"size(7)" json:"color" serialize:"json"`
+	GroupID   uint      `json:"group_id" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+}
+
+type PersonTag struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	TagID     uint      `gorm:"uniqueIndex:idx_person_tag" json:"tag_id" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+	PersonID  uint      `gorm:"uniqueIndex:idx_person_tag" json:"person_id" serialize:"json"`
+}
+
+type Watch struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	UserID    uint      `gorm:"uniqueIndex:idx_watch" json:"user_id" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+	PersonID  uint      `gorm:"uniqueIndex:idx_watch" json:"person_id" serialize:"json"`
+}
+
+type WatchEvent struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Item      string    `gorm:"size(256)" json:"item" serialize:"json"`
+	Action    string    `gorm:"size(256)" json:"action" serialize:"json"`
+	UserID    uint      `json:"user_id" serialize:"json"`
+	UserName  string    `gorm:"size(256)" json:"user" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+	PersonID  uint      `gorm:"index" json:"person_id" serialize:"json"`
+}
+
+type Comment struct {
+	ID          uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	CheckID     uint      `gorm:"index" json:"check_id" serialize:"json"`
+	Text        string    `json:"text" serialize:"json"`
+	Attachments string    `json:"attachments" serialize:"json"`
+	UserID      uint      `json:"user_id" serialize:"json"`
+	UserName    string    `gorm:"size(256)" json:"user" serialize:"json"`
+	CreatedAt   time.Time `json:"created" serialize:"json"`
+	UpdatedAt   time.Time `json:"updated" serialize:"json"`
+	PersonID    uint      `gorm:"index" json:"person_id" serialize:"json"`
+}
+
+type SlaRule struct {
+	ID         uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Kind       string    `gorm:"size(256)" json:"kind" serialize:"json"`
+	CategoryID uint      `json:"category_id" serialize:"json"`
+	RegionID   uint      `json:"region_id" serialize:"json"`
+	Days       int       `json:"days" serialize:"json"`
+	CreatedAt  time.Time `json:"created" serialize:"json"`
+}
+
+type SlaNotice struct {
+	ID        uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Kind      string    `gorm:"size(256); uniqueIndex:idx_sla_notice" json:"kind" serialize:"json"`
+	ItemID    uint      `gorm:"uniqueIndex:idx_sla_notice" json:"item_id" serialize:"json"`
+	Notice    string    `gorm:"size(256); uniqueIndex:idx_sla_notice" json:"notice" serialize:"json"`
+	Deadline  time.Time `gorm:"uniqueIndex:idx_sla_notice" json:"deadline" serialize:"json"`
+	CreatedAt time.Time `json:"created" serialize:"json"`
+}
+
+type ContactPoint struct {
+	ID       uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Kind     string `gorm:"size(16); index:idx_contact_point" json:"kind" serialize:"json"`
+	Key      string `gorm:"size(64); index:idx_contact_point" json:"-"`
+	Source   string `gorm:"size(16); index:idx_contact_point_source" json:"source" serialize:"json"`
+	RecordID uint   `gorm:"index:idx_contact_point_source" json:"record_id" serialize:"json"`
+	PersonID uint   `gorm:"index" json:"person_id" serialize:"json"`
+}
+
+type FiasObject struct {
 	ID         uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
-	Fullname   string `gorm:"size(256)" json:"fullname" serialize:"json"`
-	Birthday   string `gorm:"size(256)" json:"birthday" serialize:"json"`
-	Birthplace string `gorm:"size(256)" json:"birthplace" serialize:"json"`
-	Snils      string `gorm:"size(11)" json:"snils" serialize:"json"`
-	Inn        string `gorm:"size(12)" json:"inn" serialize:"json"`
-	Series     string `gorm:"size(256)" json:"series" serialize:"json"`
-	Number     string `gorm:"size(256)" json:"number" serialize:"json"`
-	Agency     string `gorm:"size(256)" json:"agency" serialize:"json"`
-	Issue      string `gorm:"size(256)" json:"issue" serialize:"json"`
-	Address    string `gorm:"size(256)" json:"address" serialize:"json"`
+	ObjectID   int64  `gorm:"uniqueIndex" json:"object_id" serialize:"json"`
+	GUID       string `gorm:"size(36); index" json:"guid" serialize:"json"`
+	Name       string `gorm:"size(256)" json:"name" serialize:"json"`
+	TypeName   string `gorm:"size(64)" json:"type" serialize:"json"`
+	Level      int    `gorm:"index:idx_fias_object_name" json:"level" serialize:"json"`
+	NameKey    string `gorm:"size(256); index:idx_fias_object_name" json:"-"`
+	RegionCode string `gorm:"size(2); index" json:"region_code" serialize:"json"`
+}
+
+type FiasHouse struct {
+	ID        uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	ObjectID  int64  `gorm:"uniqueIndex" json:"object_id" serialize:"json"`
+	GUID      string `gorm:"size(36); index" json:"guid" serialize:"json"`
+	Number    string `gorm:"size(64)" json:"number" serialize:"json"`
+	NumberKey string `gorm:"size(64); index" json:"-"`
+}
+
+type FiasLink struct {
+	ID       uint  `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	ObjectID int64 `gorm:"uniqueIndex" json:"object_id" serialize:"json"`
+	ParentID int64 `gorm:"index" json:"parent_id" serialize:"json"`
+}
+
+type DocumentRule struct {
+	ID        uint   `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	View      string `gorm:"size(256); uniqueIndex" json:"view" serialize:"json"`
+	Ages      string `gorm:"size(64)" json:"ages" serialize:"json"`
+	Years     int    `json:"years" serialize:"json"`
+	Expiry    bool   `json:"expiry" serialize:"json"`
+	GraceDays int    `json:"grace_days" serialize:"json"`
+}
+
+type CheckTemplate struct {
+	ID         uint                `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Name       string              `gorm:"size(256)" json:"name" serialize:"json"`
+	CategoryID uint                `gorm:"uniqueIndex:idx_check_template" json:"category_id" serialize:"json"`
+	RegionID   uint                `gorm:"uniqueIndex:idx_check_template" json:"region_id" serialize:"json"`
+	Items      []CheckTemplateItem `gorm:"foreignKey:TemplateID" json:"items"`
+	CreatedAt  time.Time           `json:"created" serialize:"json"`
+	UpdatedAt  time.Time           `json:"updated" serialize:"json"`
+}
+
+type CheckTemplateItem struct {
+	ID         uint     `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Position   int      `json:"position" serialize:"json"`
+	Label      string   `json:"label" serialize:"json"`
+	Kind       string   `gorm:"size(16)" json:"kind" serialize:"json"`
+	Options    []string `gorm:"serializer:json" json:"options" serialize:"json"`
+	Required   bool     `json:"required" serialize:"json"`
+	Help       string   `json:"help" serialize:"json"`
+	TemplateID uint     `gorm:"index" json:"template_id"`
+}
+
+type CheckItem struct {
+	ID       uint     `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Position int      `json:"position" serialize:"json"`
+	Label    string   `json:"label" serialize:"json"`
+	Kind     string   `gorm:"size(16)" json:"kind" serialize:"json"`
+	Options  []string `gorm:"serializer:json" json:"options" serialize:"json"`
+	Required bool     `json:"required" serialize:"json"`
+	Help     string   `json:"help" serialize:"json"`
+	Answer   string   `json:"answer" serialize:"json"`
+	CheckID  uint     `gorm:"index" json:"check_id"`
+}
+
+type ReportTemplate struct {
+	ID         uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Name       string    `gorm:"size(256)" json:"name" serialize:"json"`
+	FileName   string    `json:"file_name" serialize:"json"`
+	Checksum   string    `gorm:"size(64)" json:"checksum" serialize:"json"`
+	UploadedBy string    `gorm:"size(256)" json:"uploaded_by" serialize:"json"`
+	CreatedAt  time.Time `json:"created" serialize:"json"`
+}
+
+type Report struct {
+	ID         uint      `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Format     string    `gorm:"size(8)" json:"format" serialize:"json"`
+	FileName   string    `json:"file_name" serialize:"json"`
+	Checksum   string    `gorm:"size(64); index" json:"checksum" serialize:"json"`
+	Size       int64     `json:"size" serialize:"json"`
+	TemplateID uint      `json:"template_id" serialize:"json"`
+	CheckID    uint      `json:"check_id" serialize:"json"`
+	CreatedBy  string    `gorm:"size(256)" json:"created_by" serialize:"json"`
+	CreatedAt  time.Time `json:"created" serialize:"json"`
+	PersonID   uint      `gorm:"index"`
+}
+
+type Approval struct {
+	ID           uint       `gorm:"primaryKey; autoIncrement; not null; unique" json:"id" serialize:"json"`
+	Reason       string     `gorm:"size(16)" json:"reason" serialize:"json"`
+	GroupName    string     `gorm:"size(256); index" json:"group" serialize:"json"`
+	Status       string     `gorm:"size(16); index" json:"status" serialize:"json"`
+	ConclusionID uint       `json:"conclusion_id" serialize:"json"`
+	Officer      string     `gorm:"size(256)" json:"officer" serialize:"json"`
+	OfficerID    uint       `json:"officer_id" serialize:"json"`
+	Reviewer     string     `gorm:"size(256)" json:"reviewer" serialize:"json"`
+	ReviewerID   uint       `json:"reviewer_id" serialize:"json"`
+	Comment      string     `json:"comment" serialize:"json"`
+	DecidedAt    *time.Time `json:"decided" serialize:"json"`
+	CreatedAt    time.Time  `json:"created" serialize:"json"`
+	CheckID      uint       `gorm:"index" json:"check_id" serialize:"json"`
+	PersonID     uint       `gorm:"index" json:"person_id" serialize:"json"`
 }
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Organization names are written in many ways in anketas. The name key kept
+// next to them drops legal forms, quotes, punctuation and case, so the same
+// organization can be matched across persons.
+
+var legalForms = []string{
+	"федеральное государственное бюджетное учреждение",
+	"федеральное государственное унитарное предприятие",
+	"государственное бюджетное учреждение",
+	"государственное унитарное предприятие",
+	"муниципальное бюджетное учреждение",
+	"муниципальное унитарное предприятие",
+	"общество с ограниченной ответственностью",
+	"публичное акционерное общество",
+	"закрытое акционерное общество",
+	"открытое акционерное общество",
+	"непубличное акционерное общество",
+	"акционерное общество",
+	"автономная некоммерческая организация",
+	"некоммерческая организация",
+	"индивидуальный предприниматель",
+}
+
+var legalFormAbbreviations = map[string]bool{
+	"ооо": true, "оао": true, "зао": true, "пао": true, "ао": true, "нао": true,
+	"ип": true, "нко": true, "ано": true, "гуп": true, "муп": true, "фгуп": true,
+	"фгбу": true, "гбу": true, "мбу": true, "llc": true, "ltd": true, "inc": true,
+}
+
+func OrganizationKey(name string) string {
+	key := strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	key = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, key)
+	key = " " + strings.Join(strings.Fields(key), " ") + " "
+	for _, form := range legalForms {
+		key = strings.ReplaceAll(key, " "+form+" ", " ")
+	}
+	words := []string{}
+	for _, word := range strings.Fields(key) {
+		if !legalFormAbbreviations[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (workplace *Workplace) SetKeys() {
+	workplace.NameKey = OrganizationKey(workplace.Workplace)
+}
+
+func (workplace *Workplace) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Workplace); ok && dest != workplace {
+		dest.SetKeys()
+	}
+	workplace.SetKeys()
+	return nil
+}
+
+func (affilation *Affilation) SetKeys() {
+	affilation.NameKey = OrganizationKey(affilation.Name)
+}
+
+func (affilation *Affilation) BeforeSave(tx *gorm.DB) error {
+	if dest, ok := tx.Statement.Dest.(*Affilation); ok && dest != affilation {
+		dest.SetKeys()
+	}
+	affilation.SetKeys()
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestOrganizationKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{`ООО "Рога и копыта"`, "рога и копыта"},
+		{`Общество с ограниченной ответственностью «Рога и Копыта»`, "рога и копыта"},
+		{"рога и копыта, ооо", "рога и копыта"},
+		{`ПАО "Сбербанк России"`, "сбербанк россии"},
+		{"Публичное акционерное общество Сбербанк России", "сбербанк россии"},
+		{`ФГУП "Почта России"`, "почта россии"},
+		{"АО «Лёгкая промышленность-2»", "легкая промышленность 2"},
+		{"Стройтрест Ltd.", "стройтрест"},
+		{"Акционерный банк", "акционерный банк"},
+		{"  ", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := OrganizationKey(test.name); got != test.want {
+			t.Errorf("OrganizationKey(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -48,6 +49,13 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "migrate",
+				Usage: "Migrate database schema and data",
+				Action: func(c *cli.Context) error {
+					return utils.Migrate(database.OpenDb())
+				},
+			},
 			{
 				Name:  "start",
 				Usage: "Start server",
@@ -56,6 +64,88 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "purge",
+				Usage: "Purge persons kept in trash longer than retention period",
+				Action: func(c *cli.Context) error {
+					count, err := utils.PurgeTrash(database.OpenDb())
+					log.Printf("purged %d persons", count)
+					return err
+				},
+			},
+			{
+				Name:  "retention",
+				Usage: "Anonymize or purge persons past their retention period",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "dry-run", Usage: "Only report expired persons"},
+				},
+				Action: func(c *cli.Context) error {
+					run, err := utils.RunRetention(database.OpenDb(), c.Bool("dry-run"), "cli")
+					if err != nil {
+						return err
+					}
+					log.Printf("retention run %d: %d persons, %d failed", run.ID, run.Persons, run.Failed)
+					log.Println(run.Report)
+					return nil
+				},
+			},
+			{
+				Name:  "export",
+				Usage: "Export pseudonymized analytics to CSV files",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "dir", Value: "analytics", Usage: "Output directory"},
+				},
+				Action: func(c *cli.Context) error {
+					return exportAnalytics(c.String("dir"))
+				},
+			},
+			{
+				Name:  "rotate-keys",
+				Usage: "Re-encrypt sensitive fields with the active encryption key",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "batch", Value: 500, Usage: "Rows per batch"},
+				},
+				Action: func(c *cli.Context) error {
+					count, err := utils.RotateKeys(database.OpenDb(), c.Int("batch"))
+					log.Printf("re-encrypted %d rows", count)
+					return err
+				},
+			},
+			{
+				Name:  "import-gar",
+				Usage: "Import a FIAS/GAR extract for address normalization",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "dir", Required: true, Usage: "Folder of the unpacked extract"},
+					&cli.IntFlag{Name: "batch", Value: 1000, Usage: "Records per transaction"},
+				},
+				Action: func(c *cli.Context) error {
+					stats, err := utils.ImportGar(database.OpenDb(), c.String("dir"), c.Int("batch"))
+					log.Printf("imported %d objects, %d houses, %d links", stats.Objects, stats.Houses, stats.Links)
+					return err
+				},
+			},
+			{
+				Name:  "normalize-addresses",
+				Usage: "Fill region, city, street, house and flat of saved addresses",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "batch", Value: 500, Usage: "Rows per batch"},
+					&cli.BoolFlag{Name: "all", Usage: "Normalize addresses that already have parts"},
+				},
+				Action: func(c *cli.Context) error {
+					count, err := utils.NormalizeAddresses(database.OpenDb(), c.Int("batch"), c.Bool("all"))
+					log.Printf("normalized %d addresses", count)
+					return err
+				},
+			},
+			{
+				Name:  "index-contacts",
+				Usage: "Rebuild the index of shared phones, emails and addresses",
+				Action: func(c *cli.Context) error {
+					count, err := utils.IndexAllContactPoints(database.OpenDb())
+					log.Printf("indexed %d contact points", count)
+					return err
+				},
+			},
 			{
 				Name:  "test",
 				Usage: "Test cli command",
@@ -105,8 +195,27 @@ func start() {
 	routes.PublicRoutes(app)
 	routes.FileRoutes(app)
 	routes.ConnectRoutes(app)
+	routes.WorkflowRoutes(app)
+	routes.VersionRoutes(app)
+	routes.TrashRoutes(app)
+	routes.RetentionRoutes(app)
+	routes.BulkRoutes(app)
+	routes.TagRoutes(app)
+	routes.WatchRoutes(app)
+	routes.CommentRoutes(app)
+	routes.SlaRoutes(app)
+	routes.ConflictRoutes(app)
+	routes.LinkRoutes(app)
+	routes.DocumentRoutes(app)
+	routes.TimelineRoutes(app)
+	routes.TemplateRoutes(app)
+	routes.ReportRoutes(app)
+	routes.ApprovalRoutes(app)
 	routes.NotFoundRoute(app)
 
+	// Background jobs.
+	utils.StartScheduler()
+
 	log.Fatal(app.Listen(":3000"))
 }
 
@@ -137,44 +246,11 @@ func createDefault() {
 	}
 
 	db := database.OpenDb()
-	err = db.AutoMigrate(
-		&models.Group{}, &models.Role{}, &models.User{}, &models.Message{},
-		&models.Region{}, &models.Category{}, &models.Status{},
-		&models.Person{}, &models.Document{}, &models.Address{}, &models.Workplace{},
-		&models.Contact{}, &models.Staff{}, &models.Affilation{}, &models.Relation{},
-		&models.Conclusion{}, &models.Check{}, &models.Poligraf{},
-		&models.Investigation{}, &models.Inquiry{}, &models.Connection{},
-	)
+	err = utils.Migrate(db)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	for _, region := range utils.Regions {
-		db.Create(&models.Region{
-			NameRegion: region,
-		})
-	}
-	for _, status := range utils.Statuses {
-		db.Create(&models.Status{
-			NameStatus: status,
-		})
-	}
-	for _, category := range utils.Categories {
-		db.Create(&models.Category{
-			NameCategory: category,
-		})
-	}
-	for _, group := range utils.Groups {
-		db.Create(&models.Group{
-			NameGroup: group,
-		})
-	}
-	for _, role := range utils.Roles {
-		db.Create(&models.Role{
-			NameRole: role,
-		})
-	}
-
 	user := models.User{
 		UserName: "superadmin",
 		Password: utils.GeneratePassword("88888888"),
@@ -189,22 +265,54 @@ func createDefault() {
 
 	db.Create(&user)
 
-	db.Create(&models.Person{
+	person := models.Person{
 		CategoryID:       models.Category.GetID(models.Category{}, utils.Categories["candidate"]),
 		RegionID:         models.Region.GetID(models.Region{}, utils.Regions["MAIN_OFFICE"]),
 		FullName:         "Бендер Остап Сулеман",
 		PreviousFullName: "Ильф и Петров",
-		BirthDate:        time.Now().Format("2006-01-02"),
+		BirthDate:        models.NewDate(time.Now().AddDate(-30, 0, 0)),
 		BirthPlace:       "г.Нью-Васюки",
 		Citizen:          "Россия",
 		ExCitizen:        "Турция",
-		Snils:            "12345678901",
-		Inn:              "123456789012",
-		Education:        "Университет Джордано Бруно",
+		Snils:            "12345678964",
+		Inn:              "123456789047",
 		MaritalStatus:    "женат",
 		AdditionalInfo:   "Холодный философ и свободный художник",
 		PathToDocs:       basePath,
 		StatusID:         models.Status.GetID(models.Status{}, utils.Statuses["new"]),
+	}
+	utils.NormalizePersonName(&person)
+	db.Create(&person)
+	db.Create(&models.Education{
+		View:        "Высшее",
+		Institution: "Университет Джордано Бруно",
+		PersonID:    person.ID,
 	})
 	log.Println("done")
 }
+
+func exportAnalytics(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	files := []*os.File{}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	err := utils.ExportAnalytics(database.OpenDb(), func(name string) (io.Writer, error) {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		return file, nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("analytics exported to %s", dir)
+	return nil
+}
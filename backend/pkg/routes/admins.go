@@ -44,4 +44,10 @@ func AdminRoutes(a *fiber.App) {
 	)
 	tableGroup.Post("/:page", controllers.PostTablesRows)
 	tableGroup.Delete("/:item_id", controllers.DelTableRows)
+
+	a.Get(
+		"/export/analytics",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.GetAnalytics,
+	)
 }
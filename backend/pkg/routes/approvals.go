@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func ApprovalRoutes(a *fiber.App) {
+
+	reviewerAuth := middlewares.AuthRequired([]string{"user"}, []string{"reviewers", "vip_reviewers"})
+	a.Get("/approvals", reviewerAuth, controllers.GetApprovals)
+	a.Post("/approvals/:id/:decision", reviewerAuth, controllers.PostApproval)
+
+	a.Get(
+		"/approvals/person/:person_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetPersonApprovals,
+	)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func BulkRoutes(a *fiber.App) {
+
+	bulkGroup := a.Group(
+		"/bulk",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	bulkGroup.Post("/", controllers.PostBulk)
+	bulkGroup.Get("/jobs/:job_id", controllers.GetBulkJob)
+
+	filterGroup := a.Group(
+		"/filters",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	filterGroup.Get("/", controllers.GetFilters)
+	filterGroup.Post("/", controllers.PostFilter)
+	filterGroup.Delete("/:filter_id", controllers.DeleteFilter)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func CommentRoutes(a *fiber.App) {
+
+	commentsGroup := a.Group(
+		"/comments/:item/:item_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	commentsGroup.Get("/", controllers.GetComments)
+	commentsGroup.Post("/", controllers.PostComment)
+
+	commentGroup := a.Group(
+		"/comment/:comment_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	commentGroup.Patch("/", controllers.PatchComment)
+	commentGroup.Delete("/", controllers.DeleteComment)
+	commentGroup.Get("/:file", controllers.GetCommentFile)
+}
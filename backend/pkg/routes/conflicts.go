@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func ConflictRoutes(a *fiber.App) {
+
+	conflictGroup := a.Group(
+		"/conflicts",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	conflictGroup.Get("/", controllers.GetConflictReport)
+	conflictGroup.Get("/:person_id", controllers.GetPersonConflicts)
+}
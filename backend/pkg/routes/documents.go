@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func DocumentRoutes(a *fiber.App) {
+
+	rulesGroup := a.Group(
+		"/documents/rules",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+	)
+	rulesGroup.Get("/", controllers.GetDocumentRules)
+	rulesGroup.Post("/", controllers.PostDocumentRule)
+	rulesGroup.Delete("/:id", controllers.DeleteDocumentRule)
+
+	a.Get(
+		"/documents/report",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetDocumentReport,
+	)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func LinkRoutes(a *fiber.App) {
+
+	linkGroup := a.Group(
+		"/links",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	linkGroup.Get("/", controllers.GetLinkReport)
+	linkGroup.Get("/:person_id", controllers.GetPersonLinks)
+}
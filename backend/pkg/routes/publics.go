@@ -28,63 +28,60 @@ func PublicRoutes(a *fiber.App) {
 		"/staff/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	staffGroup.Get("/", controllers.GetStaffs)
-	staffGroup.Post("/", controllers.PostStaffs)
-	staffGroup.Patch("/", controllers.PatchStaffs)
-	staffGroup.Delete("/", controllers.DeleteStaffs)
+	controllers.Staffs.Register(staffGroup)
 
-	docsGroup := a.Group("/document/:action/:item_id",
+	docsGroup := a.Group(
+		"/document/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	docsGroup.Get("/", controllers.GetDocs)
-	docsGroup.Post("/", controllers.PostDocs)
-	docsGroup.Delete("/", controllers.DeleteDocs)
-	docsGroup.Patch("/", controllers.PatchDocs)
+	controllers.Documents.Register(docsGroup)
 
 	addressGroup := a.Group(
 		"/address/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	addressGroup.Get("/", controllers.GetAddress)
-	addressGroup.Post("/", controllers.PostAddress)
-	addressGroup.Delete("/", controllers.DeleteAddress)
-	addressGroup.Patch("/", controllers.PatchAddress)
+	controllers.Addresses.Register(addressGroup)
 
 	contactGroup := a.Group(
 		"/contact/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	contactGroup.Get("/", controllers.GetContact)
-	contactGroup.Post("/", controllers.PostContact)
-	contactGroup.Delete("/", controllers.DeleteContact)
-	contactGroup.Patch("/", controllers.PatchContact)
+	controllers.Contacts.Register(contactGroup)
 
 	workGroup := a.Group(
 		"/workplace/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	workGroup.Get("/", controllers.GetWorkplace)
-	workGroup.Post("/", controllers.PostWorkplace)
-	workGroup.Delete("/", controllers.DeleteWorkplace)
-	workGroup.Patch("/", controllers.PatchWorkplace)
+	controllers.Workplaces.Register(workGroup)
+
+	nameGroup := a.Group(
+		"/name/:action/:item_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	controllers.NameChanges.Register(nameGroup)
+
+	educationGroup := a.Group(
+		"/education/:action/:item_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	controllers.Educations.Register(educationGroup)
 
 	affilationGroup := a.Group(
 		"/affilation/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	affilationGroup.Get("/", controllers.GetAffilation)
-	affilationGroup.Post("/", controllers.PostAffilation)
-	affilationGroup.Delete("/", controllers.DeleteAffilation)
-	affilationGroup.Patch("/", controllers.PatchAffilation)
+	controllers.Affilations.Register(affilationGroup)
 
 	relationGroup := a.Group(
 		"/relation/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	relationGroup.Get("/", controllers.GetRelation)
-	relationGroup.Post("/", controllers.PostRelation)
-	relationGroup.Delete("/", controllers.DeleteRelation)
-	relationGroup.Patch("/", controllers.PatchRelation)
+	controllers.Relations.Register(relationGroup)
+	a.Get(
+		"/graph/:person_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetGraph,
+	)
 
 	checkGroup := a.Group(
 		"/check/:action/:item_id",
@@ -106,28 +103,19 @@ func PublicRoutes(a *fiber.App) {
 		"/investigation/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	investigationGroup.Get("/", controllers.GetInvestigation)
-	investigationGroup.Post("/", controllers.PostInvestigation)
-	investigationGroup.Patch("/", controllers.PatchInvestigation)
-	investigationGroup.Delete("/", controllers.DeleteInvestigation)
+	controllers.Investigations.Register(investigationGroup)
 
 	poligrafGroup := a.Group(
 		"/poligraf/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	poligrafGroup.Get("/", controllers.GetPoligraf)
-	poligrafGroup.Post("/", controllers.PostPoligraf)
-	poligrafGroup.Patch("/", controllers.PatchPoligraf)
-	poligrafGroup.Delete("/", controllers.DeletePoligraf)
+	controllers.Poligrafs.Register(poligrafGroup)
 
 	inquiryGroup := a.Group(
 		"/inquiry/:action/:item_id",
 		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
 	)
-	inquiryGroup.Get("/", controllers.GetInquiry)
-	inquiryGroup.Post("/", controllers.PostInquiry)
-	inquiryGroup.Patch("/", controllers.PatchInquiry)
-	inquiryGroup.Delete("/", controllers.DeleteInquiry)
+	controllers.Inquiries.Register(inquiryGroup)
 
 	fileGroup := a.Group(
 		"/file/:action/:item_id",
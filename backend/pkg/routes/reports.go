@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func ReportRoutes(a *fiber.App) {
+
+	templateGroup := a.Group(
+		"/reports/templates",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+	)
+	templateGroup.Get("/", controllers.GetReportTemplates)
+	templateGroup.Post("/", controllers.PostReportTemplate)
+	templateGroup.Delete("/:id", controllers.DeleteReportTemplate)
+
+	userAuth := middlewares.AuthRequired([]string{"user"}, []string{"staffsec"})
+	a.Get("/reports/:person_id", userAuth, controllers.GetReports)
+	a.Post("/reports/:person_id", userAuth, controllers.PostReport)
+	a.Get("/reports/:person_id/:id", userAuth, controllers.GetReportFile)
+}
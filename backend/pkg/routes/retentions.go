@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func RetentionRoutes(a *fiber.App) {
+
+	retentionGroup := a.Group(
+		"/retention",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+	)
+	retentionGroup.Get("/rules", controllers.GetRetentionRules)
+	retentionGroup.Post("/rules", controllers.PostRetentionRule)
+	retentionGroup.Delete("/rules/:id", controllers.DeleteRetentionRule)
+	retentionGroup.Get("/runs/:page", controllers.GetRetentionRuns)
+	retentionGroup.Post("/run", controllers.PostRetentionRun)
+}
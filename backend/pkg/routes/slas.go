@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func SlaRoutes(a *fiber.App) {
+
+	rulesGroup := a.Group(
+		"/sla/rules",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+	)
+	rulesGroup.Get("/", controllers.GetSlaRules)
+	rulesGroup.Post("/", controllers.PostSlaRule)
+	rulesGroup.Delete("/:id", controllers.DeleteSlaRule)
+
+	a.Get(
+		"/sla/:action",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetSla,
+	)
+}
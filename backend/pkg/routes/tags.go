@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func TagRoutes(a *fiber.App) {
+
+	a.Get(
+		"/tags",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetTags,
+	)
+
+	a.Post(
+		"/tags",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.PostTag,
+	)
+	a.Patch(
+		"/tags/:tag_id",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.PatchTag,
+	)
+	a.Delete(
+		"/tags/:tag_id",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.DeleteTag,
+	)
+
+	tagGroup := a.Group(
+		"/tag/:person_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	tagGroup.Get("/", controllers.GetPersonTags)
+	tagGroup.Post("/:tag_id", controllers.PostPersonTag)
+	tagGroup.Delete("/:tag_id", controllers.DeletePersonTag)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func TemplateRoutes(a *fiber.App) {
+
+	templateGroup := a.Group(
+		"/templates",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+	)
+	templateGroup.Get("/", controllers.GetCheckTemplates)
+	templateGroup.Post("/", controllers.PostCheckTemplate)
+	templateGroup.Patch("/:id", controllers.PatchCheckTemplate)
+	templateGroup.Delete("/:id", controllers.DeleteCheckTemplate)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func TimelineRoutes(a *fiber.App) {
+
+	a.Get(
+		"/timeline/:person_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetTimeline,
+	)
+}
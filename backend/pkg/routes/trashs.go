@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func TrashRoutes(a *fiber.App) {
+
+	trashGroup := a.Group(
+		"/trash",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	trashGroup.Get("/:page", controllers.GetTrash)
+	trashGroup.Get("/restore/:person_id", controllers.GetRestore)
+
+	a.Delete(
+		"/purge/:person_id",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.DeletePurge,
+	)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func VersionRoutes(a *fiber.App) {
+
+	a.Get(
+		"/history/:action/:item_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+		controllers.GetVersions,
+	)
+
+	a.Post(
+		"/revert/:item_id",
+		middlewares.AuthRequired([]string{"admin"}, []string{"admins"}),
+		controllers.PostRevert,
+	)
+}
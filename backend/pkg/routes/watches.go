@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func WatchRoutes(a *fiber.App) {
+
+	watchGroup := a.Group(
+		"/watch",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	watchGroup.Get("/", controllers.GetWatches)
+	watchGroup.Post("/:person_id", controllers.PostWatch)
+	watchGroup.Delete("/:person_id", controllers.DeleteWatch)
+}
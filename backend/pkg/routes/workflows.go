@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/app/controllers"
+	"backend/pkg/middlewares"
+)
+
+func WorkflowRoutes(a *fiber.App) {
+
+	workflowGroup := a.Group(
+		"/workflow/:action/:item_id",
+		middlewares.AuthRequired([]string{"user"}, []string{"staffsec"}),
+	)
+	workflowGroup.Get("/", controllers.GetWorkflow)
+	workflowGroup.Post("/", controllers.PostWorkflow)
+}
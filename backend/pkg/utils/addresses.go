@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Address parts are written as "<name> <type>" for regions, "<type> <name>"
+// for localities and streets, and without types for cities, houses and
+// flats: "Московская обл", "Москва", "ул Ленина", "5 к 2", "12".
+
+const (
+	fiasRegionLevel   = 1
+	fiasCityLevel     = 5
+	fiasLocalityLevel = 6
+	fiasStreetLevel   = 8
+)
+
+var addressTypes map[string]struct{ part, short string }
+
+func init() {
+	addressTypes = map[string]struct{ part, short string }{}
+	for part, names := range map[string]map[string][]string{
+		"region": {
+			"обл":  {"обл", "область"},
+			"край": {"край"},
+			"респ": {"респ", "республика"},
+			"ао":   {"ао", "округ"},
+			"аобл": {"аобл"},
+		},
+		"city": {
+			"г": {"г", "город"},
+		},
+		"locality": {
+			"п":     {"п", "пос", "поселок", "посёлок"},
+			"пгт":   {"пгт"},
+			"рп":    {"рп"},
+			"с":     {"с", "село"},
+			"д":     {"д", "дер", "деревня"},
+			"ст-ца": {"ст-ца", "станица"},
+			"х":     {"х", "хутор"},
+			"снт":   {"снт"},
+		},
+		"street": {
+			"ул":     {"ул", "улица"},
+			"пр-кт":  {"пр-кт", "пр", "просп", "проспект"},
+			"пер":    {"пер", "переулок"},
+			"б-р":    {"б-р", "бульвар"},
+			"ш":      {"ш", "шоссе"},
+			"наб":    {"наб", "набережная"},
+			"пл":     {"пл", "площадь"},
+			"проезд": {"проезд", "пр-д"},
+			"мкр":    {"мкр", "микрорайон"},
+			"туп":    {"туп", "тупик"},
+			"аллея":  {"аллея"},
+			"линия":  {"линия"},
+		},
+		"house":    {"": {"дом", "влд", "владение"}},
+		"corpus":   {"к": {"к", "корп", "корпус"}},
+		"building": {"стр": {"стр", "строение"}},
+		"flat":     {"": {"кв", "квартира", "оф", "офис", "пом", "помещение", "комн", "комната"}},
+	} {
+		for short, words := range names {
+			for _, word := range words {
+				addressTypes[word] = struct{ part, short string }{part, short}
+			}
+		}
+	}
+}
+
+// FederalCities are cities that are regions on their own.
+var FederalCities []string = []string{"Москва", "Санкт-Петербург", "Севастополь"}
+
+var glued = regexp.MustCompile(`([а-яёА-ЯЁ])\.(\S)`)
+
+type AddressParts struct {
+	Region   string `json:"region"`
+	City     string `json:"city"`
+	Street   string `json:"street"`
+	House    string `json:"house"`
+	Flat     string `json:"flat"`
+	FiasGUID string `json:"fias_guid"`
+}
+
+type addressSegment struct {
+	part  string
+	short string
+	words []string
+}
+
+// ParseAddress splits a free-text address into its parts by the type words.
+// Parts without a type are guessed from their position and content.
+func ParseAddress(text string) AddressParts {
+	parts := AddressParts{}
+	segments := []addressSegment{}
+	for _, piece := range strings.Split(glued.ReplaceAllString(text, "$1. $2"), ",") {
+		words := strings.Fields(piece)
+		current := addressSegment{}
+		for i, word := range words {
+			kind, ok := addressTypes[strings.ToLower(strings.TrimSuffix(word, "."))]
+			if !ok {
+				current.words = append(current.words, strings.Trim(word, ".\"«»"))
+				continue
+			}
+			// "Московская обл", "Ленина ул": the type follows the name.
+			if current.part == "" && len(current.words) > 0 && i == len(words)-1 {
+				current.part, current.short = kind.part, kind.short
+				continue
+			}
+			if current.part != "" || len(current.words) > 0 {
+				segments = append(segments, current)
+			}
+			current = addressSegment{part: kind.part, short: kind.short}
+		}
+		if current.part != "" || len(current.words) > 0 {
+			segments = append(segments, current)
+		}
+	}
+
+	for _, segment := range segments {
+		name := strings.Join(segment.words, " ")
+		if name == "" {
+			continue
+		}
+		startsWithDigit := unicode.IsDigit([]rune(name)[0])
+		part := segment.part
+		// "д" is both a village and a house.
+		if part == "locality" && segment.short == "д" && startsWithDigit {
+			part = "house"
+		}
+
+		switch part {
+		case "region":
+			parts.Region = name + " " + segment.short
+		case "city":
+			parts.City = name
+		case "locality":
+			if parts.City == "" {
+				parts.City = segment.short + " " + name
+			}
+		case "street":
+			parts.Street = segment.short + " " + name
+		case "house":
+			parts.House = name
+		case "corpus", "building":
+			parts.House = strings.TrimSpace(parts.House + " " + segment.short + " " + name)
+		case "flat":
+			parts.Flat = name
+		default:
+			switch {
+			case startsWithDigit && len(name) == 6 && strings.Trim(name, "0123456789") == "":
+				// postal code
+			case startsWithDigit && parts.House == "":
+				parts.House = name
+			case startsWithDigit && parts.Flat == "":
+				parts.Flat = name
+			case startsWithDigit:
+			case strings.EqualFold(name, "Россия") || strings.EqualFold(name, "Российская Федерация"):
+			case parts.City == "":
+				parts.City = name
+			case parts.Street == "":
+				parts.Street = name
+			}
+		}
+	}
+
+	for _, city := range FederalCities {
+		if parts.Region == "" && strings.EqualFold(parts.City, city) {
+			parts.Region = city
+		}
+	}
+	return parts
+}
+
+// ResolveAddress parses the address and matches its parts with the
+// imported FIAS objects. Matched parts take the FIAS spelling and the GUID of
+// the most precise match. Without FIAS data the parsed parts are returned.
+func ResolveAddress(db *gorm.DB, text string) AddressParts {
+	parts := ParseAddress(text)
+
+	var region models.FiasObject
+	if parts.Region != "" {
+		db.
+			Where("level = ? AND name_key = ?", fiasRegionLevel, FiasKey(addressName(parts.Region, true))).
+			First(&region)
+		if region.ID != 0 {
+			parts.Region = fiasRegionName(region)
+			parts.FiasGUID = region.GUID
+		}
+	}
+
+	var city models.FiasObject
+	if parts.City != "" {
+		query := db.
+			Where("level IN ?", []int{fiasRegionLevel, fiasCityLevel, fiasLocalityLevel}).
+			Where("name_key = ?", FiasKey(addressName(parts.City, false)))
+		if region.ID != 0 {
+			query = query.Where("region_code = ?", region.RegionCode)
+		}
+		city = uniqueFiasObject(query)
+		if city.ID != 0 {
+			parts.City = city.Name
+			if city.Level == fiasLocalityLevel {
+				parts.City = city.TypeName + " " + city.Name
+			}
+			parts.FiasGUID = city.GUID
+			if region.ID == 0 {
+				db.Where("level = ? AND region_code = ?", fiasRegionLevel, city.RegionCode).First(&region)
+				if region.ID != 0 {
+					parts.Region = fiasRegionName(region)
+				}
+			}
+		}
+	}
+
+	var street models.FiasObject
+	if parts.Street != "" && city.ID != 0 {
+		var candidates []models.FiasObject
+		db.
+			Where("level = ? AND name_key = ? AND region_code = ?", fiasStreetLevel, FiasKey(addressName(parts.Street, false)), city.RegionCode).
+			Find(&candidates)
+		for _, candidate := range candidates {
+			if fiasDescends(db, candidate.ObjectID, city.ObjectID) {
+				street = candidate
+				break
+			}
+		}
+		if street.ID != 0 {
+			parts.Street = street.TypeName + " " + street.Name
+			parts.FiasGUID = street.GUID
+		}
+	}
+
+	if parts.House != "" && street.ID != 0 {
+		var house models.FiasHouse
+		db.
+			Where("number_key = ?", HouseKey(parts.House)).
+			Where("object_id IN (SELECT object_id FROM fias_links WHERE parent_id = ?)", street.ObjectID).
+			First(&house)
+		if house.ID != 0 {
+			parts.House = house.Number
+			parts.FiasGUID = house.GUID
+		}
+	}
+	return parts
+}
+
+// AddressPartColumns are the columns NormalizeAddressRecord fills.
+var AddressPartColumns []string = []string{"region", "city", "street", "house", "flat", "fias_guid"}
+
+// NormalizeAddressRecord fills the parts of the address from its text. Parts
+// not found in the text are cleared, so none are left from a previous address.
+func NormalizeAddressRecord(db *gorm.DB, address *models.Address) {
+	parts := AddressParts{}
+	if strings.TrimSpace(address.Address) != "" {
+		parts = ResolveAddress(db, address.Address)
+	}
+	address.Region = parts.Region
+	address.City = parts.City
+	address.Street = parts.Street
+	address.House = parts.House
+	address.Flat = parts.Flat
+	address.FiasGUID = parts.FiasGUID
+}
+
+// NormalizeAddresses backfills the parts of addresses saved as text only.
+// With all set every address is normalized again, for example after a newer
+// FIAS extract was imported.
+func NormalizeAddresses(db *gorm.DB, batch int, all bool) (int, error) {
+	query := db.Model(&models.Address{})
+	if !all {
+		query = query.Where("city IS NULL OR city = ''")
+	}
+	count := 0
+	var addresses []models.Address
+	err := query.FindInBatches(&addresses, batch, func(tx *gorm.DB, _ int) error {
+		for _, address := range addresses {
+			NormalizeAddressRecord(db, &address)
+			if err := SaveAddressParts(db, &address); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}).Error
+	return count, err
+}
+
+// SaveAddressParts writes the normalized parts, the empty ones included.
+func SaveAddressParts(db *gorm.DB, address *models.Address) error {
+	return db.
+		Model(address).
+		Select(AddressPartColumns).
+		Updates(address).
+		Error
+}
+
+// addressName drops the type word of a parsed part.
+func addressName(part string, typeLast bool) string {
+	words := strings.Fields(part)
+	if len(words) < 2 {
+		return part
+	}
+	if typeLast {
+		if _, ok := addressTypes[strings.ToLower(words[len(words)-1])]; ok {
+			return strings.Join(words[:len(words)-1], " ")
+		}
+		return part
+	}
+	if _, ok := addressTypes[strings.ToLower(words[0])]; ok {
+		return strings.Join(words[1:], " ")
+	}
+	return part
+}
+
+func fiasRegionName(region models.FiasObject) string {
+	if region.TypeName == "г" {
+		return region.Name
+	}
+	return region.Name + " " + region.TypeName
+}
+
+// uniqueFiasObject returns the object if the query matches exactly one.
+func uniqueFiasObject(query *gorm.DB) models.FiasObject {
+	var objects []models.FiasObject
+	query.Limit(2).Find(&objects)
+	if len(objects) != 1 {
+		return models.FiasObject{}
+	}
+	return objects[0]
+}
+
+// fiasDescends reports whether the object lies under the ancestor in the
+// administrative hierarchy.
+func fiasDescends(db *gorm.DB, objectID int64, ancestorID int64) bool {
+	for i := 0; i < 10 && objectID != 0; i++ {
+		var link models.FiasLink
+		db.Where("object_id = ?", objectID).First(&link)
+		if link.ParentID == ancestorID {
+			return true
+		}
+		objectID = link.ParentID
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		text string
+		want AddressParts
+	}{
+		{
+			"г. Москва, ул. Ленина, д. 5, кв. 12",
+			AddressParts{Region: "Москва", City: "Москва", Street: "ул Ленина", House: "5", Flat: "12"},
+		},
+		{
+			"101000, Россия, г.Москва, ул.Ленина, д.5, корп.2, стр.1, кв.12",
+			AddressParts{Region: "Москва", City: "Москва", Street: "ул Ленина", House: "5 к 2 стр 1", Flat: "12"},
+		},
+		{
+			"Московская обл, г Подольск, проспект Ленина, 5, 12",
+			AddressParts{Region: "Московская обл", City: "Подольск", Street: "пр-кт Ленина", House: "5", Flat: "12"},
+		},
+		{
+			"Тверская область, деревня Горки, д 7",
+			AddressParts{Region: "Тверская обл", City: "д Горки", House: "7"},
+		},
+		{
+			"Республика Татарстан, Казань, Баумана ул, 3",
+			AddressParts{Region: "Татарстан респ", City: "Казань", Street: "ул Баумана", House: "3"},
+		},
+		{
+			"Санкт-Петербург, Невский, 10",
+			AddressParts{Region: "Санкт-Петербург", City: "Санкт-Петербург", Street: "Невский", House: "10"},
+		},
+		{"", AddressParts{}},
+	}
+	for _, test := range tests {
+		if got := ParseAddress(test.text); got != test.want {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// Denials and conclusions on VIP persons need a second reviewer. Such a check
+// moves the person to the approval status instead of finish, the reviewers of
+// the group get a message, and the person is finished only after one of them
+// approves. A returned conclusion goes back to the officer with a comment.
+
+// Approval states.
+const (
+	ApprovalPending   = "pending"
+	ApprovalApproved  = "approved"
+	ApprovalReturned  = "returned"
+	ApprovalWithdrawn = "withdrawn"
+)
+
+// ApprovalGroups maps an approval reason to the group of its reviewers.
+var ApprovalGroups map[string]string = map[string]string{
+	"denied": Groups["reviewers"],
+	"vip":    Groups["vip_reviewers"],
+}
+
+var approvalReasons map[string]string = map[string]string{
+	"denied": "отказ в согласовании",
+	"vip":    "проверка ВИП",
+}
+
+// ApprovalTask is an approval with the name of the person for the queues.
+type ApprovalTask struct {
+	models.Approval
+	FullName string `json:"fullname"`
+}
+
+// ApprovalReason returns why the conclusion of the check needs a second
+// reviewer, "vip" before "denied", or an empty string.
+func ApprovalReason(person models.Person, check models.Check) string {
+	if check.ID == 0 {
+		return ""
+	}
+	conclusion := ConclusionKey(check.ConclusionID)
+	if InterimConclusion(conclusion) {
+		return ""
+	}
+	if person.CategoryID != 0 && person.CategoryID == (models.Category{}).GetID(Categories["vip"]) {
+		return "vip"
+	}
+	if conclusion == "denied" {
+		return "denied"
+	}
+	return ""
+}
+
+// CheckApproved returns an error while the latest final conclusion of the
+// person needs an approval that is not recorded.
+func CheckApproved(db *gorm.DB, person models.Person) error {
+	check := concludedCheck(db, person.ID)
+	if ApprovalReason(person, check) == "" || conclusionApproved(db, check) {
+		return nil
+	}
+	return errors.New("заключение требует согласования")
+}
+
+// FinishCheck finishes the person or, when the latest final conclusion needs a
+// second reviewer, sends it for approval.
+func FinishCheck(db *gorm.DB, person *models.Person, user *middlewares.TokenMetadata, reason string) error {
+	check := concludedCheck(db, person.ID)
+	if approvalReason := ApprovalReason(*person, check); approvalReason != "" && !conclusionApproved(db, check) {
+		return RequestApproval(db, person, check, approvalReason, user)
+	}
+	return ChangeStatus(db, person, "finish", user, reason)
+}
+
+// RequestApproval replaces the pending approvals of the person with a new one
+// and notifies the reviewers of the group. The officer is never asked to
+// approve their own conclusion. The person has to be in a status that may
+// finish.
+func RequestApproval(db *gorm.DB, person *models.Person, check models.Check, reason string, user *middlewares.TokenMetadata) error {
+	from := StatusKey(person.StatusID)
+	if from != "approval" && !CanTransit(from, "finish") {
+		return fmt.Errorf("transition from %q to %q is not allowed", from, "approval")
+	}
+	approval := models.Approval{
+		Reason:       reason,
+		GroupName:    ApprovalGroups[reason],
+		Status:       ApprovalPending,
+		ConclusionID: check.ConclusionID,
+		Officer:      check.Officer,
+		CheckID:      check.ID,
+		PersonID:     person.ID,
+	}
+	if user != nil {
+		approval.Officer, approval.OfficerID = user.FullName, user.UserID
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := WithdrawApprovals(tx, person.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&approval).Error; err != nil {
+			return err
+		}
+		if from == "approval" {
+			return nil
+		}
+		return moveStatus(tx, person, from, "approval", user, "Заключение направлено на согласование")
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, reviewer := range groupUsers(db, approval.GroupName) {
+		if reviewer.ID == approval.OfficerID {
+			continue
+		}
+		db.Create(&models.Message{
+			Title:          "Согласование заключения",
+			MessageContent: fmt.Sprintf("%s: %s, исполнитель %s", person.FullName, approvalReasons[reason], approval.Officer),
+			StatusRead:     "new",
+			UserID:         reviewer.ID,
+		})
+	}
+	return nil
+}
+
+// DecideApproval records the decision of the reviewer and moves the person to
+// finish when approved or back to the check when returned.
+func DecideApproval(db *gorm.DB, approval *models.Approval, approve bool, comment string, user *middlewares.TokenMetadata) error {
+	if approval.Status != ApprovalPending {
+		return errors.New("согласование уже завершено")
+	}
+	if approval.OfficerID != 0 && approval.OfficerID == user.UserID {
+		return errors.New("нельзя согласовать собственное заключение")
+	}
+	if !userInGroup(user, approval.GroupName) {
+		return errors.New("нет прав на согласование")
+	}
+
+	now := time.Now()
+	approval.Status = ApprovalReturned
+	if approve {
+		approval.Status = ApprovalApproved
+	}
+	approval.Reviewer, approval.ReviewerID = user.FullName, user.UserID
+	approval.Comment, approval.DecidedAt = comment, &now
+
+	var person models.Person
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(approval).Error; err != nil {
+			return err
+		}
+		tx.First(&person, approval.PersonID)
+		if approve {
+			return ChangeStatus(tx, &person, "finish", user, "Заключение согласовано")
+		}
+		return ChangeStatus(tx, &person, "manual", user, "Заключение возвращено: "+comment)
+	})
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("%s: заключение согласовано", person.FullName)
+	if !approve {
+		content = fmt.Sprintf("%s: заключение возвращено, %s", person.FullName, comment)
+	}
+	if approval.OfficerID != 0 {
+		db.Create(&models.Message{
+			Title:          "Согласование заключения",
+			MessageContent: content,
+			StatusRead:     "new",
+			UserID:         approval.OfficerID,
+		})
+	}
+	return nil
+}
+
+// WithdrawApprovals closes the pending approvals of the person, for example
+// when the check is changed or the person leaves the approval status.
+func WithdrawApprovals(db *gorm.DB, personID uint) error {
+	return db.
+		Model(&models.Approval{}).
+		Where("person_id = ? AND status = ?", personID, ApprovalPending).
+		Update("status", ApprovalWithdrawn).
+		Error
+}
+
+// ApprovalQueue returns the pending approvals the user may decide.
+func ApprovalQueue(db *gorm.DB, user *middlewares.TokenMetadata) []ApprovalTask {
+	tasks := []ApprovalTask{}
+	db.
+		Model(&models.Approval{}).
+		Select("approvals.*, people.full_name").
+		Joins("JOIN people ON people.id = approvals.person_id").
+		Where("approvals.status = ? AND approvals.group_name IN ?", ApprovalPending, user.Groups).
+		Where("approvals.officer_id <> ?", user.UserID).
+		Order("approvals.created_at").
+		Find(&tasks)
+	return tasks
+}
+
+func conclusionApproved(db *gorm.DB, check models.Check) bool {
+	var count int64
+	db.
+		Model(&models.Approval{}).
+		Where("check_id = ? AND conclusion_id = ? AND status = ?", check.ID, check.ConclusionID, ApprovalApproved).
+		Count(&count)
+	return count > 0
+}
+
+// concludedCheck returns the latest check of the person with a final
+// conclusion. Later checks without one or with an interim one do not hide it.
+func concludedCheck(db *gorm.DB, personID uint) models.Check {
+	var check models.Check
+	db.
+		Where("person_id = ? AND conclusion_id NOT IN ?", personID, interimConclusions()).
+		Order("id DESC").
+		Limit(1).
+		Find(&check)
+	return check
+}
+
+// interimConclusions are the conclusion ids of checks still in progress.
+func interimConclusions() []uint {
+	return []uint{
+		0,
+		models.Conclusion{}.GetID(Conclusions["saved"]),
+		models.Conclusion{}.GetID(Conclusions["pfo"]),
+	}
+}
+
+func groupUsers(db *gorm.DB, group string) []models.User {
+	var users []models.User
+	db.
+		Joins("JOIN user_groups ON user_groups.user_id = users.id").
+		Joins("JOIN groups ON groups.id = user_groups.group_id").
+		Where("groups.name_group = ? AND users.blocked = ? AND users.deleted = ?", group, false, false).
+		Find(&users)
+	return users
+}
+
+func userInGroup(user *middlewares.TokenMetadata, group string) bool {
+	for _, name := range user.Groups {
+		if name == group {
+			return true
+		}
+	}
+	return false
+}
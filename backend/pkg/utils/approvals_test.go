@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/platform/database/databasetest"
+)
+
+// concludedQuery matches the lookup of the latest concluded check.
+const concludedQuery = `FROM "checks" WHERE person_id = \$1 AND conclusion_id NOT IN`
+
+// answerConcluded makes check 8 of officer 3 with the conclusion the latest
+// concluded check.
+func answerConcluded(fake *databasetest.Fake, conclusion string) {
+	fake.Answer(concludedQuery, []string{"id", "person_id", "conclusion_id", "officer"},
+		[]driver.Value{int64(8), int64(5), int64(lookupID(Conclusions, conclusion)), "Проверяющий"},
+	)
+}
+
+func TestApprovalReason(t *testing.T) {
+	newFakeDb(t)
+	vip := lookupID(Categories, "vip")
+	tests := []struct {
+		category   uint
+		conclusion string
+		checkID    uint
+		want       string
+	}{
+		{0, "agreed", 8, ""},
+		{0, "denied", 8, "denied"},
+		{vip, "agreed", 8, "vip"},
+		{vip, "denied", 8, "vip"},
+		{vip, "saved", 8, ""},
+		{0, "denied", 0, ""},
+	}
+	for _, test := range tests {
+		person := models.Person{CategoryID: test.category}
+		check := models.Check{ID: test.checkID, ConclusionID: lookupID(Conclusions, test.conclusion)}
+		if got := ApprovalReason(person, check); got != test.want {
+			t.Errorf("ApprovalReason(%d, %s) = %q, want %q", test.category, test.conclusion, got, test.want)
+		}
+	}
+}
+
+func TestFinishNeedsApproval(t *testing.T) {
+	db, fake := newFakeDb(t)
+	answerConcluded(fake, "denied")
+	person := models.Person{ID: 5, StatusID: lookupID(Statuses, "manual")}
+
+	if err := ChangeStatus(db, &person, "finish", nil, ""); err == nil {
+		t.Fatal("ChangeStatus() finished a denial without an approval")
+	}
+	if person.StatusID != lookupID(Statuses, "manual") || len(fake.Statements(`^UPDATE "people"`)) != 0 {
+		t.Errorf("ChangeStatus() moved the person without an approval")
+	}
+
+	fake.Answer(`SELECT count\(\*\) FROM "approvals"`, []string{"count"}, []driver.Value{int64(1)})
+	if err := ChangeStatus(db, &person, "finish", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	count := fake.Statements(`FROM "approvals"`)[1]
+	if count.Args[0] != int64(8) || count.Args[1] != int64(lookupID(Conclusions, "denied")) || count.Args[2] != ApprovalApproved {
+		t.Errorf("approval query args = %v, want an approval of the conclusion of check 8", count.Args)
+	}
+	if person.StatusID != lookupID(Statuses, "finish") {
+		t.Errorf("ChangeStatus() status = %d, want finish", person.StatusID)
+	}
+}
+
+func TestFinishCheckRequestsApproval(t *testing.T) {
+	db, fake := newFakeDb(t)
+	answerConcluded(fake, "denied")
+	fake.Answer(`FROM "users" JOIN user_groups`, []string{"id", "full_name"},
+		[]driver.Value{int64(3), "Проверяющий"},
+		[]driver.Value{int64(4), "Руководитель"},
+	)
+	person := models.Person{ID: 5, FullName: "Иванов Иван", StatusID: lookupID(Statuses, "manual")}
+	user := &middlewares.TokenMetadata{UserID: 3, FullName: "Проверяющий"}
+
+	if err := FinishCheck(db, &person, user, "Проверка окончена"); err != nil {
+		t.Fatal(err)
+	}
+	if person.StatusID != lookupID(Statuses, "approval") {
+		t.Errorf("FinishCheck() status = %d, want approval", person.StatusID)
+	}
+	inserts := fake.Statements(`^INSERT INTO "approvals"`)
+	if len(inserts) != 1 {
+		t.Fatalf("FinishCheck() requested %d approvals, want 1", len(inserts))
+	}
+	for column, want := range map[string]driver.Value{
+		"reason": "denied", "group_name": "reviewers", "status": ApprovalPending, "check_id": int64(8), "officer_id": int64(3),
+	} {
+		if value, _ := inserts[0].Arg(column); value != want {
+			t.Errorf("approval %s = %v, want %v", column, value, want)
+		}
+	}
+
+	statements := fake.Statements("")
+	withdraw, insert := -1, -1
+	for i, statement := range statements {
+		if strings.HasPrefix(statement.SQL, `UPDATE "approvals" SET "status"`) && withdraw < 0 {
+			withdraw = i
+		}
+		if strings.HasPrefix(statement.SQL, `INSERT INTO "approvals"`) {
+			insert = i
+		}
+	}
+	if withdraw < 0 || withdraw > insert {
+		t.Errorf("FinishCheck() did not withdraw the pending approvals first")
+	}
+
+	// The officer is not asked to approve their own conclusion.
+	messages := fake.Statements(`^INSERT INTO "messages"`)
+	if len(messages) != 1 {
+		t.Fatalf("FinishCheck() sent %d messages, want 1", len(messages))
+	}
+	if userID, _ := messages[0].Arg("user_id"); userID != int64(4) {
+		t.Errorf("message user_id = %v, want the other reviewer", userID)
+	}
+}
+
+func TestRequestApprovalStatus(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{ID: 5, StatusID: lookupID(Statuses, "robot")}
+	check := models.Check{ID: 8, ConclusionID: lookupID(Conclusions, "denied")}
+	if err := RequestApproval(db, &person, check, "denied", nil); err == nil {
+		t.Errorf("RequestApproval() accepted a person that may not finish")
+	}
+	if len(fake.Statements(`^INSERT`)) != 0 {
+		t.Errorf("RequestApproval() stored an approval for a person that may not finish")
+	}
+}
+
+func TestDecideApproval(t *testing.T) {
+	reviewer := &middlewares.TokenMetadata{UserID: 4, FullName: "Руководитель", Groups: []string{"reviewers"}}
+	pending := func() models.Approval {
+		return models.Approval{
+			ID: 2, Reason: "denied", GroupName: "reviewers", Status: ApprovalPending,
+			ConclusionID: lookupID(Conclusions, "denied"), OfficerID: 3, CheckID: 8, PersonID: 5,
+		}
+	}
+
+	db, fake := newFakeDb(t)
+	refusals := []struct {
+		approval models.Approval
+		user     *middlewares.TokenMetadata
+	}{
+		{models.Approval{Status: ApprovalApproved, GroupName: "reviewers"}, reviewer},
+		{pending(), &middlewares.TokenMetadata{UserID: 3, Groups: []string{"reviewers"}}},
+		{pending(), &middlewares.TokenMetadata{UserID: 6, Groups: []string{"vip_reviewers"}}},
+	}
+	for _, refusal := range refusals {
+		if err := DecideApproval(db, &refusal.approval, true, "", refusal.user); err == nil {
+			t.Errorf("DecideApproval(%+v) by %+v returned no error", refusal.approval, refusal.user)
+		}
+	}
+	if statements := fake.Statements(""); len(statements) != 0 {
+		t.Fatalf("refused decisions ran %d statements", len(statements))
+	}
+
+	tests := []struct {
+		approve bool
+		status  string
+		state   string
+	}{
+		{true, "finish", ApprovalApproved},
+		{false, "manual", ApprovalReturned},
+	}
+	for _, test := range tests {
+		db, fake := newFakeDb(t)
+		answerConcluded(fake, "denied")
+		fake.Answer(personQuery, []string{"id", "full_name", "status_id"},
+			[]driver.Value{int64(5), "Иванов Иван", int64(lookupID(Statuses, "approval"))},
+		)
+		// The saved decision is found by the finish transition.
+		fake.Answer(`SELECT count\(\*\) FROM "approvals"`, []string{"count"}, []driver.Value{int64(1)})
+
+		approval := pending()
+		if err := DecideApproval(db, &approval, test.approve, "Уточнить", reviewer); err != nil {
+			t.Fatalf("approve %v: %v", test.approve, err)
+		}
+		if approval.Status != test.state || approval.ReviewerID != 4 || approval.DecidedAt == nil {
+			t.Errorf("approve %v: approval = %+v, want %s by reviewer 4", test.approve, approval, test.state)
+		}
+		saves := fake.Statements(`^UPDATE "people"`)
+		if status, _ := saves[len(saves)-1].Arg("status_id"); status != int64(lookupID(Statuses, test.status)) {
+			t.Errorf("approve %v: status_id = %v, want %s", test.approve, status, test.status)
+		}
+		messages := fake.Statements(`^INSERT INTO "messages"`)
+		if userID, _ := messages[0].Arg("user_id"); len(messages) != 1 || userID != int64(3) {
+			t.Errorf("approve %v: messages = %d, want one to the officer", test.approve, len(messages))
+		}
+		statements := fake.Statements("")
+		if statements[0].SQL != "BEGIN" || !strings.HasPrefix(statements[1].SQL, `UPDATE "approvals"`) ||
+			len(fake.Statements(`^ROLLBACK`)) != 0 {
+			t.Errorf("approve %v: DecideApproval() did not save the decision in its transaction", test.approve)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// BulkActions lists the actions that can be applied to a batch of persons.
+var BulkActions map[string]string = map[string]string{
+	"status":   "Изменить статус",
+	"officer":  "Передать проверку",
+	"region":   "Изменить регион",
+	"category": "Изменить категорию",
+	"delete":   "Удалить",
+}
+
+type BulkRequest struct {
+	Action     string `json:"action"`
+	IDs        []uint `json:"ids"`
+	FilterID   uint   `json:"filter_id"`
+	Status     string `json:"status"`
+	Reason     string `json:"reason"`
+	OfficerID  uint   `json:"officer_id"`
+	RegionID   uint   `json:"region_id"`
+	CategoryID uint   `json:"category_id"`
+}
+
+type BulkResult struct {
+	PersonID uint   `json:"person_id"`
+	Ok       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkSyncLimit returns the largest batch processed within the request,
+// bigger batches run as a background job.
+func BulkSyncLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("BULK_SYNC_LIMIT"))
+	if err != nil || limit < 0 {
+		return 20
+	}
+	return limit
+}
+
+// FilterPersons returns the ids of persons matching the saved filter.
+func FilterPersons(db *gorm.DB, filter models.SavedFilter) ([]uint, error) {
+	ids := []uint{}
+	query := db.Model(&models.Person{})
+	if filter.Search != "" {
+		query = query.Where(
+			"search_name LIKE ? OR id IN (?)",
+			"%"+SearchKey(filter.Search)+"%", PreviousNameQuery(db, filter.Search),
+		)
+	}
+	if filter.StatusID != 0 {
+		query = query.Where("status_id = ?", filter.StatusID)
+	}
+	if filter.RegionID != 0 {
+		query = query.Where("region_id = ?", filter.RegionID)
+	}
+	if filter.CategoryID != 0 {
+		query = query.Where("category_id = ?", filter.CategoryID)
+	}
+	if filter.Officer != "" {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM checks WHERE checks.person_id = people.id AND checks.officer = ?)",
+			filter.Officer,
+		)
+	}
+	err := query.Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// BulkTargets resolves the persons of the request from the explicit ids or
+// from a saved filter of the user.
+func BulkTargets(db *gorm.DB, request BulkRequest, user *middlewares.TokenMetadata) ([]uint, error) {
+	if request.FilterID == 0 {
+		return request.IDs, nil
+	}
+	var filter models.SavedFilter
+	db.Where("id = ? AND user_id = ?", request.FilterID, user.UserID).First(&filter)
+	if filter.ID == 0 {
+		return nil, errors.New("фильтр не найден")
+	}
+	return FilterPersons(db, filter)
+}
+
+// ValidateBulk checks the action and its parameters before any person is touched.
+func ValidateBulk(db *gorm.DB, request BulkRequest) FieldErrors {
+	errs := FieldErrors{}
+	switch request.Action {
+	case "status":
+		if _, ok := Statuses[request.Status]; !ok {
+			errs["status"] = "неизвестный статус"
+		}
+	case "officer":
+		var user models.User
+		db.First(&user, request.OfficerID)
+		if user.ID == 0 || user.Blocked || user.Deleted {
+			errs["officer_id"] = "пользователь не найден"
+		}
+	case "region":
+		var region models.Region
+		db.First(&region, request.RegionID)
+		if region.ID == 0 {
+			errs["region_id"] = "регион не найден"
+		}
+	case "category":
+		var category models.Category
+		db.First(&category, request.CategoryID)
+		if category.ID == 0 {
+			errs["category_id"] = "категория не найдена"
+		}
+	case "delete":
+	default:
+		errs["action"] = "неизвестное действие"
+	}
+	if request.FilterID == 0 && len(request.IDs) == 0 {
+		errs["ids"] = "не выбраны анкеты"
+	}
+	return errs
+}
+
+// RunBulk applies the request to every person and stores the progress and the
+// per item results in the job.
+func RunBulk(db *gorm.DB, job *models.BulkJob, request BulkRequest, ids []uint, user *middlewares.TokenMetadata) []BulkResult {
+	results := []BulkResult{}
+	job.State = "running"
+	job.Total = len(ids)
+	db.Save(job)
+
+	for i, id := range ids {
+		result := BulkResult{PersonID: id, Ok: true}
+		if err := applyBulk(db, request, id, user); err != nil {
+			result.Ok = false
+			result.Error = err.Error()
+			job.Failed++
+		}
+		results = append(results, result)
+		job.Done = i + 1
+		if job.Done%10 == 0 {
+			db.Model(job).Select("done", "failed").Updates(job)
+		}
+	}
+
+	if request.Action == "officer" && job.Done > job.Failed {
+		db.Create(&models.Message{
+			Title:          "Передача анкет",
+			StatusRead:     "new",
+			MessageContent: fmt.Sprintf("Вам переданы анкеты: %d, от %s", job.Done-job.Failed, user.FullName),
+			UserID:         request.OfficerID,
+		})
+	}
+
+	data, _ := json.Marshal(results)
+	now := time.Now()
+	job.Results = string(data)
+	job.State = "finished"
+	job.FinishedAt = &now
+	db.Save(job)
+	return results
+}
+
+// RunBulkBackground runs the job outside of the request. A panic marks the
+// job failed instead of leaving it running and taking the server down.
+func RunBulkBackground(db *gorm.DB, job *models.BulkJob, request BulkRequest, ids []uint, user *middlewares.TokenMetadata) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("bulk job %d failed: %v", job.ID, r)
+			now := time.Now()
+			job.State = "failed"
+			job.FinishedAt = &now
+			db.Model(job).Select("state", "done", "failed", "finished_at").Updates(job)
+		}
+	}()
+	RunBulk(db, job, request, ids, user)
+}
+
+func applyBulk(db *gorm.DB, request BulkRequest, id uint, user *middlewares.TokenMetadata) error {
+	var person models.Person
+	if err := db.First(&person, id).Error; err != nil {
+		return errors.New("анкета не найдена")
+	}
+
+	switch request.Action {
+	case "status":
+		return ChangeStatus(db, &person, request.Status, user, request.Reason)
+
+	case "officer":
+		var officer models.User
+		db.First(&officer, request.OfficerID)
+		var check models.Check
+		db.Where("person_id = ?", person.ID).Order("id desc").First(&check)
+		if check.ID == 0 {
+			return errors.New("проверка не начата")
+		}
+		before := check
+		if err := db.Model(&check).Update("officer", officer.FullName).Error; err != nil {
+			return err
+		}
+		return RecordVersion(db, "check", &before, &check, user)
+
+	case "region", "category":
+		before := person
+		column, value := "region_id", request.RegionID
+		if request.Action == "category" {
+			column, value = "category_id", request.CategoryID
+		}
+		if err := db.Model(&person).Update(column, value).Error; err != nil {
+			return err
+		}
+		return RecordVersion(db, "person", &before, &person, user)
+
+	case "delete":
+		return TrashPerson(db, &person, user)
+	}
+	return fmt.Errorf("unknown action %q", request.Action)
+}
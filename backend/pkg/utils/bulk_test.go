@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// personQuery matches the lookup of a person by id.
+const personQuery = `SELECT \* FROM "people" WHERE "people"."id" = \$1`
+
+func TestValidateBulk(t *testing.T) {
+	db, _ := newFakeDb(t)
+	tests := []struct {
+		request BulkRequest
+		fields  []string
+	}{
+		{BulkRequest{Action: "status", Status: "finish", IDs: []uint{1}}, nil},
+		{BulkRequest{Action: "delete", FilterID: 2}, nil},
+		{BulkRequest{Action: "status", Status: "unknown", IDs: []uint{1}}, []string{"status"}},
+		{BulkRequest{Action: "region", RegionID: 99, IDs: []uint{1}}, []string{"region_id"}},
+		{BulkRequest{Action: "officer", OfficerID: 3}, []string{"officer_id", "ids"}},
+		{BulkRequest{Action: "archive", IDs: []uint{1}}, []string{"action"}},
+	}
+	for _, test := range tests {
+		errs := ValidateBulk(db, test.request)
+		if len(errs) != len(test.fields) {
+			t.Errorf("ValidateBulk(%+v) = %v, want errors for %v", test.request, errs, test.fields)
+			continue
+		}
+		for _, field := range test.fields {
+			if _, ok := errs[field]; !ok {
+				t.Errorf("ValidateBulk(%+v) = %v, want an error for %s", test.request, errs, field)
+			}
+		}
+	}
+}
+
+func TestRunBulk(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.AnswerFunc(personQuery, []string{"id", "full_name", "region_id"}, func(args []driver.Value) [][]driver.Value {
+		if args[0] == int64(4) {
+			return [][]driver.Value{{int64(4), "Иванов Иван", int64(1)}}
+		}
+		return nil
+	})
+
+	job := models.BulkJob{ID: 7, Action: "region"}
+	request := BulkRequest{Action: "region", RegionID: 2}
+	results := RunBulk(db, &job, request, []uint{4, 9}, nil)
+	if len(results) != 2 || !results[0].Ok || results[1].Ok || results[1].Error == "" {
+		t.Fatalf("RunBulk() = %+v, want person 4 done and 9 failed", results)
+	}
+	if job.State != "finished" || job.Total != 2 || job.Done != 2 || job.Failed != 1 || job.FinishedAt == nil {
+		t.Errorf("RunBulk() job = %+v, want finished with 2 done and 1 failed", job)
+	}
+	stored := []BulkResult{}
+	json.Unmarshal([]byte(job.Results), &stored)
+	if len(stored) != 2 || stored[1].PersonID != 9 {
+		t.Errorf("job results = %s, want the results of both persons", job.Results)
+	}
+
+	updates := fake.Statements(`^UPDATE "people" SET "region_id"=\$1`)
+	if len(updates) != 1 || updates[0].Args[0] != int64(2) {
+		t.Fatalf("RunBulk() region updates = %v, want person 4 moved to region 2", updates)
+	}
+	if len(fake.Statements(`^INSERT INTO "versions"`)) != 1 {
+		t.Errorf("RunBulk() did not record the version of person 4")
+	}
+}
+
+func TestRunBulkBackgroundPanic(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(personQuery, []string{"id", "full_name"}, []driver.Value{int64(4), "Иванов Иван"})
+	fake.Answer(`FROM "checks"`, []string{"id", "person_id", "officer"}, []driver.Value{int64(8), int64(4), "Петров"})
+	fake.Answer(`FROM "users"`, []string{"id", "full_name"}, []driver.Value{int64(3), "Сидоров"})
+
+	// The message to the new officer needs the user, so the job panics
+	// after the persons are processed.
+	job := models.BulkJob{ID: 7, Action: "officer"}
+	request := BulkRequest{Action: "officer", OfficerID: 3}
+	var user *middlewares.TokenMetadata
+	RunBulkBackground(db, &job, request, []uint{4}, user)
+
+	if job.State != "failed" || job.FinishedAt == nil {
+		t.Fatalf("RunBulkBackground() job = %+v, want it failed", job)
+	}
+	updates := fake.Statements(`^UPDATE "bulk_jobs" SET "state"=\$1`)
+	if len(updates) != 1 {
+		t.Fatalf("RunBulkBackground() stored the failure %d times, want once", len(updates))
+	}
+	if state, _ := updates[0].Arg("state"); state != "failed" {
+		t.Errorf("stored state = %v, want failed", state)
+	}
+	if done, _ := updates[0].Arg("done"); done != int64(1) {
+		t.Errorf("stored done = %v, want the progress of the job", done)
+	}
+}
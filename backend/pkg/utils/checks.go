@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Check templates list the items an officer has to answer in a check. A
+// template is chosen by the category and the region of the person, a template
+// without a region applies to all regions of its category. New checks keep a
+// copy of the template items, so later edits of the template do not change
+// checks already started.
+
+// CheckItemKinds are the kinds of check items and their names.
+var CheckItemKinds map[string]string = map[string]string{
+	"yesno": "Да/Нет",
+	"text":  "Текст",
+	"enum":  "Выбор из списка",
+	"file":  "Файл",
+}
+
+// Answers of yes/no items.
+const (
+	CheckYes = "yes"
+	CheckNo  = "no"
+)
+
+// ValidateCheckTemplate checks the template and orders its items.
+func ValidateCheckTemplate(template *models.CheckTemplate) FieldErrors {
+	errs := FieldErrors{}
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		errs["name"] = "не указано название шаблона"
+	}
+	if template.CategoryID == 0 {
+		errs["category_id"] = "не указана категория"
+	}
+	if len(template.Items) == 0 {
+		errs["items"] = "шаблон не содержит пунктов"
+	}
+	sort.SliceStable(template.Items, func(i, j int) bool {
+		return template.Items[i].Position < template.Items[j].Position
+	})
+	for i := range template.Items {
+		item := &template.Items[i]
+		item.ID, item.TemplateID, item.Position = 0, 0, i+1
+		item.Label = strings.TrimSpace(item.Label)
+		itemErrs := FieldErrors{}
+		if item.Label == "" {
+			itemErrs["label"] = "не указан текст пункта"
+		}
+		if _, ok := CheckItemKinds[item.Kind]; !ok {
+			itemErrs["kind"] = "неизвестный тип пункта"
+		}
+		options := []string{}
+		for _, option := range item.Options {
+			if option = strings.TrimSpace(option); option != "" {
+				options = append(options, option)
+			}
+		}
+		item.Options = options
+		if item.Kind == "enum" && len(item.Options) == 0 {
+			itemErrs["options"] = "не указаны варианты ответа"
+		}
+		if item.Kind != "enum" {
+			item.Options = nil
+		}
+		errs.merge(fmt.Sprintf("items[%d]", i), itemErrs)
+	}
+	return errs
+}
+
+// CheckTemplateFor returns the template for the person, the one of the
+// region before the one for all regions. A zero template means checks of the
+// person have no items.
+func CheckTemplateFor(db *gorm.DB, person models.Person) models.CheckTemplate {
+	var template models.CheckTemplate
+	db.
+		Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Where("category_id = ? AND region_id IN ?", person.CategoryID, []uint{person.RegionID, 0}).
+		Order("region_id DESC").
+		First(&template)
+	return template
+}
+
+// SnapshotCheckTemplate copies the items of the template for the person to
+// the new check.
+func SnapshotCheckTemplate(db *gorm.DB, check *models.Check) error {
+	var person models.Person
+	db.First(&person, check.PersonID)
+	template := CheckTemplateFor(db, person)
+	if template.ID == 0 {
+		return nil
+	}
+
+	items := []models.CheckItem{}
+	for _, item := range template.Items {
+		items = append(items, models.CheckItem{
+			Position: item.Position,
+			Label:    item.Label,
+			Kind:     item.Kind,
+			Options:  item.Options,
+			Required: item.Required,
+			Help:     item.Help,
+			CheckID:  check.ID,
+		})
+	}
+	check.TemplateID = template.ID
+	check.Items = items
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(check).UpdateColumn("template_id", template.ID).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&check.Items).Error
+	})
+}
+
+// ValidateCheckAnswers checks the answers to the items of the check. With
+// complete set every required item has to be answered, taking the stored
+// answers into account. The valid answers are returned by item id.
+func ValidateCheckAnswers(db *gorm.DB, check models.Check, answers []models.CheckItem, complete bool) (map[uint]string, FieldErrors) {
+	errs := FieldErrors{}
+	var items []models.CheckItem
+	db.Where("check_id = ?", check.ID).Order("position").Find(&items)
+	byID := map[uint]models.CheckItem{}
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var person models.Person
+	db.First(&person, check.PersonID)
+
+	valid := map[uint]string{}
+	for i, answer := range answers {
+		field := fmt.Sprintf("items[%d].answer", i)
+		item, ok := byID[answer.ID]
+		if !ok {
+			errs[fmt.Sprintf("items[%d].id", i)] = "пункт не относится к проверке"
+			continue
+		}
+		value := strings.TrimSpace(answer.Answer)
+		if value != "" {
+			if err := checkAnswerError(item, value, person); err != "" {
+				errs[field] = err
+				continue
+			}
+		}
+		valid[item.ID] = value
+	}
+
+	if complete {
+		for _, item := range items {
+			value, ok := valid[item.ID]
+			if !ok {
+				value = item.Answer
+			}
+			if item.Required && value == "" {
+				errs[fmt.Sprintf("items.%d", item.ID)] = "нет ответа на обязательный пункт: " + item.Label
+			}
+		}
+	}
+	return valid, errs
+}
+
+// SaveCheckAnswers stores the answers validated by ValidateCheckAnswers.
+func SaveCheckAnswers(db *gorm.DB, check models.Check, answers map[uint]string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for id, answer := range answers {
+			err := tx.
+				Model(&models.CheckItem{}).
+				Where("id = ? AND check_id = ?", id, check.ID).
+				Update("answer", answer).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CheckItems returns the items of the checks by check id.
+func CheckItems(db *gorm.DB, checkIDs []uint) map[uint][]models.CheckItem {
+	var items []models.CheckItem
+	db.Where("check_id IN ?", checkIDs).Order("check_id, position").Find(&items)
+	byCheck := map[uint][]models.CheckItem{}
+	for _, item := range items {
+		byCheck[item.CheckID] = append(byCheck[item.CheckID], item)
+	}
+	return byCheck
+}
+
+func checkAnswerError(item models.CheckItem, value string, person models.Person) string {
+	switch item.Kind {
+	case "yesno":
+		if value != CheckYes && value != CheckNo {
+			return "ответ должен быть yes или no"
+		}
+	case "enum":
+		for _, option := range item.Options {
+			if option == value {
+				return ""
+			}
+		}
+		return "ответа нет среди вариантов"
+	case "file":
+		if !filepath.IsLocal(value) || person.PathToDocs == "" {
+			return "неверный путь к файлу"
+		}
+		if _, err := os.Stat(filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs, value)); err != nil {
+			return "файл не найден"
+		}
+	}
+	return ""
+}
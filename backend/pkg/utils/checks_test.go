@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/app/models"
+)
+
+func TestCheckAnswerError(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("BASE_PATH", base)
+	if err := os.MkdirAll(filepath.Join(base, "person", "checks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "person", "checks", "scan.pdf"), []byte("scan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	person := models.Person{PathToDocs: "person"}
+	yesno := models.CheckItem{Kind: "yesno"}
+	enum := models.CheckItem{Kind: "enum", Options: []string{"чисто", "есть долги"}}
+	file := models.CheckItem{Kind: "file"}
+	text := models.CheckItem{Kind: "text"}
+
+	tests := []struct {
+		name   string
+		item   models.CheckItem
+		value  string
+		person models.Person
+		valid  bool
+	}{
+		{"yes", yesno, CheckYes, person, true},
+		{"no", yesno, CheckNo, person, true},
+		{"yes in Russian", yesno, "да", person, false},
+		{"empty yes/no", yesno, "", person, false},
+		{"option", enum, "есть долги", person, true},
+		{"unknown option", enum, "нет данных", person, false},
+		{"empty option", enum, "", person, false},
+		{"file", file, "checks/scan.pdf", person, true},
+		{"missing file", file, "checks/other.pdf", person, false},
+		{"file outside the folder", file, "../person/checks/scan.pdf", person, false},
+		{"absolute file path", file, "/etc/passwd", person, false},
+		{"file without a folder", file, "checks/scan.pdf", models.Person{}, false},
+		{"text", text, "любой ответ", person, true},
+		{"empty text", text, "", person, true},
+	}
+	for _, test := range tests {
+		if got := checkAnswerError(test.item, test.value, test.person); (got == "") != test.valid {
+			t.Errorf("%s: checkAnswerError(%q) = %q, want valid %v", test.name, test.value, got, test.valid)
+		}
+	}
+}
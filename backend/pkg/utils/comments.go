@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.\-]+)`)
+
+// Mentions returns the distinct user names mentioned in the text.
+func Mentions(text string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// NotifyMentions sends a message to every active user mentioned in the
+// comment and not mentioned in its previous text.
+func NotifyMentions(db *gorm.DB, comment models.Comment, previous string, person models.Person) {
+	notified := map[string]bool{}
+	for _, name := range Mentions(previous) {
+		notified[name] = true
+	}
+
+	content := []rune(fmt.Sprintf("%s: %s", comment.UserName, comment.Text))
+	if len(content) > 256 {
+		content = append(content[:253], []rune("...")...)
+	}
+	for _, name := range Mentions(comment.Text) {
+		if notified[name] {
+			continue
+		}
+		var user models.User
+		db.Where("user_name = ? AND blocked = ? AND deleted = ?", name, false, false).First(&user)
+		if user.ID == 0 || user.ID == comment.UserID {
+			continue
+		}
+		db.Create(&models.Message{
+			Title:          "Упоминание: " + person.FullName,
+			MessageContent: string(content),
+			StatusRead:     "new",
+			UserID:         user.ID,
+		})
+	}
+}
+
+// CommentPath returns the folder with the attachments of the comment.
+func CommentPath(person models.Person, commentID uint) string {
+	return filepath.Join(
+		os.Getenv("BASE_PATH"), person.PathToDocs, "comments", strconv.FormatUint(uint64(commentID), 10),
+	)
+}
+
+// CommentAttachments returns the file names attached to the comment.
+func CommentAttachments(comment models.Comment) []string {
+	files := []string{}
+	if comment.Attachments != "" {
+		json.Unmarshal([]byte(comment.Attachments), &files)
+	}
+	return files
+}
+
+// DeleteComment removes the comment with its attachments.
+func DeleteComment(db *gorm.DB, comment models.Comment, person models.Person) error {
+	if err := db.Delete(&comment).Error; err != nil {
+		return err
+	}
+	if person.PathToDocs == "" {
+		return nil
+	}
+	return os.RemoveAll(CommentPath(person, comment.ID))
+}
+
+// CanEditComment reports whether the user may change the comment. Only the
+// author and administrators can.
+func CanEditComment(comment models.Comment, user *middlewares.TokenMetadata) bool {
+	if comment.UserID == user.UserID {
+		return true
+	}
+	for _, role := range user.Roles {
+		if role == "admin" {
+			return true
+		}
+	}
+	return false
+}
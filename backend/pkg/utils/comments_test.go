@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"@ivanov, проверьте", []string{"ivanov"}},
+		{"для @ivanov и @p.petrov-2 (@ivanov)", []string{"ivanov", "p.petrov-2"}},
+		{"почта ivanov@example.com", []string{}},
+		{"без упоминаний", []string{}},
+	}
+	for _, test := range tests {
+		if got := Mentions(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Mentions(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCanEditComment(t *testing.T) {
+	comment := models.Comment{UserID: 3}
+	tests := []struct {
+		user *middlewares.TokenMetadata
+		want bool
+	}{
+		{&middlewares.TokenMetadata{UserID: 3}, true},
+		{&middlewares.TokenMetadata{UserID: 4, Roles: []string{"user"}}, false},
+		{&middlewares.TokenMetadata{UserID: 4, Roles: []string{"user", "admin"}}, true},
+	}
+	for _, test := range tests {
+		if got := CanEditComment(comment, test.user); got != test.want {
+			t.Errorf("CanEditComment(%+v) = %v, want %v", test.user, got, test.want)
+		}
+	}
+}
+
+func TestNotifyMentions(t *testing.T) {
+	db, fake := newFakeDb(t)
+	users := map[string]int64{"author": 3, "ivanov": 4, "petrov": 5}
+	fake.AnswerFunc(`FROM "users" WHERE user_name = \$1`, []string{"id", "user_name"},
+		func(args []driver.Value) [][]driver.Value {
+			if id, ok := users[args[0].(string)]; ok {
+				return [][]driver.Value{{id, args[0]}}
+			}
+			return nil
+		})
+
+	comment := models.Comment{
+		UserID:   3,
+		UserName: "Автор",
+		Text:     "@author @ivanov @petrov @unknown " + strings.Repeat("я", 300),
+	}
+	person := models.Person{FullName: "Иванов Иван"}
+	NotifyMentions(db, comment, "ранее @petrov", person)
+
+	messages := fake.Statements(`^INSERT INTO "messages"`)
+	if len(messages) != 1 {
+		t.Fatalf("NotifyMentions() sent %d messages, want only to ivanov", len(messages))
+	}
+	if userID, _ := messages[0].Arg("user_id"); userID != int64(4) {
+		t.Errorf("message user_id = %v, want 4", userID)
+	}
+	content, _ := messages[0].Arg("message_content")
+	if runes := []rune(content.(string)); len(runes) != 256 || !strings.HasSuffix(content.(string), "...") {
+		t.Errorf("message content has %d runes, want it cut to 256", len(runes))
+	}
+	lookup := fake.Statements(`FROM "users"`)[0]
+	if lookup.Args[1] != false || lookup.Args[2] != false {
+		t.Errorf("user lookup args = %v, want only active users", lookup.Args)
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	db, fake := newFakeDb(t)
+	t.Setenv("BASE_PATH", t.TempDir())
+	person := models.Person{PathToDocs: filepath.Join("И", "5-Иванов Иван")}
+	comment := models.Comment{ID: 7, Attachments: `["scan.pdf"]`}
+	path := CommentPath(person, comment.ID)
+	os.MkdirAll(path, 0755)
+	os.WriteFile(filepath.Join(path, "scan.pdf"), []byte("%PDF"), 0644)
+
+	if files := CommentAttachments(comment); !reflect.DeepEqual(files, []string{"scan.pdf"}) {
+		t.Errorf("CommentAttachments() = %v", files)
+	}
+	if err := DeleteComment(db, comment, person); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Statements(`^(UPDATE|DELETE FROM) "comments"`)) != 1 {
+		t.Errorf("DeleteComment() did not delete the comment")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("attachments of the comment are still in place")
+	}
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// GovernmentViews are the affiliation views of service in government bodies.
+var GovernmentViews []string = []string{
+	"Являлся государственным или муниципальным служащим",
+	"Являлся государственным должностным лицом",
+	"Связанные лица работают в госудраственных организациях",
+}
+
+// OrgEntry is an affiliation or a workplace of a person, the evidence of a
+// conflict of interest.
+type OrgEntry struct {
+	Source   string `json:"source"`
+	RecordID uint   `json:"record_id"`
+	PersonID uint   `json:"person_id"`
+	FullName string `json:"fullname"`
+	Staff    bool   `json:"staff"`
+	View     string `json:"view"`
+	Name     string `json:"name"`
+	Inn      string `json:"inn"`
+	Position string `json:"position"`
+	NameKey  string `json:"-"`
+}
+
+// Conflict links persons sharing an organization. Kind is "inn" for a shared
+// affiliated organization INN, "government" for a shared government body and
+// "organization" for any other organization with the same name.
+type Conflict struct {
+	Kind    string     `json:"kind"`
+	Key     string     `json:"key"`
+	Staff   bool       `json:"staff"`
+	Entries []OrgEntry `json:"entries"`
+}
+
+// PersonConflicts returns the conflicts of interest of the person with other
+// persons in the registry.
+func PersonConflicts(db *gorm.DB, personID uint) []Conflict {
+	own := orgEntries(db, "person_id = ?", []interface{}{personID}, "person_id = ?", []interface{}{personID})
+	if len(own) == 0 {
+		return []Conflict{}
+	}
+	keys, inns := []string{}, []string{}
+	for _, entry := range own {
+		if entry.NameKey != "" {
+			keys = append(keys, entry.NameKey)
+		}
+		if entry.Inn != "" {
+			inns = append(inns, entry.Inn)
+		}
+	}
+
+	entries := orgEntries(
+		db,
+		"name_key IN ? OR inn IN ?", []interface{}{keys, inns},
+		"name_key IN ?", []interface{}{keys},
+	)
+	conflicts := []Conflict{}
+	for _, conflict := range groupConflicts(db, entries) {
+		for _, entry := range conflict.Entries {
+			if entry.PersonID == personID {
+				conflicts = append(conflicts, conflict)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// ConflictReport returns all conflicts of interest in the registry. With
+// staffOnly only the conflicts involving current staff are returned.
+func ConflictReport(db *gorm.DB, staffOnly bool) []Conflict {
+	sharedKeys := db.Raw(
+		"SELECT name_key FROM (" +
+			"SELECT name_key, person_id FROM affilations UNION ALL " +
+			"SELECT name_key, person_id FROM workplaces" +
+			") orgs WHERE name_key <> '' GROUP BY name_key HAVING COUNT(DISTINCT person_id) > 1",
+	)
+	sharedInns := db.Raw(
+		"SELECT inn FROM affilations WHERE inn <> '' GROUP BY inn HAVING COUNT(DISTINCT person_id) > 1",
+	)
+	entries := orgEntries(
+		db,
+		"name_key IN (?) OR inn IN (?)", []interface{}{sharedKeys, sharedInns},
+		"name_key IN (?)", []interface{}{sharedKeys},
+	)
+
+	conflicts := []Conflict{}
+	for _, conflict := range groupConflicts(db, entries) {
+		if !staffOnly || conflict.Staff {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// orgEntries loads the affiliations and the workplaces of persons that are
+// not in the trash.
+func orgEntries(db *gorm.DB, affilationQuery string, affilationArgs []interface{}, workplaceQuery string, workplaceArgs []interface{}) []OrgEntry {
+	entries := []OrgEntry{}
+
+	var affilations []models.Affilation
+	db.
+		Where(affilationQuery, affilationArgs...).
+		Where("person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").
+		Find(&affilations)
+	for _, affilation := range affilations {
+		entries = append(entries, OrgEntry{
+			Source:   "affilation",
+			RecordID: affilation.ID,
+			PersonID: affilation.PersonID,
+			View:     affilation.View,
+			Name:     affilation.Name,
+			Inn:      affilation.Inn,
+			Position: affilation.Position,
+			NameKey:  affilation.NameKey,
+		})
+	}
+
+	var workplaces []models.Workplace
+	db.
+		Where(workplaceQuery, workplaceArgs...).
+		Where("person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").
+		Find(&workplaces)
+	for _, workplace := range workplaces {
+		entries = append(entries, OrgEntry{
+			Source:   "workplace",
+			RecordID: workplace.ID,
+			PersonID: workplace.PersonID,
+			View:     workplace.View,
+			Name:     workplace.Workplace,
+			Position: workplace.Position,
+			NameKey:  workplace.NameKey,
+		})
+	}
+	return entries
+}
+
+// groupConflicts groups the entries by INN and by name key and keeps the
+// groups shared by several persons. A name group with the same evidence as
+// an INN group is left out.
+func groupConflicts(db *gorm.DB, entries []OrgEntry) []Conflict {
+	personIDs := []uint{}
+	for _, entry := range entries {
+		personIDs = append(personIDs, entry.PersonID)
+	}
+	names := map[uint]string{}
+	var persons []models.Person
+	db.Select("id, full_name").Where("id IN ?", personIDs).Find(&persons)
+	for _, person := range persons {
+		names[person.ID] = person.FullName
+	}
+	staff := StaffPersons(db, personIDs)
+
+	government := map[string]bool{}
+	for _, view := range GovernmentViews {
+		government[view] = true
+	}
+
+	byInn := map[string][]OrgEntry{}
+	byName := map[string][]OrgEntry{}
+	for _, entry := range entries {
+		entry.FullName = names[entry.PersonID]
+		entry.Staff = staff[entry.PersonID]
+		if entry.Inn != "" {
+			byInn[entry.Inn] = append(byInn[entry.Inn], entry)
+		}
+		if entry.NameKey != "" {
+			byName[entry.NameKey] = append(byName[entry.NameKey], entry)
+		}
+	}
+
+	conflicts := []Conflict{}
+	covered := map[orgRecord]bool{}
+	for inn, group := range byInn {
+		if !sharedByPersons(group) {
+			continue
+		}
+		for _, entry := range group {
+			covered[orgRecord{entry.Source, entry.RecordID}] = true
+		}
+		conflicts = append(conflicts, newConflict("inn", inn, group))
+	}
+	for key, group := range byName {
+		if !sharedByPersons(group) {
+			continue
+		}
+		kind, duplicate := "organization", true
+		for _, entry := range group {
+			if entry.Source == "affilation" && government[entry.View] {
+				kind = "government"
+			}
+			if !covered[orgRecord{entry.Source, entry.RecordID}] {
+				duplicate = false
+			}
+		}
+		if !duplicate {
+			conflicts = append(conflicts, newConflict(kind, key, group))
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Staff != conflicts[j].Staff {
+			return conflicts[i].Staff
+		}
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].Key < conflicts[j].Key
+	})
+	return conflicts
+}
+
+type orgRecord struct {
+	source string
+	id     uint
+}
+
+func newConflict(kind string, key string, entries []OrgEntry) Conflict {
+	conflict := Conflict{Kind: kind, Key: key, Entries: entries}
+	for _, entry := range entries {
+		if entry.Staff {
+			conflict.Staff = true
+		}
+	}
+	sort.Slice(conflict.Entries, func(i, j int) bool {
+		return conflict.Entries[i].PersonID < conflict.Entries[j].PersonID
+	})
+	return conflict
+}
+
+func sharedByPersons(entries []OrgEntry) bool {
+	for _, entry := range entries {
+		if entry.PersonID != entries[0].PersonID {
+			return true
+		}
+	}
+	return false
+}
+
+// StaffOnly limits a persons query to the current staff, the persons of the
+// staff category. Conflicts and document alerts share it.
+func StaffOnly(db *gorm.DB) *gorm.DB {
+	return db.Where("people.category_id = ?", models.Category{}.GetID(Categories["staff"]))
+}
+
+// StaffPersons reports which of the persons are current staff.
+func StaffPersons(db *gorm.DB, personIDs []uint) map[uint]bool {
+	var ids []uint
+	db.
+		Model(&models.Person{}).
+		Scopes(StaffOnly).
+		Where("people.id IN ?", personIDs).
+		Pluck("people.id", &ids)
+
+	staff := map[uint]bool{}
+	for _, id := range ids {
+		staff[id] = true
+	}
+	return staff
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"backend/platform/database/databasetest"
+)
+
+// lookupTables are the tables filled from the maps, with their name columns.
+var lookupTables = []struct {
+	table  string
+	column string
+	names  map[string]string
+}{
+	{"statuses", "name_status", Statuses},
+	{"conclusions", "conclusion", Conclusions},
+	{"categories", "name_category", Categories},
+	{"regions", "name_region", Regions},
+	{"groups", "name_group", Groups},
+	{"roles", "name_role", Roles},
+}
+
+// newFakeDb opens a fake database with the lookup rows of the maps and sets
+// the keys for encrypted fields and blind indexes.
+func newFakeDb(t *testing.T) (*gorm.DB, *databasetest.Fake) {
+	t.Setenv("ENCRYPTION_KEYS", "test:secret")
+	t.Setenv("ENCRYPTION_KEY_ID", "test")
+	t.Setenv("BLIND_INDEX_KEY", "blind")
+
+	db, fake := databasetest.New(t)
+	for _, lookup := range lookupTables {
+		names := lookup.names
+		columns := []string{"id", lookup.column}
+		fake.AnswerFunc(`FROM "`+lookup.table+`" WHERE "`+lookup.table+`"."`+lookup.column+`" = \$1`, columns,
+			func(args []driver.Value) [][]driver.Value {
+				for _, key := range sortedKeys(names) {
+					if names[key] == args[0] {
+						return [][]driver.Value{{int64(lookupID(names, key)), names[key]}}
+					}
+				}
+				return nil
+			})
+		fake.AnswerFunc(`FROM "`+lookup.table+`" WHERE "`+lookup.table+`"."id" = \$1`, columns,
+			func(args []driver.Value) [][]driver.Value {
+				for _, key := range sortedKeys(names) {
+					if int64(lookupID(names, key)) == args[0] {
+						return [][]driver.Value{{args[0], names[key]}}
+					}
+				}
+				return nil
+			})
+	}
+	return db, fake
+}
+
+// lookupID returns the id the fake database gives to the key of the map.
+func lookupID(names map[string]string, key string) uint {
+	for i, name := range sortedKeys(names) {
+		if name == key {
+			return uint(i + 1)
+		}
+	}
+	return 0
+}
+
+func sortedKeys(names map[string]string) []string {
+	keys := []string{}
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Document validity values.
+const (
+	DocumentValid    = "valid"
+	DocumentExpiring = "expiring"
+	DocumentInvalid  = "invalid"
+	DocumentUnknown  = "unknown"
+)
+
+// DefaultDocumentRules are created with the rules table. The Russian passport
+// has to be replaced within 90 days after the 20th and the 45th birthday.
+var DefaultDocumentRules []models.DocumentRule = []models.DocumentRule{
+	{View: PassportView, Ages: "20,45", GraceDays: 90},
+	{View: "Заграничный паспорт", Expiry: true},
+	{View: "Паспорт иностранного гражданина", Expiry: true},
+	{View: "Вид на жительство", Expiry: true},
+}
+
+type DocumentAlert struct {
+	DocumentID uint       `json:"document_id"`
+	PersonID   uint       `json:"person_id"`
+	FullName   string     `json:"fullname"`
+	View       string     `json:"view"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Validity   string     `json:"validity"`
+	Officer    string     `json:"officer"`
+}
+
+// DocumentRemindDays returns how many days before the end of validity a
+// document is reported as expiring.
+func DocumentRemindDays() int {
+	return envDays("DOCUMENT_REMIND_DAYS", 60)
+}
+
+// ValidateDocumentRule checks the ages and the terms of a validity rule.
+func ValidateDocumentRule(rule *models.DocumentRule) FieldErrors {
+	errs := FieldErrors{}
+	rule.View = strings.TrimSpace(rule.View)
+	if rule.View == "" {
+		errs["view"] = "не указан вид документа"
+	}
+	if _, err := ruleAges(rule.Ages); err != nil {
+		errs["ages"] = "возрасты указываются числами через запятую"
+	}
+	if rule.Years < 0 || rule.GraceDays < 0 {
+		errs["years"] = "сроки не могут быть отрицательными"
+	}
+	return errs
+}
+
+// DocumentValidUntil works out the last day the document is valid. An
+// explicit expiry date wins over the rule. Documents without a rule are valid
+// indefinitely and return nil, as do passports issued after the last age.
+func DocumentValidUntil(document models.Document, rule models.DocumentRule, birthDate time.Time) (*time.Time, string) {
+	if document.Expiry != nil && !document.Expiry.IsZero() {
+		return document.Expiry, ""
+	}
+	if rule.ID == 0 {
+		return nil, DocumentValid
+	}
+
+	if rule.Ages != "" {
+		if document.Issue.IsZero() || birthDate.IsZero() {
+			return nil, DocumentUnknown
+		}
+		ages, _ := ruleAges(rule.Ages)
+		for _, age := range ages {
+			replace := birthDate.AddDate(age, 0, 0)
+			if document.Issue.Before(replace) {
+				until := replace.AddDate(0, 0, rule.GraceDays)
+				return &until, ""
+			}
+		}
+		return nil, DocumentValid
+	}
+	if rule.Years > 0 {
+		if document.Issue.IsZero() {
+			return nil, DocumentUnknown
+		}
+		until := document.Issue.AddDate(rule.Years, 0, rule.GraceDays)
+		return &until, ""
+	}
+	if rule.Expiry {
+		return nil, DocumentUnknown
+	}
+	return nil, DocumentValid
+}
+
+// SetDocumentValidity fills the validity of the documents of the person.
+func SetDocumentValidity(db *gorm.DB, documents []models.Document, birthDate time.Time) {
+	rules := documentRules(db)
+	now := time.Now()
+	soon := now.AddDate(0, 0, DocumentRemindDays())
+	for i := range documents {
+		until, validity := DocumentValidUntil(documents[i], rules[documents[i].View], birthDate)
+		if until != nil {
+			switch {
+			case until.Before(now):
+				validity = DocumentInvalid
+			case until.Before(soon):
+				validity = DocumentExpiring
+			default:
+				validity = DocumentValid
+			}
+		}
+		documents[i].ValidUntil, documents[i].Validity = until, validity
+	}
+}
+
+// PersonDocuments returns the documents of the person with their validity.
+func PersonDocuments(db *gorm.DB, person models.Person) []models.Document {
+	documents := []models.Document{}
+	db.Where("person_id = ?", person.ID).Order("id").Find(&documents)
+	SetDocumentValidity(db, documents, person.BirthDate.Time)
+	return documents
+}
+
+// DocumentAlerts returns the invalid, expiring and unknown documents of
+// staff-category persons, with the officer of their last check.
+func DocumentAlerts(db *gorm.DB) []DocumentAlert {
+	var persons []models.Person
+	db.
+		Scopes(StaffOnly).
+		Where("anonymized_at IS NULL").
+		Find(&persons)
+
+	alerts := []DocumentAlert{}
+	for _, person := range persons {
+		var officer string
+		db.
+			Model(&models.Check{}).
+			Where("person_id = ?", person.ID).
+			Order("id DESC").
+			Limit(1).
+			Pluck("officer", &officer)
+		for _, document := range PersonDocuments(db, person) {
+			if document.Validity == DocumentValid {
+				continue
+			}
+			alerts = append(alerts, DocumentAlert{
+				DocumentID: document.ID,
+				PersonID:   person.ID,
+				FullName:   person.FullName,
+				View:       document.View,
+				ValidUntil: document.ValidUntil,
+				Validity:   document.Validity,
+				Officer:    officer,
+			})
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Officer != alerts[j].Officer {
+			return alerts[i].Officer < alerts[j].Officer
+		}
+		return alerts[i].FullName < alerts[j].FullName
+	})
+	return alerts
+}
+
+// NotifyDocuments sends each officer one message with the documents that
+// became invalid or expiring since the last run. Documents without an
+// officer are reported to admins. Each state is reported once per date.
+func NotifyDocuments(db *gorm.DB) (int, error) {
+	var admins []models.User
+	db.
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name_role = ? AND users.blocked = ? AND users.deleted = ?", "admin", false, false).
+		Find(&admins)
+
+	lines := map[string][]string{}
+	for _, alert := range DocumentAlerts(db) {
+		if alert.Validity == DocumentUnknown {
+			continue
+		}
+		notice := models.SlaNotice{Kind: "document", ItemID: alert.DocumentID, Notice: alert.Validity, Deadline: *alert.ValidUntil}
+		var count int64
+		db.
+			Model(&models.SlaNotice{}).
+			Where("kind = ? AND item_id = ? AND notice = ? AND deadline = ?", notice.Kind, notice.ItemID, notice.Notice, notice.Deadline).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := db.Create(&notice).Error; err != nil {
+			return 0, err
+		}
+		state := "истекает"
+		if alert.Validity == DocumentInvalid {
+			state = "недействителен"
+		}
+		lines[alert.Officer] = append(lines[alert.Officer], fmt.Sprintf(
+			"%s: %s %s с %s", alert.FullName, alert.View, state, alert.ValidUntil.Format("02.01.2006"),
+		))
+	}
+
+	sent := 0
+	for officerName, officerLines := range lines {
+		recipients := []uint{}
+		var officer models.User
+		if officerName != "" {
+			db.Where("full_name = ?", officerName).First(&officer)
+		}
+		if officer.ID != 0 {
+			recipients = append(recipients, officer.ID)
+		} else {
+			for _, admin := range admins {
+				recipients = append(recipients, admin.ID)
+			}
+		}
+		for _, userID := range recipients {
+			db.Create(&models.Message{
+				Title:          "Документы сотрудников",
+				MessageContent: strings.Join(officerLines, "\n"),
+				StatusRead:     "new",
+				UserID:         userID,
+			})
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func documentRules(db *gorm.DB) map[string]models.DocumentRule {
+	var rules []models.DocumentRule
+	db.Find(&rules)
+	byView := map[string]models.DocumentRule{}
+	for _, rule := range rules {
+		byView[rule.View] = rule
+	}
+	return byView
+}
+
+func ruleAges(value string) ([]int, error) {
+	ages := []int{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		age, err := strconv.Atoi(part)
+		if err != nil || age <= 0 {
+			return nil, fmt.Errorf("invalid age %q", part)
+		}
+		ages = append(ages, age)
+	}
+	sort.Ints(ages)
+	return ages, nil
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"backend/app/models"
+)
+
+func TestDocumentValidUntil(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	birth := date(1990, 3, 15)
+	expiry := date(2030, 1, 1)
+	passport := models.DocumentRule{ID: 1, View: PassportView, Ages: "20,45", GraceDays: 90}
+	years := models.DocumentRule{ID: 2, Years: 10}
+	foreign := models.DocumentRule{ID: 3, Expiry: true}
+
+	tests := []struct {
+		name     string
+		document models.Document
+		rule     models.DocumentRule
+		birth    time.Time
+		until    *time.Time
+		validity string
+	}{
+		{"issued at 14", models.Document{Issue: date(2004, 4, 1)}, passport, birth, ptr(date(2010, 6, 13)), ""},
+		{"issued at 20", models.Document{Issue: date(2010, 3, 15)}, passport, birth, ptr(date(2035, 6, 13)), ""},
+		{"issued before 45", models.Document{Issue: date(2020, 1, 1)}, passport, birth, ptr(date(2035, 6, 13)), ""},
+		{"issued at 45", models.Document{Issue: date(2035, 3, 15)}, passport, birth, nil, DocumentValid},
+		{"issued after 45", models.Document{Issue: date(2036, 1, 1)}, passport, birth, nil, DocumentValid},
+		{"no issue date", models.Document{}, passport, birth, nil, DocumentUnknown},
+		{"no birth date", models.Document{Issue: date(2010, 3, 15)}, passport, time.Time{}, nil, DocumentUnknown},
+		{"explicit expiry", models.Document{Issue: date(2004, 4, 1), Expiry: &expiry}, passport, birth, &expiry, ""},
+		{"years", models.Document{Issue: date(2020, 5, 1)}, years, birth, ptr(date(2030, 5, 1)), ""},
+		{"years without issue", models.Document{}, years, birth, nil, DocumentUnknown},
+		{"expiry missing", models.Document{Issue: date(2020, 5, 1)}, foreign, birth, nil, DocumentUnknown},
+		{"no rule", models.Document{Issue: date(2004, 4, 1)}, models.DocumentRule{}, birth, nil, DocumentValid},
+	}
+	for _, test := range tests {
+		until, validity := DocumentValidUntil(test.document, test.rule, test.birth)
+		if validity != test.validity {
+			t.Errorf("%s: validity = %q, want %q", test.name, validity, test.validity)
+		}
+		if (until == nil) != (test.until == nil) || until != nil && !until.Equal(*test.until) {
+			t.Errorf("%s: until = %v, want %v", test.name, until, test.until)
+		}
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
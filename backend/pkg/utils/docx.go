@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DOCX templates mark the fields as {{name}}. Word often splits such a
+// placeholder into several runs, so the tags inside a placeholder are kept
+// after the value to leave the document well-formed.
+
+var (
+	docxPlaceholder = regexp.MustCompile(`\{(?:<[^>]*>)*\{((?:[^{}<]|<[^>]*>)*?)\}(?:<[^>]*>)*\}`)
+	docxTag         = regexp.MustCompile(`<[^>]*>`)
+	docxParts       = regexp.MustCompile(`^word/(document|header\d*|footer\d*)\.xml$`)
+)
+
+// FillDocx replaces the placeholders of the template with the fields. Unknown
+// placeholders are left as they are, so mistakes in templates can be seen.
+func FillDocx(template []byte, fields map[string]string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(template), int64(len(template)))
+	if err != nil {
+		return nil, err
+	}
+	if !docxHasDocument(reader) {
+		return nil, errors.New("the file is not a DOCX document")
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, file := range reader.File {
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if docxParts.MatchString(file.Name) {
+			content = fillDocxPart(content, fields)
+		}
+		header := file.FileHeader
+		out, err := writer.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := out.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func fillDocxPart(content []byte, fields map[string]string) []byte {
+	return docxPlaceholder.ReplaceAllFunc(content, func(match []byte) []byte {
+		inner := docxPlaceholder.FindSubmatch(match)[1]
+		key := strings.TrimSpace(string(docxTag.ReplaceAll(inner, nil)))
+		value, ok := fields[key]
+		if !ok {
+			return match
+		}
+		var escaped bytes.Buffer
+		xml.EscapeText(&escaped, []byte(value))
+		text := strings.ReplaceAll(escaped.String(), "&#xA;", `</w:t><w:br/><w:t xml:space="preserve">`)
+		return append([]byte(text), bytes.Join(docxTag.FindAll(match, -1), nil)...)
+	})
+}
+
+// DocxParagraphs returns the text of the paragraphs of the document body.
+func DocxParagraphs(document []byte) ([]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(document), int64(len(document)))
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	for _, file := range reader.File {
+		if file.Name == "word/document.xml" {
+			if content, err = readZipFile(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if content == nil {
+		return nil, errors.New("the file is not a DOCX document")
+	}
+
+	paragraphs := []string{}
+	var paragraph strings.Builder
+	inText := false
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "t":
+				inText = true
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "tab":
+				paragraph.WriteString("    ")
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				paragraphs = append(paragraphs, paragraph.String())
+				paragraph.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(token)
+			}
+		}
+	}
+	return paragraphs, nil
+}
+
+// NewDocx builds a plain DOCX document with a paragraph for every line.
+func NewDocx(paragraphs []string) ([]byte, error) {
+	var body bytes.Buffer
+	for _, paragraph := range paragraphs {
+		body.WriteString(`<w:p><w:r><w:t xml:space="preserve">`)
+		xml.EscapeText(&body, []byte(paragraph))
+		body.WriteString(`</w:t></w:r></w:p>`)
+	}
+	text := strings.ReplaceAll(body.String(), "&#xA;", `</w:t><w:br/><w:t xml:space="preserve">`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/word/document.xml" ` +
+			`ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+			`Target="word/document.xml"/></Relationships>`},
+		{"word/document.xml", xml.Header +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			text + `</w:body></w:document>`},
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, part := range parts {
+		out, err := writer.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(out, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func docxHasDocument(reader *zip.Reader) bool {
+	for _, file := range reader.File {
+		if file.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,67 @@
+package utils
+
+import "testing"
+
+func TestFillDocxPart(t *testing.T) {
+	fields := map[string]string{
+		"fullname": "Иванов Иван",
+		"comments": "первая\nвторая",
+		"company":  `ООО "Рога & копыта"`,
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"single run",
+			`<w:r><w:t>Кандидат: {{fullname}}</w:t></w:r>`,
+			`<w:r><w:t>Кандидат: Иванов Иван</w:t></w:r>`,
+		},
+		{
+			"spaces inside",
+			`<w:t>{{ fullname }}</w:t>`,
+			`<w:t>Иванов Иван</w:t>`,
+		},
+		{
+			"split name",
+			`<w:r><w:t>{{full</w:t></w:r><w:r><w:t>name}}</w:t></w:r>`,
+			`<w:r><w:t>Иванов Иван</w:t></w:r><w:r><w:t></w:t></w:r>`,
+		},
+		{
+			"split braces",
+			`<w:r><w:t>{</w:t></w:r><w:r><w:t>{fullname}</w:t></w:r><w:r><w:t>}</w:t></w:r>`,
+			`<w:r><w:t>Иванов Иван</w:t></w:r><w:r><w:t></w:t></w:r><w:r><w:t></w:t></w:r>`,
+		},
+		{
+			"split with formatting",
+			`<w:r><w:t>{{</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>fullname</w:t></w:r><w:r><w:t>}}</w:t></w:r>`,
+			`<w:r><w:t>Иванов Иван</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t></w:t></w:r><w:r><w:t></w:t></w:r>`,
+		},
+		{
+			"escaped value",
+			`<w:t>{{company}}</w:t>`,
+			`<w:t>ООО &#34;Рога &amp; копыта&#34;</w:t>`,
+		},
+		{
+			"line breaks",
+			`<w:t>{{comments}}</w:t>`,
+			`<w:t>первая</w:t><w:br/><w:t xml:space="preserve">вторая</w:t>`,
+		},
+		{
+			"unknown placeholder",
+			`<w:t>{{unknown}} {{fullname}}</w:t>`,
+			`<w:t>{{unknown}} Иванов Иван</w:t>`,
+		},
+		{
+			"single braces",
+			`<w:t>{fullname}</w:t>`,
+			`<w:t>{fullname}</w:t>`,
+		},
+	}
+	for _, test := range tests {
+		if got := string(fillDocxPart([]byte(test.content), fields)); got != test.want {
+			t.Errorf("%s: fillDocxPart() = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Pseudonym returns a keyed hash of the identifier that stays the same across
+// exports as long as ANALYTICS_KEY is unchanged.
+func Pseudonym(kind string, value string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ANALYTICS_KEY")))
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func personPseudonym(id uint) string {
+	return Pseudonym("person", strconv.FormatUint(uint64(id), 10))
+}
+
+// ExportAnalytics writes pseudonymized persons, checks, status transitions,
+// processing timings and the dictionaries as CSV files. The create function
+// returns the writer for each file name.
+func ExportAnalytics(db *gorm.DB, create func(name string) (io.Writer, error)) error {
+	if os.Getenv("ANALYTICS_KEY") == "" {
+		return errors.New("ANALYTICS_KEY is not set")
+	}
+
+	var persons []models.Person
+	db.Order("id").Find(&persons)
+	rows := [][]string{{"person", "category_id", "region_id", "status_id", "birth_year", "created", "updated"}}
+	for _, person := range persons {
+		birthYear := ""
+		if !person.BirthDate.IsZero() {
+			birthYear = strconv.Itoa(person.BirthDate.Year())
+		}
+		rows = append(rows, []string{
+			personPseudonym(person.ID),
+			formatID(person.CategoryID),
+			formatID(person.RegionID),
+			formatID(person.StatusID),
+			birthYear,
+			formatTime(person.CreatedAt),
+			formatTime(person.UpdatedAt),
+		})
+	}
+	if err := writeCsv(create, "persons.csv", rows); err != nil {
+		return err
+	}
+
+	// Records of persons in the trash are left out like the persons.
+	live := db.Model(&models.Person{}).Select("id")
+
+	var checks []models.Check
+	db.Where("person_id IN (?)", live).Order("id").Find(&checks)
+	rows = [][]string{{"check", "person", "conclusion_id", "officer", "created", "updated", "duration_hours"}}
+	for _, check := range checks {
+		rows = append(rows, []string{
+			Pseudonym("check", formatID(check.ID)),
+			personPseudonym(check.PersonID),
+			formatID(check.ConclusionID),
+			Pseudonym("officer", check.Officer),
+			formatTime(check.CreatedAt),
+			formatTime(check.UpdatedAt),
+			formatHours(check.UpdatedAt.Sub(check.CreatedAt)),
+		})
+	}
+	if err := writeCsv(create, "checks.csv", rows); err != nil {
+		return err
+	}
+
+	var histories []models.StatusHistory
+	db.Where("person_id IN (?)", live).Order("person_id, created_at").Find(&histories)
+	rows = [][]string{{"person", "from", "to", "created"}}
+	started := map[uint]time.Time{}
+	finished := map[uint]time.Time{}
+	for _, history := range histories {
+		rows = append(rows, []string{
+			personPseudonym(history.PersonID),
+			history.FromStatus,
+			history.ToStatus,
+			formatTime(history.CreatedAt),
+		})
+		if _, ok := started[history.PersonID]; !ok {
+			started[history.PersonID] = history.CreatedAt
+		}
+		if history.ToStatus == "finish" || history.ToStatus == "cancel" {
+			finished[history.PersonID] = history.CreatedAt
+		}
+	}
+	if err := writeCsv(create, "transitions.csv", rows); err != nil {
+		return err
+	}
+
+	rows = [][]string{{"person", "started", "finished", "processing_hours"}}
+	for _, person := range persons {
+		start, ok := started[person.ID]
+		if !ok {
+			continue
+		}
+		row := []string{personPseudonym(person.ID), formatTime(start), "", ""}
+		if finish, ok := finished[person.ID]; ok {
+			row[2] = formatTime(finish)
+			row[3] = formatHours(finish.Sub(start))
+		}
+		rows = append(rows, row)
+	}
+	if err := writeCsv(create, "timings.csv", rows); err != nil {
+		return err
+	}
+
+	var conclusions []models.Conclusion
+	db.Order("id").Find(&conclusions)
+	rows = [][]string{{"id", "conclusion"}}
+	for _, conclusion := range conclusions {
+		rows = append(rows, []string{formatID(conclusion.ID), conclusion.Conclusion})
+	}
+	if err := writeCsv(create, "conclusions.csv", rows); err != nil {
+		return err
+	}
+
+	var regions []models.Region
+	db.Order("id").Find(&regions)
+	rows = [][]string{{"id", "region"}}
+	for _, region := range regions {
+		rows = append(rows, []string{formatID(region.ID), region.NameRegion})
+	}
+	if err := writeCsv(create, "regions.csv", rows); err != nil {
+		return err
+	}
+
+	var statuses []models.Status
+	db.Order("id").Find(&statuses)
+	rows = [][]string{{"id", "status"}}
+	for _, status := range statuses {
+		rows = append(rows, []string{formatID(status.ID), status.NameStatus})
+	}
+	return writeCsv(create, "statuses.csv", rows)
+}
+
+func writeCsv(create func(name string) (io.Writer, error), name string, rows [][]string) error {
+	w, err := create(name)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatHours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 1, 64)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPseudonym(t *testing.T) {
+	t.Setenv("ANALYTICS_KEY", "first")
+	first := Pseudonym("person", "5")
+	if first != Pseudonym("person", "5") {
+		t.Errorf("Pseudonym() differs between calls")
+	}
+	if first == Pseudonym("check", "5") {
+		t.Errorf("Pseudonym() is equal for different kinds")
+	}
+	if _, err := hex.DecodeString(first); err != nil || len(first) != 32 {
+		t.Errorf("Pseudonym() = %q, want 32 hex digits", first)
+	}
+	t.Setenv("ANALYTICS_KEY", "second")
+	if first == Pseudonym("person", "5") {
+		t.Errorf("Pseudonym() does not depend on ANALYTICS_KEY")
+	}
+}
+
+func TestExportAnalytics(t *testing.T) {
+	db, fake := newFakeDb(t)
+	t.Setenv("ANALYTICS_KEY", "secret")
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fake.Answer(`FROM "people"`, []string{"id", "full_name", "category_id", "status_id", "created_at"},
+		[]driver.Value{int64(5), "Иванов Иван", int64(1), int64(2), start},
+	)
+	fake.Answer(`FROM "checks"`, []string{"id", "person_id", "officer", "created_at", "updated_at"},
+		[]driver.Value{int64(8), int64(5), "Петров", start, start.Add(30 * time.Hour)},
+	)
+	fake.Answer(`FROM "status_histories"`, []string{"person_id", "from_status", "to_status", "created_at"},
+		[]driver.Value{int64(5), "", "new", start},
+		[]driver.Value{int64(5), "manual", "finish", start.Add(48 * time.Hour)},
+	)
+
+	files := map[string]*bytes.Buffer{}
+	err := ExportAnalytics(db, func(name string) (io.Writer, error) {
+		files[name] = &bytes.Buffer{}
+		return files[name], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) [][]string {
+		rows, err := csv.NewReader(files[name]).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return rows
+	}
+	person := personPseudonym(5)
+	persons := read("persons.csv")
+	if len(persons) != 2 || persons[1][0] != person {
+		t.Fatalf("persons.csv = %v, want the pseudonym of person 5", persons)
+	}
+	for name, buffer := range files {
+		if strings.Contains(buffer.String(), "Иванов") || strings.Contains(buffer.String(), "Петров") {
+			t.Errorf("%s contains a name", name)
+		}
+	}
+	checks := read("checks.csv")
+	if len(checks) != 2 || checks[1][1] != person || checks[1][6] != "30.0" {
+		t.Errorf("checks.csv = %v, want a 30 hour check of person 5", checks)
+	}
+	timings := read("timings.csv")
+	if len(timings) != 2 || timings[1][0] != person || timings[1][3] != "48.0" {
+		t.Errorf("timings.csv = %v, want 48 processing hours of person 5", timings)
+	}
+
+	// Checks and transitions of persons in the trash are left out.
+	for _, table := range []string{"checks", "status_histories"} {
+		query := fake.Statements(`FROM "` + table + `"`)[0]
+		if !strings.Contains(query.SQL, `person_id IN (SELECT "id" FROM "people" WHERE "people"."deleted_at" IS NULL)`) {
+			t.Errorf("%s query %q does not leave out trashed persons", table, query.SQL)
+		}
+	}
+}
+
+func TestExportAnalyticsWithoutKey(t *testing.T) {
+	db, fake := newFakeDb(t)
+	t.Setenv("ANALYTICS_KEY", "")
+	err := ExportAnalytics(db, func(string) (io.Writer, error) { return io.Discard, nil })
+	if err == nil {
+		t.Errorf("ExportAnalytics() returned no error without ANALYTICS_KEY")
+	}
+	if statements := fake.Statements(""); len(statements) != 0 {
+		t.Errorf("ExportAnalytics() ran %d statements without ANALYTICS_KEY", len(statements))
+	}
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/app/models"
+)
+
+// A GAR extract is a folder of XML files, one subfolder per region. Only
+// address objects, houses and the administrative hierarchy are imported, and
+// only their actual and active versions.
+
+var (
+	garObjectsFile   = regexp.MustCompile(`(?i)^AS_ADDR_OBJ_\d{8}_.*\.xml$`)
+	garHousesFile    = regexp.MustCompile(`(?i)^AS_HOUSES_\d{8}_.*\.xml$`)
+	garHierarchyFile = regexp.MustCompile(`(?i)^AS_ADM_HIERARCHY_\d{8}_.*\.xml$`)
+	garRegionDir     = regexp.MustCompile(`^\d{2}$`)
+)
+
+// garHouseTypes are the short names of the GAR additional house number types.
+var garHouseTypes map[string]string = map[string]string{
+	"1": "к",
+	"2": "стр",
+	"3": "соор",
+	"4": "лит",
+}
+
+type GarStats struct {
+	Objects int `json:"objects"`
+	Houses  int `json:"houses"`
+	Links   int `json:"links"`
+}
+
+// ImportGar loads the GAR extract in dir. Records already imported are
+// updated, so a newer extract can be loaded over an older one.
+func ImportGar(db *gorm.DB, dir string, batch int) (GarStats, error) {
+	stats := GarStats{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name := info.Name()
+		region := filepath.Base(filepath.Dir(path))
+		if !garRegionDir.MatchString(region) {
+			region = ""
+		}
+
+		switch {
+		case garObjectsFile.MatchString(name):
+			count, err := importGarFile(db, path, "OBJECT", batch, func(attrs map[string]string) interface{} {
+				if attrs["ISACTUAL"] != "1" || attrs["ISACTIVE"] != "1" {
+					return nil
+				}
+				return &models.FiasObject{
+					ObjectID:   garInt(attrs["OBJECTID"]),
+					GUID:       attrs["OBJECTGUID"],
+					Name:       attrs["NAME"],
+					TypeName:   attrs["TYPENAME"],
+					Level:      int(garInt(attrs["LEVEL"])),
+					NameKey:    FiasKey(attrs["NAME"]),
+					RegionCode: region,
+				}
+			})
+			stats.Objects += count
+			return err
+		case garHousesFile.MatchString(name):
+			count, err := importGarFile(db, path, "HOUSE", batch, func(attrs map[string]string) interface{} {
+				if attrs["ISACTUAL"] != "1" || attrs["ISACTIVE"] != "1" {
+					return nil
+				}
+				number := GarHouseNumber(
+					attrs["HOUSENUM"], attrs["ADDTYPE1"], attrs["ADDNUM1"], attrs["ADDTYPE2"], attrs["ADDNUM2"],
+				)
+				return &models.FiasHouse{
+					ObjectID:  garInt(attrs["OBJECTID"]),
+					GUID:      attrs["OBJECTGUID"],
+					Number:    number,
+					NumberKey: HouseKey(number),
+				}
+			})
+			stats.Houses += count
+			return err
+		case garHierarchyFile.MatchString(name):
+			count, err := importGarFile(db, path, "ITEM", batch, func(attrs map[string]string) interface{} {
+				if attrs["ISACTIVE"] != "1" {
+					return nil
+				}
+				return &models.FiasLink{
+					ObjectID: garInt(attrs["OBJECTID"]),
+					ParentID: garInt(attrs["PARENTOBJID"]),
+				}
+			})
+			stats.Links += count
+			return err
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// importGarFile streams the elements of the file and upserts the records
+// built from their attributes in batches.
+func importGarFile(db *gorm.DB, path string, element string, batch int, build func(map[string]string) interface{}) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	records := []interface{}{}
+	flush := func() error {
+		if len(records) == 0 {
+			return nil
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, record := range records {
+				err := tx.
+					Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "object_id"}}, UpdateAll: true}).
+					Create(record).
+					Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		count += len(records)
+		records = records[:0]
+		return err
+	}
+
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != element {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, attr := range start.Attr {
+			attrs[attr.Name.Local] = attr.Value
+		}
+		if record := build(attrs); record != nil {
+			records = append(records, record)
+		}
+		if len(records) >= batch {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, flush()
+}
+
+// GarHouseNumber joins the house number with its additional numbers the way
+// they are written in addresses, for example "5 к 2".
+func GarHouseNumber(number string, type1 string, number1 string, type2 string, number2 string) string {
+	parts := []string{}
+	if number != "" {
+		parts = append(parts, number)
+	}
+	if number1 != "" {
+		parts = append(parts, garHouseTypes[type1], number1)
+	}
+	if number2 != "" {
+		parts = append(parts, garHouseTypes[type2], number2)
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// FiasKey is the comparable form of an address object name.
+func FiasKey(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '.' || r == ',' || r == '"' || r == '«' || r == '»'
+	}), " ")
+}
+
+// HouseKey is the comparable form of a house number.
+func HouseKey(number string) string {
+	return strings.ReplaceAll(FiasKey(number), " ", "")
+}
+
+func garInt(value string) int64 {
+	number, _ := strconv.ParseInt(value, 10, 64)
+	return number
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestGarHouseNumber(t *testing.T) {
+	tests := []struct {
+		number, type1, number1, type2, number2 string
+		want                                   string
+	}{
+		{"5", "", "", "", "", "5"},
+		{"5", "1", "2", "", "", "5 к 2"},
+		{"5", "1", "2", "2", "1", "5 к 2 стр 1"},
+		{"12А", "4", "Б", "", "", "12А лит Б"},
+		{"", "2", "3", "", "", "стр 3"},
+		{"7", "9", "1", "", "", "7 1"},
+		{" 7 ", "", "", "3", "2", "7 соор 2"},
+		{"", "", "", "", "", ""},
+	}
+	for _, test := range tests {
+		got := GarHouseNumber(test.number, test.type1, test.number1, test.type2, test.number2)
+		if got != test.want {
+			t.Errorf("GarHouseNumber(%q, %q, %q, %q, %q) = %q, want %q",
+				test.number, test.type1, test.number1, test.type2, test.number2, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/platform/encryption"
+)
+
+// FindDuplicate returns the stored person matching the SNILS, the INN or the
+// name and birth date of the given one, in that order. The previous names of
+// both are compared too, given as search keys for the new person.
+func FindDuplicate(db *gorm.DB, person *models.Person, previous ...string) (models.Person, error) {
+	var found models.Person
+	if err := person.SetIndexes(); err != nil {
+		return found, err
+	}
+	if person.SnilsIndex != "" {
+		db.Where("snils_index = ?", person.SnilsIndex).First(&found)
+	}
+	if found.ID == 0 && person.InnIndex != "" {
+		db.Where("inn_index = ?", person.InnIndex).First(&found)
+	}
+	if found.ID == 0 {
+		db.
+			Where("search_name = ? AND birth_date = ?", person.SearchName, person.BirthDate).
+			First(&found)
+	}
+	if found.ID == 0 && !person.BirthDate.IsZero() {
+		names := append([]string{person.SearchName}, previous...)
+		db.
+			Where("birth_date = ?", person.BirthDate).
+			Where(
+				"search_name IN ? OR id IN (SELECT person_id FROM name_changes WHERE search_name IN ?)",
+				previous, names,
+			).
+			First(&found)
+	}
+	return found, nil
+}
+
+// RotateKeys re-encrypts with the active key every row that is stored in
+// plain text or with another key, and rebuilds its blind indexes.
+func RotateKeys(db *gorm.DB, batch int) (int, error) {
+	id, _, err := encryption.ActiveKey()
+	if err != nil {
+		return 0, err
+	}
+	prefix := encryption.Prefix + id + ":"
+
+	total := 0
+	count, err := rotateTable[models.Person](db.Unscoped(), batch, prefix, []string{"snils", "inn"},
+		func(person *models.Person) ([]string, error) {
+			return []string{"snils_index", "inn_index"}, person.SetIndexes()
+		})
+	total += count
+	if err != nil {
+		return total, err
+	}
+	count, err = rotateTable[models.Document](db, batch, prefix, []string{"series", "number"},
+		func(document *models.Document) ([]string, error) {
+			return []string{"document_index"}, document.SetIndexes()
+		})
+	total += count
+	if err != nil {
+		return total, err
+	}
+	count, err = rotateTable[models.Contact](db, batch, prefix, []string{"contact"},
+		func(contact *models.Contact) ([]string, error) {
+			return []string{"contact_index"}, contact.SetIndexes()
+		})
+	total += count
+	if err != nil {
+		return total, err
+	}
+	if db.Migrator().HasTable(&models.Anketa{}) {
+		count, err = rotateTable[models.Anketa](db, batch, prefix, []string{"snils", "inn", "series", "number"},
+			func(anketa *models.Anketa) ([]string, error) {
+				return []string{"snils_index", "inn_index", "document_index"}, anketa.SetIndexes()
+			})
+		total += count
+		if err != nil {
+			return total, err
+		}
+	}
+	count, err = rotateTable[models.Version](db, batch, prefix, []string{"diff", "snapshot"}, nil)
+	return total + count, err
+}
+
+// rotateTable rewrites the encrypted columns of the rows not encrypted with
+// the active key. UpdateColumns keeps updated_at and skips the hooks, so the
+// indexes are rebuilt by the index function. Trashed persons are rotated too.
+func rotateTable[T any](db *gorm.DB, batch int, prefix string, columns []string, index func(*T) ([]string, error)) (int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, column := range columns {
+		conditions = append(conditions, "("+column+" <> '' AND "+column+" NOT LIKE ?)")
+		args = append(args, prefix+"%")
+	}
+
+	count := 0
+	var rows []T
+	result := db.
+		Where(strings.Join(conditions, " OR "), args...).
+		FindInBatches(&rows, batch, func(tx *gorm.DB, number int) error {
+			for i := range rows {
+				selected := append([]string{}, columns...)
+				if index != nil {
+					indexes, err := index(&rows[i])
+					if err != nil {
+						return err
+					}
+					selected = append(selected, indexes...)
+				}
+				err := tx.
+					Session(&gorm.Session{NewDB: true}).
+					Unscoped().
+					Model(&rows[i]).
+					Select(selected).
+					UpdateColumns(&rows[i]).
+					Error
+				if err != nil {
+					return err
+				}
+			}
+			count += len(rows)
+			log.Printf("rotated %d rows of %T", count, rows[0])
+			return nil
+		})
+	return count, result.Error
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/app/models"
+)
+
+func TestFindDuplicatePreviousNames(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`FROM "people" WHERE birth_date = \$1 AND \(search_name IN`, []string{"id", "full_name"},
+		[]driver.Value{int64(7), "Петрова Алена"},
+	)
+	person := models.Person{
+		FullName:   "Петрова Алёна",
+		SearchName: "петрова алена",
+		Snils:      "112-233-445 95",
+		BirthDate:  models.NewDate(time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	found, err := FindDuplicate(db, &person, "сидорова алена")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != 7 {
+		t.Fatalf("FindDuplicate() = %d, want person 7 by the previous name", found.ID)
+	}
+
+	queries := fake.Statements(`FROM "people"`)
+	if len(queries) != 3 {
+		t.Fatalf("FindDuplicate() ran %d queries, want by SNILS, by name and by previous names", len(queries))
+	}
+	if !strings.Contains(queries[0].SQL, "snils_index = $1") || queries[0].Args[0] != person.SnilsIndex {
+		t.Errorf("first query %q %v does not look up the SNILS", queries[0].SQL, queries[0].Args)
+	}
+	previous := queries[2]
+	if !strings.Contains(previous.SQL, "id IN (SELECT person_id FROM name_changes WHERE search_name IN ($3,$4))") {
+		t.Errorf("query %q does not match the previous names", previous.SQL)
+	}
+	if previous.Args[1] != "сидорова алена" || previous.Args[2] != "петрова алена" || previous.Args[3] != "сидорова алена" {
+		t.Errorf("query args = %v, want the previous names of the new person and both names for the stored ones", previous.Args)
+	}
+}
+
+func TestFindDuplicateWithoutBirthDate(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{FullName: "Петрова Алёна", SearchName: "петрова алена"}
+	found, err := FindDuplicate(db, &person, "сидорова алена")
+	if err != nil || found.ID != 0 {
+		t.Fatalf("FindDuplicate() = %d, %v, want no person", found.ID, err)
+	}
+	if len(fake.Statements(`name_changes`)) != 0 {
+		t.Errorf("FindDuplicate() matched previous names without a birth date")
+	}
+}
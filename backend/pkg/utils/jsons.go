@@ -96,7 +96,9 @@ type Anketa struct {
 	Staff       map[string]string
 	Document    map[string]string
 	Addresses   []map[string]string
-	Workplaces  []map[string]string
+	Workplaces  []Experience
+	Educations  []Education
+	NameChanges []NameChange
 	Contacts    []map[string]string
 	Affilations []map[string]string
 }
@@ -120,15 +122,18 @@ func JsonParse(jsonPath string) Anketa {
 		log.Println(err)
 	}
 
+	surname, firstname, patronymic := person.parseFullname()
 	resume := map[string]string{
-		"fullname":   person.parseFullname(),
+		"fullname":   CanonicalName(surname, firstname, patronymic),
+		"surname":    surname,
+		"firstname":  firstname,
+		"patronymic": patronymic,
 		"previous":   person.parsePrevious(),
 		"birthday":   person.Birthday,
 		"birthplace": person.Birthplace,
 		"citizen":    person.Citizen,
 		"exCitizen":  person.AdditionalCitizenship,
 		"marital":    person.MaritalStatus,
-		"education":  person.parseEducation(),
 		"inn":        person.Inn,
 		"snils":      person.Snils,
 	}
@@ -139,7 +144,7 @@ func JsonParse(jsonPath string) Anketa {
 	}
 
 	document := map[string]string{
-		"view":   "Паспорт РФ",
+		"view":   PassportView,
 		"series": person.PassportSerial,
 		"number": person.PassportNumber,
 		"issue":  person.PassportIssueDate,
@@ -148,39 +153,26 @@ func JsonParse(jsonPath string) Anketa {
 
 	addresses := []map[string]string{
 		{
-			"view":         "Адрес проживания",
-			"live_address": person.RegAddress,
+			"view":    "Адрес проживания",
+			"address": person.ValidAddress,
 		},
 		{
-			"view":        "Адрес регистрации",
-			"reg_address": person.ValidAddress,
+			"view":    "Адрес регистрации",
+			"address": person.RegAddress,
 		},
 	}
 
 	contacts := []map[string]string{
 		{
-			"view":  "Телефон",
-			"phone": person.ContactPhone,
+			"view":    "Телефон",
+			"contact": person.ContactPhone,
 		},
 		{
-			"view":  "Электронная почта",
-			"email": person.Email,
+			"view":    "Электронная почта",
+			"contact": person.Email,
 		},
 	}
 
-	works := person.parseWorkplace()
-	workplaces := []map[string]string{}
-	for _, item := range works {
-		workplaces = append(workplaces, map[string]string{
-			"beginDate": item.BeginDate,
-			"endDate":   item.EndDate,
-			"name":      item.Name,
-			"address":   item.Address,
-			"position":  item.Position,
-			"fire":      item.FireReason,
-		})
-	}
-
 	affs := person.parseAffilation()
 	affilations := []map[string]string{}
 	for _, item := range affs {
@@ -197,20 +189,18 @@ func JsonParse(jsonPath string) Anketa {
 		Staff:       staff,
 		Document:    document,
 		Addresses:   addresses,
-		Workplaces:  workplaces,
+		Workplaces:  person.parseWorkplace(),
+		Educations:  person.Education,
+		NameChanges: person.parseNameChanges(),
 		Contacts:    contacts,
 		Affilations: affilations,
 	}
 }
 
-func (person Person) parseFullname() string {
-	name := fmt.Sprintf(
-		"%s %s %s",
-		strings.TrimSpace(person.LastName),
-		strings.TrimSpace(person.FirstName),
-		strings.TrimSpace(person.MidName),
-	)
-	return strings.ToTitle(strings.TrimSpace(name))
+func (person Person) parseFullname() (string, string, string) {
+	return NormalizeNamePart(person.LastName),
+		NormalizeNamePart(person.FirstName),
+		NormalizeNamePart(person.MidName)
 }
 
 func (person Person) parsePrevious() string {
@@ -226,15 +216,11 @@ func (person Person) parsePrevious() string {
 	return strings.Join(previous, "")
 }
 
-func (person Person) parseEducation() string {
-	var education []string
-	if len(person.Education) > 0 {
-		for _, item := range person.Education {
-			education = append(education, fmt.Sprintf("%s, %s, %d, %d",
-				item.EducationType, item.InstitutionName, item.BeginYear, item.EndYear))
-		}
+func (person Person) parseNameChanges() []NameChange {
+	if !person.HasNameChanged {
+		return nil
 	}
-	return strings.Join(education, "")
+	return person.NameWasChanged
 }
 
 func (person Person) parseWorkplace() []Experience {
@@ -244,6 +230,7 @@ func (person Person) parseWorkplace() []Experience {
 			expirience = append(expirience, Experience{
 				BeginDate:  item.BeginDate,
 				EndDate:    item.EndDate,
+				CurrentJob: item.CurrentJob,
 				Name:       item.Name,
 				Address:    item.Address,
 				Position:   item.Position,
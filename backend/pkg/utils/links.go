@@ -0,0 +1,412 @@
+package utils
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/platform/encryption"
+)
+
+// Contact points are the phones, emails and addresses of persons and
+// connections. They are indexed by blind keys of the normalized values, so
+// shared points can be found without storing the values in plain text.
+
+var addressWords map[string]string = map[string]string{
+	"улица": "ул", "проспект": "пр", "пр-кт": "пр", "переулок": "пер", "бульвар": "б-р",
+	"шоссе": "ш", "площадь": "пл", "набережная": "наб", "город": "г", "поселок": "п",
+	"деревня": "д", "село": "с", "дом": "д", "корпус": "к", "корп": "к",
+	"строение": "стр", "квартира": "кв", "область": "обл", "район": "р-н",
+}
+
+var addressSkip map[string]bool = map[string]bool{
+	"россия": true, "российская": true, "федерация": true, "рф": true,
+}
+
+// NormalizePhone returns the phone as 11 digits starting with 7, or an empty
+// string if the value is not a phone.
+func NormalizePhone(value string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+	switch {
+	case len(digits) == 10:
+		return "7" + digits
+	case len(digits) == 11 && (digits[0] == '7' || digits[0] == '8'):
+		return "7" + digits[1:]
+	case len(digits) > 11:
+		return digits
+	}
+	return ""
+}
+
+// NormalizeEmail returns the lowercased email or an empty string if the value
+// is not an email.
+func NormalizeEmail(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	at := strings.Index(value, "@")
+	if at < 1 || at == len(value)-1 || strings.ContainsAny(value, " \t") {
+		return ""
+	}
+	return value
+}
+
+// NormalizeAddress unifies the case, the punctuation and the abbreviations of
+// an address. Postal codes and the country are dropped as they are often
+// omitted.
+func NormalizeAddress(value string) string {
+	value = strings.ReplaceAll(strings.ToLower(value), "ё", "е")
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '/' {
+			return r
+		}
+		return ' '
+	}, value)
+	words := []string{}
+	for _, word := range strings.Fields(value) {
+		if addressSkip[word] {
+			continue
+		}
+		if _, err := strconv.Atoi(word); err == nil && len(word) == 6 {
+			continue
+		}
+		if short, ok := addressWords[word]; ok {
+			word = short
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// ContactPointKey returns the kind and the blind key of a contact value.
+func ContactPointKey(value string) (string, string, error) {
+	if email := NormalizeEmail(value); email != "" {
+		key, err := encryption.BlindIndex("point:email", email)
+		return "email", key, err
+	}
+	if phone := NormalizePhone(value); phone != "" {
+		key, err := encryption.BlindIndex("point:phone", phone)
+		return "phone", key, err
+	}
+	return "", "", nil
+}
+
+func addressPointKey(value string) (string, error) {
+	return encryption.BlindIndex("point:address", NormalizeAddress(value))
+}
+
+// IndexContactPoints rebuilds the contact points of the person.
+func IndexContactPoints(db *gorm.DB, personID uint) error {
+	if personID == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where("person_id = ? AND source IN ?", personID, []string{"contact", "address"}).
+			Delete(&models.ContactPoint{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		points := []models.ContactPoint{}
+		var contacts []models.Contact
+		tx.Where("person_id = ?", personID).Find(&contacts)
+		for _, contact := range contacts {
+			kind, key, err := ContactPointKey(contact.Contact)
+			if err != nil {
+				return err
+			}
+			if key != "" {
+				points = append(points, models.ContactPoint{
+					Kind: kind, Key: key, Source: "contact", RecordID: contact.ID, PersonID: personID,
+				})
+			}
+		}
+		var addresses []models.Address
+		tx.Where("person_id = ?", personID).Find(&addresses)
+		for _, address := range addresses {
+			key, err := addressPointKey(address.Address)
+			if err != nil {
+				return err
+			}
+			if key != "" {
+				points = append(points, models.ContactPoint{
+					Kind: "address", Key: key, Source: "address", RecordID: address.ID, PersonID: personID,
+				})
+			}
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		return tx.Create(&points).Error
+	})
+}
+
+// IndexConnection rebuilds the contact points of the connection. The points
+// of a deleted connection are removed.
+func IndexConnection(db *gorm.DB, connectionID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where("source = ? AND record_id = ?", "connection", connectionID).
+			Delete(&models.ContactPoint{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		var connection models.Connection
+		tx.First(&connection, connectionID)
+		if connection.ID == 0 {
+			return nil
+		}
+		points := []models.ContactPoint{}
+		for _, value := range []string{connection.Phone, connection.Mobile, connection.Mail} {
+			kind, key, err := ContactPointKey(value)
+			if err != nil {
+				return err
+			}
+			if key != "" {
+				points = append(points, models.ContactPoint{
+					Kind: kind, Key: key, Source: "connection", RecordID: connection.ID,
+				})
+			}
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		return tx.Create(&points).Error
+	})
+}
+
+// IndexAllContactPoints rebuilds the contact points of all persons, including
+// the trashed ones, and all connections.
+func IndexAllContactPoints(db *gorm.DB) (int, error) {
+	var personIDs, connectionIDs []uint
+	db.Unscoped().Model(&models.Person{}).Pluck("id", &personIDs)
+	db.Model(&models.Connection{}).Pluck("id", &connectionIDs)
+	for _, id := range personIDs {
+		if err := IndexContactPoints(db, id); err != nil {
+			return 0, err
+		}
+	}
+	for _, id := range connectionIDs {
+		if err := IndexConnection(db, id); err != nil {
+			return 0, err
+		}
+	}
+	var count int64
+	db.Model(&models.ContactPoint{}).Count(&count)
+	return int(count), nil
+}
+
+// LinkReportThreshold returns the number of persons a contact point must be
+// shared by more than to appear in the report.
+func LinkReportThreshold() int {
+	count, err := strconv.Atoi(os.Getenv("LINK_REPORT_THRESHOLD"))
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
+
+// LinkEntry is a record holding a shared contact point.
+type LinkEntry struct {
+	Source       string `json:"source"`
+	RecordID     uint   `json:"record_id"`
+	PersonID     uint   `json:"person_id,omitempty"`
+	ConnectionID uint   `json:"connection_id,omitempty"`
+	FullName     string `json:"fullname"`
+	Company      string `json:"company,omitempty"`
+	View         string `json:"view"`
+	Value        string `json:"value"`
+}
+
+// SharedPoint is a contact point with the persons and the connections
+// sharing it.
+type SharedPoint struct {
+	Kind    string      `json:"kind"`
+	Persons int         `json:"persons"`
+	Entries []LinkEntry `json:"entries"`
+}
+
+// PersonLinks returns the contact points of the person shared with other
+// persons or connections.
+func PersonLinks(db *gorm.DB, personID uint) []SharedPoint {
+	var keys []string
+	db.Model(&models.ContactPoint{}).Where("person_id = ?", personID).Distinct().Pluck("key", &keys)
+	if len(keys) == 0 {
+		return []SharedPoint{}
+	}
+	var points []models.ContactPoint
+	db.
+		Where("key IN ?", keys).
+		Where("person_id = 0 OR person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").
+		Find(&points)
+
+	shared := []SharedPoint{}
+	for _, point := range sharedPoints(db, points) {
+		for _, entry := range point.Entries {
+			if entry.PersonID != personID {
+				shared = append(shared, point)
+				break
+			}
+		}
+	}
+	return shared
+}
+
+// LinkReport returns the contact points shared by more than threshold persons.
+func LinkReport(db *gorm.DB, threshold int) []SharedPoint {
+	keys := db.
+		Model(&models.ContactPoint{}).
+		Select("key").
+		Where("person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").
+		Group("key").
+		Having("COUNT(DISTINCT person_id) > ?", threshold)
+	var points []models.ContactPoint
+	db.
+		Where("key IN (?)", keys).
+		Where("person_id = 0 OR person_id IN (SELECT id FROM people WHERE deleted_at IS NULL)").
+		Find(&points)
+
+	shared := sharedPoints(db, points)
+	sort.SliceStable(shared, func(i, j int) bool {
+		return shared[i].Persons > shared[j].Persons
+	})
+	return shared
+}
+
+// sharedPoints groups the points by key and resolves the records behind them.
+func sharedPoints(db *gorm.DB, points []models.ContactPoint) []SharedPoint {
+	records := loadLinkRecords(db, points)
+	groups := map[string]*SharedPoint{}
+	order := []string{}
+	persons := map[string]map[uint]bool{}
+	for _, point := range points {
+		entry, ok := records.entry(point)
+		if !ok {
+			continue
+		}
+		group, ok := groups[point.Key]
+		if !ok {
+			group = &SharedPoint{Kind: point.Kind, Entries: []LinkEntry{}}
+			groups[point.Key] = group
+			persons[point.Key] = map[uint]bool{}
+			order = append(order, point.Key)
+		}
+		group.Entries = append(group.Entries, entry)
+		if point.PersonID != 0 {
+			persons[point.Key][point.PersonID] = true
+		}
+	}
+
+	shared := []SharedPoint{}
+	for _, key := range order {
+		group := groups[key]
+		group.Persons = len(persons[key])
+		shared = append(shared, *group)
+	}
+	return shared
+}
+
+// linkRecords holds the records behind a set of contact points by id.
+type linkRecords struct {
+	contacts    map[uint]models.Contact
+	addresses   map[uint]models.Address
+	connections map[uint]models.Connection
+	names       map[uint]string
+}
+
+// loadLinkRecords loads the records and the person names of the points with
+// one query per table.
+func loadLinkRecords(db *gorm.DB, points []models.ContactPoint) linkRecords {
+	ids := map[string][]uint{}
+	personIDs := []uint{}
+	for _, point := range points {
+		ids[point.Source] = append(ids[point.Source], point.RecordID)
+		if point.PersonID != 0 {
+			personIDs = append(personIDs, point.PersonID)
+		}
+	}
+
+	records := linkRecords{
+		contacts:    map[uint]models.Contact{},
+		addresses:   map[uint]models.Address{},
+		connections: map[uint]models.Connection{},
+		names:       map[uint]string{},
+	}
+	if len(ids["contact"]) > 0 {
+		var contacts []models.Contact
+		db.Where("id IN ?", ids["contact"]).Find(&contacts)
+		for _, contact := range contacts {
+			records.contacts[contact.ID] = contact
+		}
+	}
+	if len(ids["address"]) > 0 {
+		var addresses []models.Address
+		db.Where("id IN ?", ids["address"]).Find(&addresses)
+		for _, address := range addresses {
+			records.addresses[address.ID] = address
+		}
+	}
+	if len(ids["connection"]) > 0 {
+		var connections []models.Connection
+		db.Where("id IN ?", ids["connection"]).Find(&connections)
+		for _, connection := range connections {
+			records.connections[connection.ID] = connection
+		}
+	}
+	if len(personIDs) > 0 {
+		var people []models.Person
+		db.Select("id, full_name").Where("id IN ?", personIDs).Find(&people)
+		for _, person := range people {
+			records.names[person.ID] = person.FullName
+		}
+	}
+	return records
+}
+
+func (records linkRecords) entry(point models.ContactPoint) (LinkEntry, bool) {
+	entry := LinkEntry{Source: point.Source, RecordID: point.RecordID, PersonID: point.PersonID}
+	switch point.Source {
+	case "contact":
+		contact, ok := records.contacts[point.RecordID]
+		if !ok {
+			return entry, false
+		}
+		entry.View, entry.Value = contact.View, contact.Contact
+	case "address":
+		address, ok := records.addresses[point.RecordID]
+		if !ok {
+			return entry, false
+		}
+		entry.View, entry.Value = address.View, address.Address
+	case "connection":
+		connection, ok := records.connections[point.RecordID]
+		if !ok {
+			return entry, false
+		}
+		entry.ConnectionID = connection.ID
+		entry.FullName, entry.Company = connection.Fullname, connection.Company
+		for _, value := range []string{connection.Phone, connection.Mobile, connection.Mail} {
+			if _, key, _ := ContactPointKey(value); key == point.Key {
+				entry.Value = value
+			}
+		}
+		return entry, true
+	default:
+		return entry, false
+	}
+
+	entry.FullName = records.names[point.PersonID]
+	return entry, true
+}
@@ -0,0 +1,44 @@
+package utils
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"+7 (912) 345-67-89", "79123456789"},
+		{"8 912 345 67 89", "79123456789"},
+		{"79123456789", "79123456789"},
+		{"9123456789", "79123456789"},
+		{"+44 20 7946 0958", "442079460958"},
+		{"19123456789", ""},
+		{"345-67-89", ""},
+		{"ivan@example.com", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizePhone(test.value); got != test.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"г. Москва, ул. Ленина, д. 1, кв. 2", "г москва ул ленина д 1 кв 2"},
+		{"Россия, 101000, город Москва, улица Ленина, дом 1, квартира 2", "г москва ул ленина д 1 кв 2"},
+		{"Российская Федерация, Московская область, поселок Заречье", "московская обл п заречье"},
+		{"Санкт-Петербург, Невский пр-кт, 10/2, корп. 3", "санкт-петербург невский пр 10/2 к 3"},
+		{"РФ, Ёлкино, Берёзовая ул., 5", "елкино березовая ул 5"},
+		{"  ", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizeAddress(test.value); got != test.want {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -9,9 +9,11 @@ var Regions map[string]string = map[string]string{
 }
 
 var Groups map[string]string = map[string]string{
-	"admins":   "admins",
-	"staffsec": "staffsec",
-	"api":      "api",
+	"admins":        "admins",
+	"staffsec":      "staffsec",
+	"api":           "api",
+	"reviewers":     "reviewers",
+	"vip_reviewers": "vip_reviewers",
 }
 
 var Roles map[string]string = map[string]string{
@@ -35,6 +37,7 @@ var Statuses map[string]string = map[string]string{
 	"robot":    "Робот",
 	"reply":    "Обработан",
 	"poligraf": "ПФО",
+	"approval": "Согласование",
 	"finish":   "Окончено",
 	"cancel":   "Отменено",
 	"error":    "Ошибка",
@@ -45,5 +48,6 @@ var Conclusions map[string]string = map[string]string{
 	"with_comment": "Согласовано с комментарием",
 	"denied":       "Отказано в согласовании",
 	"saved":        "Сохранен",
+	"pfo":          "Направлен на ПФО",
 	"canceled":     "Отменено",
 }
@@ -0,0 +1,477 @@
+package utils
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Migrate updates the schema of all tables and runs the data migrations.
+func Migrate(db *gorm.DB) error {
+	if err := migrateBirthDates(db); err != nil {
+		return err
+	}
+
+	legacyDeadlines := !db.Migrator().HasTable(&models.SlaRule{}) && db.Migrator().HasTable(&models.Check{})
+	newContactPoints := !db.Migrator().HasTable(&models.ContactPoint{})
+	legacyRelations := db.Migrator().HasColumn(&models.Relation{}, "relation")
+	newDocumentRules := !db.Migrator().HasTable(&models.DocumentRule{})
+	legacyEducation := db.Migrator().HasColumn(&models.Person{}, "education")
+	legacyAddressViews := !db.Migrator().HasTable(&models.FiasObject{}) && db.Migrator().HasTable(&models.Address{})
+
+	err := db.AutoMigrate(
+		&models.Group{}, &models.Role{}, &models.User{}, &models.Message{},
+		&models.Region{}, &models.Category{}, &models.Status{},
+		&models.Person{}, &models.Document{}, &models.Address{}, &models.Workplace{},
+		&models.Education{}, &models.NameChange{},
+		&models.Contact{}, &models.Staff{}, &models.Affilation{}, &models.Relation{},
+		&models.Conclusion{}, &models.Check{}, &models.Poligraf{},
+		&models.Robot{}, &models.Investigation{}, &models.Inquiry{}, &models.Connection{},
+		&models.StatusHistory{}, &models.Version{},
+		&models.RetentionRule{}, &models.RetentionRun{},
+		&models.SavedFilter{}, &models.BulkJob{},
+		&models.Tag{}, &models.PersonTag{},
+		&models.Watch{}, &models.WatchEvent{}, &models.Comment{},
+		&models.SlaRule{}, &models.SlaNotice{},
+		&models.ContactPoint{},
+		&models.FiasObject{}, &models.FiasHouse{}, &models.FiasLink{},
+		&models.DocumentRule{},
+		&models.CheckTemplate{}, &models.CheckTemplateItem{}, &models.CheckItem{},
+		&models.ReportTemplate{}, &models.Report{},
+		&models.Approval{},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := migrateLookups(db); err != nil {
+		return err
+	}
+	if legacyDeadlines {
+		if err := migrateDeadlines(db); err != nil {
+			return err
+		}
+	}
+	if legacyRelations {
+		if err := migrateRelations(db); err != nil {
+			return err
+		}
+	}
+	if legacyEducation {
+		if err := migrateEducation(db); err != nil {
+			return err
+		}
+	}
+	if legacyAddressViews {
+		if err := migrateAddressViews(db); err != nil {
+			return err
+		}
+	}
+	if newContactPoints {
+		count, err := IndexAllContactPoints(db)
+		if err != nil {
+			return err
+		}
+		log.Printf("contact points indexed: %d", count)
+	}
+	if newDocumentRules {
+		if err := db.Create(&DefaultDocumentRules).Error; err != nil {
+			return err
+		}
+	}
+	migrateOrganizationKeys(db)
+	if err := migrateNames(db); err != nil {
+		return err
+	}
+	return migratePreviousNames(db)
+}
+
+// migrateLookups creates the regions, statuses, categories, groups, roles and
+// conclusions that are missing, so a database created by an older version
+// gets the rows added since.
+func migrateLookups(db *gorm.DB) error {
+	created := 0
+	create := func(model interface{}, column string, names map[string]string) error {
+		for _, name := range names {
+			var count int64
+			if err := db.Model(model).Where(column+" = ?", name).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := db.Model(model).Create(map[string]interface{}{column: name}).Error; err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	}
+	lookups := []struct {
+		model  interface{}
+		column string
+		names  map[string]string
+	}{
+		{&models.Region{}, "name_region", Regions},
+		{&models.Status{}, "name_status", Statuses},
+		{&models.Category{}, "name_category", Categories},
+		{&models.Group{}, "name_group", Groups},
+		{&models.Role{}, "name_role", Roles},
+		{&models.Conclusion{}, "conclusion", Conclusions},
+	}
+	for _, lookup := range lookups {
+		if err := create(lookup.model, lookup.column, lookup.names); err != nil {
+			return err
+		}
+	}
+	if created > 0 {
+		log.Printf("lookup rows created: %d", created)
+	}
+	return nil
+}
+
+// migrateBirthDates converts the legacy text birth_date column to a date.
+// Unparsed values stay in birth_date_raw and are reported to the log.
+func migrateBirthDates(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Person{}) {
+		return nil
+	}
+	columns, err := migrator.ColumnTypes(&models.Person{})
+	if err != nil {
+		return err
+	}
+	legacy := false
+	for _, column := range columns {
+		if column.Name() == "birth_date" {
+			dataType := strings.ToLower(column.DatabaseTypeName())
+			legacy = strings.Contains(dataType, "text") || strings.Contains(dataType, "char")
+		}
+	}
+	if !legacy {
+		return nil
+	}
+
+	if err := migrator.RenameColumn("people", "birth_date", "birth_date_raw"); err != nil {
+		return err
+	}
+	if err := migrator.AddColumn(&models.Person{}, "BirthDate"); err != nil {
+		return err
+	}
+
+	rows := []struct {
+		ID           uint
+		BirthDateRaw string
+	}{}
+	db.Unscoped().Table("people").Select("id, birth_date_raw").Find(&rows)
+
+	failed := 0
+	for _, row := range rows {
+		date, err := ParseDate(row.BirthDateRaw)
+		if err != nil {
+			failed++
+			log.Printf("person %d: cannot parse birth date %q", row.ID, row.BirthDateRaw)
+			continue
+		}
+		db.Table("people").Where("id = ?", row.ID).Update("birth_date", models.NewDate(date))
+	}
+	log.Printf("birth dates migrated: %d parsed, %d failed", len(rows)-failed, failed)
+	return nil
+}
+
+// migrateNames splits legacy full names into components and canonical forms.
+func migrateNames(db *gorm.DB) error {
+	var persons []models.Person
+	db.
+		Unscoped().
+		Where("search_name IS NULL OR search_name = ''").
+		Find(&persons)
+
+	for _, person := range persons {
+		NormalizePersonName(&person)
+		err := db.
+			Unscoped().
+			Model(&person).
+			Select("full_name", "surname", "first_name", "patronymic", "search_name").
+			Updates(&person).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(persons) > 0 {
+		log.Printf("names normalized: %d", len(persons))
+	}
+	return nil
+}
+
+// legacyPreviousNameRe matches the start of an entry of the legacy previous
+// name text, "Имя - Фамилия год документ, причина", which the anketa import
+// joined without separator.
+var legacyPreviousNameRe = regexp.MustCompile(`(\pL*) - (\pL*) (\d{1,4}) `)
+
+// ParseLegacyPreviousNames splits the legacy previous name text of the person
+// into name changes. The missing name parts are taken from the current name.
+// Text in another format is read as a single full name.
+func ParseLegacyPreviousNames(person models.Person) []models.NameChange {
+	text := person.PreviousFullName
+	changes := []models.NameChange{}
+	if strings.TrimSpace(text) == "" {
+		return changes
+	}
+
+	matches := legacyPreviousNameRe.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		change := models.NameChange{}
+		change.Surname, change.FirstName, change.Patronymic = SplitFullName(text)
+		NormalizeNameChange(&change)
+		return append(changes, change)
+	}
+	// The reason of an entry runs into the given name of the next one, which
+	// starts at its last capital letter after a lower case one.
+	starts := make([]int, len(matches))
+	for i, match := range matches {
+		starts[i] = match[2]
+		name := text[match[2]:match[3]]
+		previous := rune(0)
+		for offset, r := range name {
+			if offset > 0 && unicode.IsLower(previous) && unicode.IsUpper(r) {
+				starts[i] = match[2] + offset
+			}
+			previous = r
+		}
+	}
+	for i, match := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = starts[i+1]
+		}
+		year, _ := strconv.Atoi(text[match[6]:match[7]])
+		document, reason, _ := strings.Cut(text[match[1]:end], ", ")
+		change := models.NameChange{
+			Surname:    text[match[4]:match[5]],
+			FirstName:  text[starts[i]:match[3]],
+			Patronymic: person.Patronymic,
+			Year:       year,
+			Document:   strings.TrimSpace(document),
+			Reason:     strings.TrimSpace(reason),
+		}
+		if change.Surname == "" {
+			change.Surname = person.Surname
+		}
+		if change.FirstName == "" {
+			change.FirstName = person.FirstName
+		}
+		NormalizeNameChange(&change)
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// migratePreviousNames moves the legacy previous names of persons without
+// name changes to name change records, so search and duplicate matching find
+// them. The text is kept in previous_full_name.
+func migratePreviousNames(db *gorm.DB) error {
+	var persons []models.Person
+	err := db.
+		Unscoped().
+		Where("previous_full_name <> ''").
+		Where("id NOT IN (?)", db.Model(&models.NameChange{}).Select("person_id")).
+		Find(&persons).
+		Error
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, person := range persons {
+		changes := ParseLegacyPreviousNames(person)
+		if len(changes) == 0 {
+			continue
+		}
+		for i := range changes {
+			changes[i].PersonID = person.ID
+		}
+		if err := db.Create(&changes).Error; err != nil {
+			return err
+		}
+		count += len(changes)
+	}
+	if count > 0 {
+		log.Printf("previous names migrated: %d records of %d persons", count, len(persons))
+	}
+	return nil
+}
+
+// migrateDeadlines clears the legacy deadlines that only mirrored the last
+// save. That time is kept as the start of the work for tables that had no
+// created_at, so RecalculateDeadlines can set real due dates once SLA rules
+// are configured.
+func migrateDeadlines(db *gorm.DB) error {
+	for _, table := range []string{"poligrafs", "investigations", "inquiries"} {
+		if err := db.Exec("UPDATE " + table + " SET created_at = deadline WHERE created_at IS NULL").Error; err != nil {
+			return err
+		}
+	}
+	err := db.Exec(
+		"UPDATE checks SET created_at = COALESCE(created_at, deadline), updated_at = COALESCE(updated_at, deadline) " +
+			"WHERE created_at IS NULL OR updated_at IS NULL",
+	).Error
+	if err != nil {
+		return err
+	}
+	for _, table := range SlaKinds {
+		if err := db.Exec("UPDATE " + table + " SET deadline = NULL").Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("legacy deadlines moved to the creation dates and cleared")
+	return nil
+}
+
+// migrateRelations moves the legacy relation column to related_id. The
+// reverse rows that pointed at the relation instead of the other person are
+// dropped. Legacy relations keep their view and get no kind, so they have no
+// counterpart until an officer sets one.
+func migrateRelations(db *gorm.DB) error {
+	reverse := db.Exec(
+		"DELETE FROM relations WHERE EXISTS (" +
+			"SELECT 1 FROM relations origin WHERE origin.id = relations.relation " +
+			"AND origin.person_id = relations.person_id AND origin.id < relations.id)",
+	)
+	if reverse.Error != nil {
+		return reverse.Error
+	}
+	linked := db.Exec(
+		"UPDATE relations SET related_id = relation " +
+			"WHERE relation <> person_id AND relation IN (SELECT id FROM people)",
+	)
+	if linked.Error != nil {
+		return linked.Error
+	}
+	if err := db.Migrator().DropColumn(&models.Relation{}, "relation"); err != nil {
+		return err
+	}
+	log.Printf("relations migrated: %d linked, %d reverse rows removed", linked.RowsAffected, reverse.RowsAffected)
+	return nil
+}
+
+// legacyEducationRe matches an entry of the legacy education text, which
+// joined "type, institution, begin year, end year" entries without separator.
+var legacyEducationRe = regexp.MustCompile(`(.*?), (.*?), (\d{1,4}), (\d{1,4})`)
+
+// ParseLegacyEducation splits the legacy education text into records. Text
+// that matches no entry is kept as one more record with the text as
+// institution, and complete reports whether there was any.
+func ParseLegacyEducation(text string) (educations []models.Education, complete bool) {
+	educations = []models.Education{}
+	rest := []string{}
+	last := 0
+	for _, match := range legacyEducationRe.FindAllStringSubmatchIndex(text, -1) {
+		rest = append(rest, text[last:match[0]])
+		last = match[1]
+		begin, _ := strconv.Atoi(text[match[6]:match[7]])
+		end, _ := strconv.Atoi(text[match[8]:match[9]])
+		educations = append(educations, models.Education{
+			View:        strings.TrimSpace(text[match[2]:match[3]]),
+			Institution: strings.TrimSpace(text[match[4]:match[5]]),
+			BeginYear:   begin,
+			EndYear:     end,
+		})
+	}
+	rest = append(rest, text[last:])
+	unmatched := strings.Trim(strings.Join(rest, " "), " ,;")
+	if unmatched != "" {
+		educations = append(educations, models.Education{Institution: unmatched})
+	}
+	return educations, unmatched == ""
+}
+
+// migrateEducation moves the legacy education text of persons to education
+// records. The text stays in education_raw, and persons whose text was not
+// fully parsed are reported to the log.
+func migrateEducation(db *gorm.DB) error {
+	type legacyPerson struct {
+		ID        uint
+		Education string
+	}
+	var persons []legacyPerson
+	err := db.Raw("SELECT id, education FROM people WHERE education IS NOT NULL AND education <> ''").
+		Scan(&persons).Error
+	if err != nil {
+		return err
+	}
+
+	count, partial := 0, 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, person := range persons {
+			educations, complete := ParseLegacyEducation(person.Education)
+			if !complete {
+				partial++
+				log.Printf("person %d: education not fully parsed %q", person.ID, person.Education)
+			}
+			if len(educations) == 0 {
+				continue
+			}
+			for i := range educations {
+				educations[i].PersonID = person.ID
+			}
+			if err := tx.Create(&educations).Error; err != nil {
+				return err
+			}
+			count += len(educations)
+		}
+		return tx.Migrator().RenameColumn("people", "education", "education_raw")
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("education migrated: %d records of %d persons, %d not fully parsed", count, len(persons), partial)
+	return nil
+}
+
+// migrateAddressViews swaps the views of the address pairs imported from
+// ankety before FIAS support, when the residence address was stored as the
+// registration one and the other way round. The import created the residence
+// address right before the registration one of the same person.
+func migrateAddressViews(db *gorm.DB) error {
+	const residence, registration = "Адрес проживания", "Адрес регистрации"
+	pairs := db.Raw(
+		"SELECT a.id FROM addresses a JOIN addresses b ON b.person_id = a.person_id AND b.id = a.id + 1 "+
+			"WHERE a.view = ? AND b.view = ?",
+		residence, registration,
+	)
+	result := db.Exec(
+		"UPDATE addresses SET view = CASE WHEN view = ? THEN ? ELSE ? END WHERE id IN (?) OR id - 1 IN (?)",
+		residence, registration, residence, pairs, pairs,
+	)
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("imported address views swapped: %d addresses", result.RowsAffected)
+	return nil
+}
+
+// migrateOrganizationKeys fills the name keys of affiliations and workplaces
+// saved before the keys were added.
+func migrateOrganizationKeys(db *gorm.DB) {
+	var affilations []models.Affilation
+	db.Where("name_key IS NULL AND name <> ''").Find(&affilations)
+	for _, affilation := range affilations {
+		db.Model(&affilation).UpdateColumn("name_key", models.OrganizationKey(affilation.Name))
+	}
+	var workplaces []models.Workplace
+	db.Where("name_key IS NULL AND workplace <> ''").Find(&workplaces)
+	for _, workplace := range workplaces {
+		db.Model(&workplace).UpdateColumn("name_key", models.OrganizationKey(workplace.Workplace))
+	}
+	if count := len(affilations) + len(workplaces); count > 0 {
+		log.Printf("organization keys filled: %d", count)
+	}
+}
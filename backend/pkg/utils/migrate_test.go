@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"backend/app/models"
+)
+
+func TestParseLegacyEducation(t *testing.T) {
+	tests := []struct {
+		text     string
+		want     string
+		complete bool
+	}{
+		{
+			"Высшее, МГУ, 2000, 2005",
+			"Высшее|МГУ|2000|2005",
+			true,
+		},
+		{
+			"Высшее, МГУ, 2000, 2005Среднее специальное, Колледж связи, 1995, 1999",
+			"Высшее|МГУ|2000|2005; Среднее специальное|Колледж связи|1995|1999",
+			true,
+		},
+		{
+			"Высшее, МГУ, физический факультет, 2000, 2005",
+			"Высшее|МГУ, физический факультет|2000|2005",
+			true,
+		},
+		{
+			" Высшее ,  МГУ , 2000, 2005",
+			"Высшее|МГУ|2000|2005",
+			true,
+		},
+		{
+			"Высшее, МГУ, 2000, 2005; курсы английского",
+			"Высшее|МГУ|2000|2005; |курсы английского|0|0",
+			false,
+		},
+		{
+			"Окончил МГУ в 2005 году",
+			"|Окончил МГУ в 2005 году|0|0",
+			false,
+		},
+		{"   ", "", true},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		records := []string{}
+		educations, complete := ParseLegacyEducation(test.text)
+		for _, education := range educations {
+			records = append(records, fmt.Sprintf("%s|%s|%d|%d",
+				education.View, education.Institution, education.BeginYear, education.EndYear))
+		}
+		if got := strings.Join(records, "; "); got != test.want {
+			t.Errorf("ParseLegacyEducation(%q) = %q, want %q", test.text, got, test.want)
+		}
+		if complete != test.complete {
+			t.Errorf("ParseLegacyEducation(%q) complete = %v, want %v", test.text, complete, test.complete)
+		}
+	}
+}
+
+func TestParseLegacyPreviousNames(t *testing.T) {
+	person := models.Person{Surname: "Петрова", FirstName: "Анна", Patronymic: "Сергеевна"}
+	tests := []struct {
+		previous string
+		want     string
+	}{
+		{
+			"Анна - Иванова 2010 Свидетельство о браке, Замужество",
+			"Иванова Анна Сергеевна|2010|Свидетельство о браке|Замужество",
+		},
+		{
+			"Анна - Иванова 2010 Свидетельство о браке, ЗамужествоАнна - Сидорова 2015 Свидетельство о браке, Замужество",
+			"Иванова Анна Сергеевна|2010|Свидетельство о браке|Замужество; " +
+				"Сидорова Анна Сергеевна|2015|Свидетельство о браке|Замужество",
+		},
+		{
+			" - Иванова 2010 Свидетельство, ",
+			"Иванова Анна Сергеевна|2010|Свидетельство|",
+		},
+		{
+			"иванова анна сергеевна",
+			"Иванова Анна Сергеевна|0||",
+		},
+		{"  ", ""},
+	}
+	for _, test := range tests {
+		person.PreviousFullName = test.previous
+		records := []string{}
+		for _, change := range ParseLegacyPreviousNames(person) {
+			records = append(records, fmt.Sprintf("%s|%d|%s|%s",
+				change.FullName, change.Year, change.Document, change.Reason))
+		}
+		if got := strings.Join(records, "; "); got != test.want {
+			t.Errorf("ParseLegacyPreviousNames(%q) = %q, want %q", test.previous, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// NormalizeNamePart trims a name component and capitalizes every word of it,
+// including both parts of hyphenated names.
+func NormalizeNamePart(part string) string {
+	words := strings.Fields(part)
+	for i, word := range words {
+		pieces := strings.Split(word, "-")
+		for j, piece := range pieces {
+			runes := []rune(strings.ToLower(piece))
+			if len(runes) > 0 {
+				runes[0] = unicode.ToUpper(runes[0])
+			}
+			pieces[j] = string(runes)
+		}
+		words[i] = strings.Join(pieces, "-")
+	}
+	return strings.Join(words, " ")
+}
+
+// SplitFullName splits a full name into surname, given name and patronymic.
+// Words after the third one, like "оглы" or "кызы", stay in the patronymic.
+func SplitFullName(fullname string) (string, string, string) {
+	words := strings.Fields(fullname)
+	var surname, firstname, patronymic string
+	if len(words) > 0 {
+		surname = words[0]
+	}
+	if len(words) > 1 {
+		firstname = words[1]
+	}
+	if len(words) > 2 {
+		patronymic = strings.Join(words[2:], " ")
+	}
+	return NormalizeNamePart(surname), NormalizeNamePart(firstname), NormalizeNamePart(patronymic)
+}
+
+// CanonicalName returns the display form "Фамилия Имя Отчество".
+func CanonicalName(surname string, firstname string, patronymic string) string {
+	parts := []string{}
+	for _, part := range []string{surname, firstname, patronymic} {
+		if part = NormalizeNamePart(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// SearchKey returns the form of a name used for search and duplicate matching.
+func SearchKey(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	return strings.ReplaceAll(name, "ё", "е")
+}
+
+// NormalizePersonName fills the name components, display and search forms of
+// the person from whichever of them is set.
+func NormalizePersonName(person *models.Person) {
+	if person.Surname == "" && person.FirstName == "" && person.Patronymic == "" {
+		person.Surname, person.FirstName, person.Patronymic = SplitFullName(person.FullName)
+	}
+	person.Surname = NormalizeNamePart(person.Surname)
+	person.FirstName = NormalizeNamePart(person.FirstName)
+	person.Patronymic = NormalizeNamePart(person.Patronymic)
+	person.FullName = CanonicalName(person.Surname, person.FirstName, person.Patronymic)
+	person.SearchName = SearchKey(person.FullName)
+}
+
+// NormalizeNameChange fills the display and search forms of a previous name.
+func NormalizeNameChange(change *models.NameChange) {
+	change.Surname = NormalizeNamePart(change.Surname)
+	change.FirstName = NormalizeNamePart(change.FirstName)
+	change.Patronymic = NormalizeNamePart(change.Patronymic)
+	change.FullName = CanonicalName(change.Surname, change.FirstName, change.Patronymic)
+	change.SearchName = SearchKey(change.FullName)
+}
+
+// PreviousNameQuery selects the ids of persons with a previous name matching
+// the search.
+func PreviousNameQuery(db *gorm.DB, search string) *gorm.DB {
+	return db.
+		Model(&models.NameChange{}).
+		Select("person_id").
+		Where("search_name LIKE ?", "%"+SearchKey(search)+"%")
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"backend/app/models"
+)
+
+func TestNormalizePersonName(t *testing.T) {
+	tests := []struct {
+		person     models.Person
+		surname    string
+		firstname  string
+		patronymic string
+		fullname   string
+		search     string
+	}{
+		{
+			models.Person{FullName: "  иванов   иван  иванович "},
+			"Иванов", "Иван", "Иванович", "Иванов Иван Иванович", "иванов иван иванович",
+		},
+		{
+			models.Person{FullName: "РИМСКИЙ-КОРСАКОВ НИКОЛАЙ АНДРЕЕВИЧ"},
+			"Римский-Корсаков", "Николай", "Андреевич", "Римский-Корсаков Николай Андреевич", "римский-корсаков николай андреевич",
+		},
+		{
+			models.Person{FullName: "Алиев Гейдар Ализа оглы"},
+			"Алиев", "Гейдар", "Ализа Оглы", "Алиев Гейдар Ализа Оглы", "алиев гейдар ализа оглы",
+		},
+		{
+			models.Person{FullName: "Ёлкина Алёна"},
+			"Ёлкина", "Алёна", "", "Ёлкина Алёна", "елкина алена",
+		},
+		{
+			models.Person{FullName: "Старое Имя", Surname: "петров", FirstName: "пётр"},
+			"Петров", "Пётр", "", "Петров Пётр", "петров петр",
+		},
+		{
+			models.Person{},
+			"", "", "", "", "",
+		},
+	}
+	for _, test := range tests {
+		person := test.person
+		NormalizePersonName(&person)
+		if person.Surname != test.surname || person.FirstName != test.firstname || person.Patronymic != test.patronymic {
+			t.Errorf("NormalizePersonName(%q) parts = %q %q %q, want %q %q %q", test.person.FullName,
+				person.Surname, person.FirstName, person.Patronymic, test.surname, test.firstname, test.patronymic)
+		}
+		if person.FullName != test.fullname || person.SearchName != test.search {
+			t.Errorf("NormalizePersonName(%q) = %q, %q, want %q, %q", test.person.FullName,
+				person.FullName, person.SearchName, test.fullname, test.search)
+		}
+	}
+}
+
+func TestValidateNameChange(t *testing.T) {
+	change := models.NameChange{Surname: " сидорова ", FirstName: "алёна", Year: 2010}
+	if errs := ValidateNameChange(&change); len(errs) != 0 {
+		t.Errorf("ValidateNameChange() = %v, want no errors", errs)
+	}
+	if change.FullName != "Сидорова Алёна" || change.SearchName != "сидорова алена" {
+		t.Errorf("ValidateNameChange() = %q, %q, want the name normalized", change.FullName, change.SearchName)
+	}
+
+	errs := ValidateNameChange(&models.NameChange{Surname: " ", Year: 1850})
+	if errs["fullname"] == "" || errs["year"] == "" {
+		t.Errorf("ValidateNameChange() = %v, want errors for the name and the year", errs)
+	}
+}
+
+func TestPreviousNameQuery(t *testing.T) {
+	db, fake := newFakeDb(t)
+	var ids []uint
+	db.Model(&models.Person{}).Where("id IN (?)", PreviousNameQuery(db, " Сидорова  Алёна")).Pluck("id", &ids)
+
+	query := fake.Statements(`FROM "people"`)[0]
+	if !strings.Contains(query.SQL, `id IN (SELECT "person_id" FROM "name_changes" WHERE search_name LIKE $1)`) {
+		t.Errorf("query %q does not search the previous names", query.SQL)
+	}
+	if query.Args[0] != "%сидорова алена%" {
+		t.Errorf("query args = %v, want the search key", query.Args)
+	}
+}
@@ -0,0 +1,521 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PDF reports are plain text laid out on A4 pages. Cyrillic text needs an
+// embedded font, so the TrueType font of REPORT_FONT is embedded as a subset
+// with the glyphs used in the report.
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 56.0
+	pdfFontSize   = 11.0
+	pdfLeading    = 15.0
+)
+
+// ReportFont returns the path of the TrueType font used in PDF reports.
+func ReportFont() string {
+	if font := os.Getenv("REPORT_FONT"); font != "" {
+		return font
+	}
+	return "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+}
+
+// NewPdf lays out the paragraphs on A4 pages with the TrueType font.
+func NewPdf(paragraphs []string, fontPath string) ([]byte, error) {
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, err
+	}
+	font, err := parseTrueType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	maxWidth := pdfPageWidth - 2*pdfMargin
+	for _, paragraph := range paragraphs {
+		for _, line := range strings.Split(paragraph, "\n") {
+			lines = append(lines, font.wrap(strings.ReplaceAll(line, "\t", "    "), maxWidth)...)
+		}
+	}
+	perPage := int(pdfPageHeight-2*pdfMargin) / int(pdfLeading)
+	pages := [][]string{}
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	pages = append(pages, lines)
+
+	used := map[uint16]rune{0: 0}
+	contents := []string{}
+	for _, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %.0f Tf %.0f TL %.0f %.0f Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+		for _, line := range page {
+			content.WriteString("<")
+			for _, r := range line {
+				glyph := font.glyph(r)
+				if glyph != 0 {
+					used[glyph] = r
+				}
+				fmt.Fprintf(&content, "%04X", glyph)
+			}
+			content.WriteString("> Tj T*\n")
+		}
+		content.WriteString("ET")
+		contents = append(contents, content.String())
+	}
+
+	subset, err := font.subset(used)
+	if err != nil {
+		return nil, err
+	}
+	return font.writePdf(contents, used, subset)
+}
+
+type trueType struct {
+	tables      map[string][]byte
+	unitsPerEm  float64
+	numGlyphs   int
+	longLoca    bool
+	advances    []uint16
+	cmap        map[rune]uint16
+	ascent      int16
+	descent     int16
+	boundingBox [4]int16
+}
+
+func parseTrueType(data []byte) (*trueType, error) {
+	if len(data) < 12 {
+		return nil, errors.New("invalid font file")
+	}
+	font := &trueType{tables: map[string][]byte{}, cmap: map[rune]uint16{}}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errors.New("invalid font file")
+		}
+		tag := string(data[record : record+4])
+		offset := uint64(binary.BigEndian.Uint32(data[record+8:]))
+		length := uint64(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > uint64(len(data)) {
+			return nil, errors.New("invalid font file")
+		}
+		font.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := font.tables[tag]; !ok {
+			return nil, fmt.Errorf("the font has no %s table, only TrueType fonts are supported", tag)
+		}
+	}
+	// Minimal lengths of the fixed parts read below.
+	for tag, length := range map[string]int{"head": 54, "hhea": 36, "maxp": 6, "cmap": 4} {
+		if len(font.tables[tag]) < length {
+			return nil, fmt.Errorf("invalid %s table", tag)
+		}
+	}
+
+	head := font.tables["head"]
+	font.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if font.unitsPerEm == 0 {
+		return nil, errors.New("invalid head table")
+	}
+	for i := range font.boundingBox {
+		font.boundingBox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+	font.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+	font.numGlyphs = int(binary.BigEndian.Uint16(font.tables["maxp"][4:]))
+	locaSize := 2
+	if font.longLoca {
+		locaSize = 4
+	}
+	if font.numGlyphs == 0 || len(font.tables["loca"]) < locaSize*(font.numGlyphs+1) {
+		return nil, errors.New("invalid loca table")
+	}
+
+	hhea := font.tables["hhea"]
+	font.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	font.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := font.tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errors.New("invalid hmtx table")
+	}
+	font.advances = make([]uint16, font.numGlyphs)
+	for i := 0; i < font.numGlyphs; i++ {
+		if i < metrics && 4*i+2 <= len(hmtx) {
+			font.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+		} else if i > 0 {
+			font.advances[i] = font.advances[i-1]
+		}
+	}
+	return font, font.parseCmap()
+}
+
+// parseCmap reads the Unicode mapping of the font, format 4 for the basic
+// plane or format 12 for the full range.
+func (font *trueType) parseCmap() error {
+	cmap := font.tables["cmap"]
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count && 12+8*i <= len(cmap); i++ {
+		platform := binary.BigEndian.Uint16(cmap[4+8*i:])
+		encoding := binary.BigEndian.Uint16(cmap[6+8*i:])
+		offset := int(binary.BigEndian.Uint32(cmap[8+8*i:]))
+		if platform != 3 || (encoding != 1 && encoding != 10) || offset+16 > len(cmap) {
+			continue
+		}
+		table := cmap[offset:]
+		switch binary.BigEndian.Uint16(table) {
+		case 4:
+			segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+			if len(table) < 16+8*segments {
+				continue
+			}
+			ends, starts := table[14:], table[16+2*segments:]
+			deltas, ranges := table[16+4*segments:], table[16+6*segments:]
+			for s := 0; s < segments; s++ {
+				end := rune(binary.BigEndian.Uint16(ends[2*s:]))
+				start := rune(binary.BigEndian.Uint16(starts[2*s:]))
+				delta := binary.BigEndian.Uint16(deltas[2*s:])
+				rangeOffset := int(binary.BigEndian.Uint16(ranges[2*s:]))
+				for r := start; r <= end && r != 0xFFFF; r++ {
+					glyph := uint16(r) + delta
+					if rangeOffset != 0 {
+						index := 16 + 6*segments + 2*s + rangeOffset + 2*int(r-start)
+						if index+2 > len(table) {
+							continue
+						}
+						if glyph = binary.BigEndian.Uint16(table[index:]); glyph != 0 {
+							glyph += delta
+						}
+					}
+					if glyph != 0 {
+						font.cmap[r] = glyph
+					}
+				}
+			}
+		case 12:
+			groups := int(binary.BigEndian.Uint32(table[12:]))
+			if groups < 0 || groups > (len(table)-16)/12 {
+				continue
+			}
+			for g := 0; g < groups; g++ {
+				group := table[16+12*g:]
+				start := rune(binary.BigEndian.Uint32(group))
+				end := rune(binary.BigEndian.Uint32(group[4:]))
+				glyph := binary.BigEndian.Uint32(group[8:])
+				if start < 0 || end > unicode.MaxRune {
+					continue
+				}
+				for r := start; r <= end; r++ {
+					font.cmap[r] = uint16(glyph + uint32(r-start))
+				}
+			}
+		}
+	}
+	if len(font.cmap) == 0 {
+		return errors.New("the font has no Unicode character map")
+	}
+	return nil
+}
+
+// glyph returns the glyph of the character, the missing glyph 0 for
+// characters the font lacks or maps outside of its glyphs.
+func (font *trueType) glyph(r rune) uint16 {
+	if glyph := font.cmap[r]; int(glyph) < font.numGlyphs {
+		return glyph
+	}
+	return 0
+}
+
+func (font *trueType) width(text string) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += float64(font.advances[font.glyph(r)])
+	}
+	return width * pdfFontSize / font.unitsPerEm
+}
+
+// wrap splits the line at spaces to fit the width, long words are split too.
+func (font *trueType) wrap(line string, maxWidth float64) []string {
+	lines := []string{}
+	current := ""
+	for _, word := range strings.Split(line, " ") {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if font.width(candidate) <= maxWidth {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		current = ""
+		for _, r := range word {
+			if font.width(current+string(r)) > maxWidth && current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			current += string(r)
+		}
+	}
+	return append(lines, current)
+}
+
+func (font *trueType) glyphData(glyph uint16) []byte {
+	loca, glyf := font.tables["loca"], font.tables["glyf"]
+	var start, end uint32
+	if font.longLoca {
+		start = binary.BigEndian.Uint32(loca[4*int(glyph):])
+		end = binary.BigEndian.Uint32(loca[4*int(glyph)+4:])
+	} else {
+		start = 2 * uint32(binary.BigEndian.Uint16(loca[2*int(glyph):]))
+		end = 2 * uint32(binary.BigEndian.Uint16(loca[2*int(glyph)+2:]))
+	}
+	if start >= end || int(end) > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// subset builds a font file keeping the glyph ids, glyphs not used in the
+// report and not referenced by used composite glyphs are left empty.
+func (font *trueType) subset(used map[uint16]rune) ([]byte, error) {
+	keep := map[uint16]bool{}
+	pending := []uint16{}
+	for glyph := range used {
+		pending = append(pending, glyph)
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if keep[glyph] || int(glyph) >= font.numGlyphs {
+			continue
+		}
+		keep[glyph] = true
+		data := font.glyphData(glyph)
+		if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+			continue
+		}
+		// Composite glyph: add its components.
+		for offset := 10; offset+4 <= len(data); {
+			flags := binary.BigEndian.Uint16(data[offset:])
+			pending = append(pending, binary.BigEndian.Uint16(data[offset+2:]))
+			offset += 4
+			if flags&0x0001 != 0 {
+				offset += 4
+			} else {
+				offset += 2
+			}
+			switch {
+			case flags&0x0008 != 0:
+				offset += 2
+			case flags&0x0040 != 0:
+				offset += 4
+			case flags&0x0080 != 0:
+				offset += 8
+			}
+			if flags&0x0020 == 0 {
+				break
+			}
+		}
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*(font.numGlyphs+1))
+	for glyph := 0; glyph < font.numGlyphs; glyph++ {
+		binary.BigEndian.PutUint32(loca[4*glyph:], uint32(glyf.Len()))
+		if keep[uint16(glyph)] {
+			glyf.Write(font.glyphData(uint16(glyph)))
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*font.numGlyphs:], uint32(glyf.Len()))
+
+	head := append([]byte{}, font.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{"head": head, "glyf": glyf.Bytes(), "loca": loca}
+	for _, tag := range []string{"cmap", "hhea", "hmtx", "maxp", "cvt ", "fpgm", "prep"} {
+		if table, ok := font.tables[tag]; ok {
+			tables[tag] = table
+		}
+	}
+	return writeTrueType(tables), nil
+}
+
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := []string{}
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	count := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= count {
+		searchRange *= 2
+		entrySelector++
+	}
+	header := make([]byte, 12+16*count)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(count))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(count*16-searchRange*16))
+
+	var body bytes.Buffer
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], trueTypeChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(header)+body.Len()))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		body.Write(table)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	return append(header, body.Bytes()...)
+}
+
+func trueTypeChecksum(table []byte) uint32 {
+	var sum uint32
+	padded := append(append([]byte{}, table...), 0, 0, 0)
+	for i := 0; i+4 <= len(padded); i += 4 {
+		sum += binary.BigEndian.Uint32(padded[i:])
+	}
+	return sum
+}
+
+// writePdf writes the document with the page contents and the embedded font.
+func (font *trueType) writePdf(contents []string, used map[uint16]rune, fontFile []byte) ([]byte, error) {
+	scale := 1000 / font.unitsPerEm
+	glyphs := []int{}
+	for glyph := range used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	var widths, toUnicode strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%.0f] ", glyph, float64(font.advances[glyph])*scale)
+	}
+	toUnicode.WriteString("/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def /CMapType 2 def\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n")
+	mapped := []int{}
+	for _, glyph := range glyphs {
+		if used[uint16(glyph)] != 0 {
+			mapped = append(mapped, glyph)
+		}
+	}
+	for len(mapped) > 0 {
+		block := mapped
+		if len(block) > 100 {
+			block = block[:100]
+		}
+		mapped = mapped[len(block):]
+		fmt.Fprintf(&toUnicode, "%d beginbfchar\n", len(block))
+		for _, glyph := range block {
+			fmt.Fprintf(&toUnicode, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{used[uint16(glyph)]}) {
+				fmt.Fprintf(&toUnicode, "%04X", unit)
+			}
+			toUnicode.WriteString(">\n")
+		}
+		toUnicode.WriteString("endbfchar\n")
+	}
+	toUnicode.WriteString("endcmap CMapName currentdict /CMap defineresource pop end end")
+
+	// Objects: 1 catalog, 2 pages, 3 font, 4 CID font, 5 descriptor,
+	// 6 font file, 7 ToUnicode, then a page and its content for every page.
+	objects := []string{}
+	kids := []string{}
+	for i := range contents {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 8+2*i))
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(contents)),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /AAAAAA+ReportFont /Encoding /Identity-H "+
+			"/DescendantFonts [4 0 R] /ToUnicode 7 0 R >>",
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /AAAAAA+ReportFont "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor 5 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", widths.String()),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /AAAAAA+ReportFont /Flags 32 "+
+			"/FontBBox [%.0f %.0f %.0f %.0f] /ItalicAngle 0 /Ascent %.0f /Descent %.0f "+
+			"/CapHeight %.0f /StemV 80 /FontFile2 6 0 R >>",
+			float64(font.boundingBox[0])*scale, float64(font.boundingBox[1])*scale,
+			float64(font.boundingBox[2])*scale, float64(font.boundingBox[3])*scale,
+			float64(font.ascent)*scale, float64(font.descent)*scale, float64(font.ascent)*scale),
+	)
+	objects = append(objects, "", "")
+	streams := map[int][]byte{6: fontFile, 7: []byte(toUnicode.String())}
+	for i, content := range contents {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+				"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 9+2*i),
+			"",
+		)
+		streams[9+2*i] = []byte(content)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := []int{}
+	for i, object := range objects {
+		number := i + 1
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", number)
+		if stream, ok := streams[number]; ok {
+			var compressed bytes.Buffer
+			writer := zlib.NewWriter(&compressed)
+			if _, err := writer.Write(stream); err != nil {
+				return nil, err
+			}
+			if err := writer.Close(); err != nil {
+				return nil, err
+			}
+			extra := ""
+			if number == 6 {
+				extra = fmt.Sprintf(" /Length1 %d", len(stream))
+			}
+			fmt.Fprintf(&out, "<< /Length %d /Filter /FlateDecode%s >>\nstream\n", compressed.Len(), extra)
+			out.Write(compressed.Bytes())
+			out.WriteString("\nendstream")
+		} else {
+			out.WriteString(object)
+		}
+		out.WriteString("\nendobj\n")
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes(), nil
+}
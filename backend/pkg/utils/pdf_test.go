@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func reportFontData(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(ReportFont())
+	if err != nil {
+		t.Skipf("report font is not available: %v", err)
+	}
+	return data
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	reportFontData(t)
+
+	source, err := NewDocx([]string{"Кандидат: {{fullname}}", "Заключение: {{conclusion}}", "{{comments}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docx, err := FillDocx(source, map[string]string{
+		"fullname":   "Бендер Остап Сулеман",
+		"conclusion": "согласовано",
+		"comments":   "первая строка\nвторая строка",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paragraphs, err := DocxParagraphs(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Кандидат: Бендер Остап Сулеман", "Заключение: согласовано", "первая строка\nвторая строка"}
+	if strings.Join(paragraphs, "|") != strings.Join(want, "|") {
+		t.Fatalf("DocxParagraphs() = %q, want %q", paragraphs, want)
+	}
+
+	pdf, err := NewPdf(paragraphs, ReportFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte("%%EOF")) {
+		t.Fatalf("NewPdf() did not return a PDF document")
+	}
+	if !bytes.Contains(pdf, []byte("/FontFile2")) {
+		t.Errorf("NewPdf() did not embed the font")
+	}
+}
+
+func TestParseTrueTypeMalformed(t *testing.T) {
+	data := reportFontData(t)
+	font, err := parseTrueType(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shortTables := func(tag string, length int) []byte {
+		tables := map[string][]byte{}
+		for name, table := range font.tables {
+			tables[name] = table
+		}
+		tables[tag] = tables[tag][:length]
+		return writeTrueType(tables)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"header only", data[:12]},
+		{"truncated directory", data[:40]},
+		{"truncated tables", data[:len(data)/2]},
+		{"short head", shortTables("head", 20)},
+		{"short hhea", shortTables("hhea", 10)},
+		{"short maxp", shortTables("maxp", 4)},
+		{"short hmtx", shortTables("hmtx", 2)},
+		{"short loca", shortTables("loca", 8)},
+		{"short cmap", shortTables("cmap", 2)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseTrueType(test.data); err == nil {
+				t.Errorf("parseTrueType() returned no error")
+			}
+		})
+	}
+}
+
+func TestNewPdfBadFont(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	if err := os.WriteFile(path, []byte("not a font file at all"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPdf([]string{"текст"}, path); err == nil {
+		t.Errorf("NewPdf() returned no error for a bad font")
+	}
+}
+
+// pdfStreams returns the inflated streams of the document in order.
+func pdfStreams(t *testing.T, pdf []byte) [][]byte {
+	t.Helper()
+	streams := [][]byte{}
+	for {
+		start := bytes.Index(pdf, []byte(">>\nstream\n"))
+		if start < 0 {
+			return streams
+		}
+		pdf = pdf[start+len(">>\nstream\n"):]
+		end := bytes.Index(pdf, []byte("\nendstream"))
+		reader, err := zlib.NewReader(bytes.NewReader(pdf[:end]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, stream)
+		pdf = pdf[end:]
+	}
+}
+
+// glyphComponents returns the components of a composite glyph.
+func glyphComponents(data []byte) []uint16 {
+	components := []uint16{}
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return components
+	}
+	for offset := 10; offset+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[offset:])
+		components = append(components, binary.BigEndian.Uint16(data[offset+2:]))
+		offset += 6
+		if flags&0x0001 != 0 {
+			offset += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			offset += 2
+		case flags&0x0040 != 0:
+			offset += 4
+		case flags&0x0080 != 0:
+			offset += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return components
+}
+
+func TestSubsetCompositeGlyphs(t *testing.T) {
+	font, err := parseTrueType(reportFontData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	used := map[uint16]rune{0: 0}
+	composites := []uint16{}
+	for _, r := range "йЙёЁéÅ" {
+		glyph := font.glyph(r)
+		if len(glyphComponents(font.glyphData(glyph))) > 0 {
+			used[glyph] = r
+			composites = append(composites, glyph)
+		}
+	}
+	if len(composites) == 0 {
+		t.Skip("the report font has no composite glyphs for the test characters")
+	}
+	unused := font.glyph('Z')
+
+	data, err := font.subset(used)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subset, err := parseTrueType(data)
+	if err != nil {
+		t.Fatalf("parseTrueType(subset) error = %v", err)
+	}
+	if subset.numGlyphs != font.numGlyphs {
+		t.Errorf("subset has %d glyphs, want the %d of the font", subset.numGlyphs, font.numGlyphs)
+	}
+	for _, glyph := range composites {
+		if !bytes.Equal(subset.glyphData(glyph), font.glyphData(glyph)) {
+			t.Errorf("composite glyph %d was not kept", glyph)
+		}
+		for _, component := range glyphComponents(font.glyphData(glyph)) {
+			if !bytes.Equal(subset.glyphData(component), font.glyphData(component)) {
+				t.Errorf("component %d of glyph %d was not kept", component, glyph)
+			}
+		}
+	}
+	if subset.glyphData(unused) != nil {
+		t.Errorf("unused glyph %d was kept", unused)
+	}
+}
+
+func TestNewPdfMissingGlyphs(t *testing.T) {
+	font, err := parseTrueType(reportFontData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const missing = '\U0010FFFD'
+	if glyph := font.glyph(missing); glyph != 0 {
+		t.Fatalf("glyph(%U) = %d, want the missing glyph 0", missing, glyph)
+	}
+
+	pdf, err := NewPdf([]string{"a" + string(missing) + "b"}, ReportFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := pdfStreams(t, pdf)
+	if len(streams) != 3 {
+		t.Fatalf("NewPdf() wrote %d streams, want the font, ToUnicode and one page", len(streams))
+	}
+	content := string(streams[2])
+	want := fmt.Sprintf("<%04X0000%04X> Tj", font.glyph('a'), font.glyph('b'))
+	if !strings.Contains(content, want) {
+		t.Errorf("page content %q does not contain %q", content, want)
+	}
+	if strings.Contains(string(streams[1]), "\n<0000> <") {
+		t.Errorf("ToUnicode maps the missing glyph: %q", streams[1])
+	}
+}
+
+func TestNewPdfPages(t *testing.T) {
+	reportFontData(t)
+
+	perPage := int(pdfPageHeight-2*pdfMargin) / int(pdfLeading)
+	tests := []struct {
+		lines int
+		pages int
+	}{
+		{0, 1},
+		{1, 1},
+		{perPage, 1},
+		{perPage + 1, 2},
+		{2*perPage + 5, 3},
+	}
+	for _, test := range tests {
+		paragraphs := []string{}
+		for i := 0; i < test.lines; i++ {
+			paragraphs = append(paragraphs, fmt.Sprintf("Строка %d", i+1))
+		}
+		pdf, err := NewPdf(paragraphs, ReportFont())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d", test.pages))) {
+			t.Errorf("%d lines: page tree does not count %d pages", test.lines, test.pages)
+		}
+		if got := bytes.Count(pdf, []byte("/Type /Page ")); got != test.pages {
+			t.Errorf("%d lines: %d page objects, want %d", test.lines, got, test.pages)
+		}
+		streams := pdfStreams(t, pdf)
+		lines := 0
+		for _, content := range streams[2:] {
+			lines += bytes.Count(content, []byte("Tj T*"))
+		}
+		if lines != test.lines {
+			t.Errorf("%d lines: pages show %d lines", test.lines, lines)
+		}
+	}
+}
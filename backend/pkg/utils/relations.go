@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// RelationKinds maps a kind of relation to the kind of its counterpart.
+var RelationKinds map[string]string = map[string]string{
+	"spouse":    "spouse",
+	"parent":    "child",
+	"child":     "parent",
+	"sibling":   "sibling",
+	"colleague": "colleague",
+}
+
+// RelationLabels are the default views of the relation kinds.
+var RelationLabels map[string]string = map[string]string{
+	"spouse":    "Супруг(а)",
+	"parent":    "Родитель",
+	"child":     "Ребенок",
+	"sibling":   "Брат/сестра",
+	"colleague": "Коллега",
+}
+
+// MaxGraphDepth limits the number of hops of the relation graph.
+const MaxGraphDepth = 5
+
+type GraphNode struct {
+	ID         uint        `json:"id"`
+	FullName   string      `json:"fullname"`
+	BirthDate  models.Date `json:"birthday"`
+	Status     string      `json:"status"`
+	Conclusion string      `json:"conclusion"`
+	Depth      int         `json:"depth"`
+}
+
+type GraphEdge struct {
+	From uint   `json:"from"`
+	To   uint   `json:"to"`
+	Kind string `json:"kind"`
+}
+
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// ValidateRelation checks the kind and the related person of a relation.
+func ValidateRelation(db *gorm.DB, relation *models.Relation) FieldErrors {
+	errs := FieldErrors{}
+	if _, ok := RelationKinds[relation.Kind]; !ok {
+		errs["kind"] = "неизвестный вид связи"
+	}
+	if relation.RelatedID == 0 {
+		errs["related_id"] = "не указано связанное лицо"
+	} else if relation.RelatedID == relation.PersonID {
+		errs["related_id"] = "лицо не может быть связано с собой"
+	} else {
+		var related models.Person
+		db.First(&related, relation.RelatedID)
+		if related.ID == 0 {
+			errs["related_id"] = "связанное лицо не найдено"
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	var count int64
+	db.
+		Model(&models.Relation{}).
+		Where("person_id = ? AND related_id = ? AND kind = ?", relation.PersonID, relation.RelatedID, relation.Kind).
+		Where("id <> ?", relation.ID).
+		Count(&count)
+	if count > 0 {
+		errs["related_id"] = "связь уже добавлена"
+	}
+	if relation.View == "" {
+		relation.View = RelationLabels[relation.Kind]
+	}
+	return errs
+}
+
+// PairRelation creates or updates the counterpart of the relation on the
+// related person, so the relation is seen from both sides.
+func PairRelation(db *gorm.DB, relation *models.Relation, user *middlewares.TokenMetadata) error {
+	var pair models.Relation
+	if relation.PairID != 0 {
+		db.First(&pair, relation.PairID)
+	}
+	before := pair
+	pair.PersonID = relation.RelatedID
+	pair.RelatedID = relation.PersonID
+	pair.Kind = RelationKinds[relation.Kind]
+	pair.View = RelationLabels[pair.Kind]
+	pair.PairID = relation.ID
+
+	if pair.ID == 0 {
+		if err := db.Create(&pair).Error; err != nil {
+			return err
+		}
+		relation.PairID = pair.ID
+		if err := db.Model(relation).UpdateColumn("pair_id", pair.ID).Error; err != nil {
+			return err
+		}
+		return RecordVersion(db, "relation", nil, &pair, user)
+	}
+	if err := db.Save(&pair).Error; err != nil {
+		return err
+	}
+	return RecordVersion(db, "relation", &before, &pair, user)
+}
+
+// UnpairRelation deletes the counterpart of the deleted relation.
+func UnpairRelation(db *gorm.DB, relation *models.Relation, user *middlewares.TokenMetadata) error {
+	if relation.PairID == 0 {
+		return nil
+	}
+	var pair models.Relation
+	db.First(&pair, relation.PairID)
+	if pair.ID == 0 {
+		return nil
+	}
+	if err := db.Delete(&pair).Error; err != nil {
+		return err
+	}
+	return RecordVersion(db, "relation", &pair, nil, user)
+}
+
+// RelationGraph walks the relations of the person up to depth hops and
+// returns the connected persons with their statuses and last conclusions.
+// Trashed persons and their relations are left out.
+func RelationGraph(db *gorm.DB, personID uint, depth int) Graph {
+	graph := Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	depths := map[uint]int{}
+	persons := map[uint]models.Person{}
+	edges := map[GraphEdge]bool{}
+
+	frontier := []uint{personID}
+	for level := 0; len(frontier) > 0; level++ {
+		var found []models.Person
+		db.Where("id IN ?", frontier).Find(&found)
+		frontier = []uint{}
+		for _, person := range found {
+			depths[person.ID] = level
+			persons[person.ID] = person
+			frontier = append(frontier, person.ID)
+		}
+		if level == depth || len(frontier) == 0 {
+			break
+		}
+
+		var relations []models.Relation
+		db.
+			Where("related_id <> 0").
+			Where("person_id IN ? OR related_id IN ?", frontier, frontier).
+			Find(&relations)
+		next := map[uint]bool{}
+		for _, relation := range relations {
+			edge := GraphEdge{From: relation.PersonID, To: relation.RelatedID, Kind: relation.Kind}
+			if edge.From > edge.To {
+				edge = GraphEdge{From: edge.To, To: edge.From, Kind: RelationKinds[edge.Kind]}
+			}
+			edges[edge] = true
+			for _, id := range []uint{relation.PersonID, relation.RelatedID} {
+				if _, ok := depths[id]; !ok {
+					next[id] = true
+				}
+			}
+		}
+		frontier = []uint{}
+		for id := range next {
+			frontier = append(frontier, id)
+		}
+	}
+
+	ids := []uint{}
+	for id := range persons {
+		ids = append(ids, id)
+	}
+	statuses := map[uint]string{}
+	var statusList []models.Status
+	db.Find(&statusList)
+	for _, status := range statusList {
+		statuses[status.ID] = status.NameStatus
+	}
+	conclusions := map[uint]string{}
+	var conclusionList []models.Conclusion
+	db.Find(&conclusionList)
+	for _, conclusion := range conclusionList {
+		conclusions[conclusion.ID] = conclusion.Conclusion
+	}
+	lastConclusion := map[uint]string{}
+	var checks []models.Check
+	db.
+		Select("id, person_id, conclusion_id").
+		Where("person_id IN ? AND conclusion_id <> 0", ids).
+		Order("id").
+		Find(&checks)
+	for _, check := range checks {
+		lastConclusion[check.PersonID] = conclusions[check.ConclusionID]
+	}
+
+	for _, id := range ids {
+		person := persons[id]
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:         person.ID,
+			FullName:   person.FullName,
+			BirthDate:  person.BirthDate,
+			Status:     statuses[person.StatusID],
+			Conclusion: lastConclusion[person.ID],
+			Depth:      depths[person.ID],
+		})
+	}
+	for edge := range edges {
+		_, from := persons[edge.From]
+		_, to := persons[edge.To]
+		if from && to {
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Depth != graph.Nodes[j].Depth {
+			return graph.Nodes[i].Depth < graph.Nodes[j].Depth
+		}
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph
+}
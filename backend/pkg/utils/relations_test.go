@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"backend/app/models"
+)
+
+func TestValidateRelation(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(personQuery, []string{"id"}, []driver.Value{int64(3)})
+
+	relation := models.Relation{PersonID: 1, RelatedID: 3, Kind: "parent"}
+	if errs := ValidateRelation(db, &relation); len(errs) != 0 {
+		t.Fatalf("ValidateRelation() = %v, want no errors", errs)
+	}
+	if relation.View != "Родитель" {
+		t.Errorf("ValidateRelation() view = %q, want the label of the kind", relation.View)
+	}
+
+	tests := []struct {
+		relation models.Relation
+		field    string
+	}{
+		{models.Relation{PersonID: 1, RelatedID: 3, Kind: "friend"}, "kind"},
+		{models.Relation{PersonID: 1, Kind: "spouse"}, "related_id"},
+		{models.Relation{PersonID: 1, RelatedID: 1, Kind: "spouse"}, "related_id"},
+	}
+	for _, test := range tests {
+		if errs := ValidateRelation(db, &test.relation); errs[test.field] == "" {
+			t.Errorf("ValidateRelation(%+v) = %v, want an error for %s", test.relation, errs, test.field)
+		}
+	}
+
+	fake.Answer(`SELECT count\(\*\) FROM "relations"`, []string{"count"}, []driver.Value{int64(1)})
+	if errs := ValidateRelation(db, &models.Relation{ID: 2, PersonID: 1, RelatedID: 3, Kind: "parent"}); errs["related_id"] == "" {
+		t.Errorf("ValidateRelation() = %v, want an error for a duplicate", errs)
+	}
+	if count := fake.Statements(`FROM "relations"`); count[len(count)-1].Args[3] != int64(2) {
+		t.Errorf("duplicate query args = %v, want the relation itself left out", count[len(count)-1].Args)
+	}
+}
+
+func TestPairRelation(t *testing.T) {
+	db, fake := newFakeDb(t)
+	relation := models.Relation{ID: 10, PersonID: 1, RelatedID: 3, Kind: "parent"}
+	if err := PairRelation(db, &relation, nil); err != nil {
+		t.Fatal(err)
+	}
+	inserts := fake.Statements(`^INSERT INTO "relations"`)
+	if len(inserts) != 1 {
+		t.Fatalf("PairRelation() created %d relations, want 1", len(inserts))
+	}
+	for column, want := range map[string]driver.Value{
+		"person_id": int64(3), "related_id": int64(1), "kind": "child", "view": "Ребенок", "pair_id": int64(10),
+	} {
+		if value, _ := inserts[0].Arg(column); value != want {
+			t.Errorf("pair %s = %v, want %v", column, value, want)
+		}
+	}
+	links := fake.Statements(`^UPDATE "relations" SET "pair_id"=\$1`)
+	if relation.PairID == 0 || len(links) != 1 || links[0].Args[0] != int64(relation.PairID) {
+		t.Errorf("PairRelation() did not link the relation to its pair %d", relation.PairID)
+	}
+
+	// A changed kind updates the existing counterpart.
+	fake.Answer(`FROM "relations" WHERE "relations"."id" = \$1`,
+		[]string{"id", "view", "kind", "related_id", "pair_id", "person_id"},
+		[]driver.Value{int64(11), "Ребенок", "child", int64(1), int64(10), int64(3)},
+	)
+	relation = models.Relation{ID: 10, PersonID: 1, RelatedID: 3, Kind: "sibling", PairID: 11}
+	if err := PairRelation(db, &relation, nil); err != nil {
+		t.Fatal(err)
+	}
+	saves := fake.Statements(`^UPDATE "relations" SET "view"`)
+	if len(fake.Statements(`^INSERT INTO "relations"`)) != 1 || len(saves) != 1 {
+		t.Fatalf("PairRelation() did not update the existing pair")
+	}
+	if kind, _ := saves[0].Arg("kind"); kind != "sibling" {
+		t.Errorf("pair kind = %v, want sibling", kind)
+	}
+}
+
+func TestRelationGraph(t *testing.T) {
+	db, fake := newFakeDb(t)
+	// Person 4 is in the trash.
+	fake.AnswerFunc(`FROM "people" WHERE id IN`, []string{"id", "full_name", "status_id"},
+		func(args []driver.Value) [][]driver.Value {
+			rows := [][]driver.Value{}
+			for _, id := range args {
+				if id != int64(4) {
+					rows = append(rows, []driver.Value{id, fmt.Sprintf("Лицо %d", id), int64(1)})
+				}
+			}
+			return rows
+		})
+	relations := [][]int64{{1, 2}, {3, 1}, {2, 4}, {3, 5}}
+	kinds := []string{"spouse", "child", "sibling", "colleague"}
+	fake.AnswerFunc(`FROM "relations"`, []string{"id", "kind", "related_id", "person_id"},
+		func(args []driver.Value) [][]driver.Value {
+			frontier := map[driver.Value]bool{}
+			for _, id := range args[:len(args)/2] {
+				frontier[id] = true
+			}
+			rows := [][]driver.Value{}
+			for i, relation := range relations {
+				if frontier[relation[0]] || frontier[relation[1]] {
+					rows = append(rows, []driver.Value{int64(i + 1), kinds[i], relation[1], relation[0]})
+				}
+			}
+			return rows
+		})
+	fake.Answer(`^SELECT \* FROM "statuses"$`, []string{"id", "name_status"}, []driver.Value{int64(1), "Новая"})
+	fake.Answer(`^SELECT \* FROM "conclusions"$`, []string{"id", "conclusion"},
+		[]driver.Value{int64(1), "Согласовано"},
+		[]driver.Value{int64(2), "Отказано"},
+	)
+	fake.Answer(`FROM "checks"`, []string{"id", "person_id", "conclusion_id"},
+		[]driver.Value{int64(1), int64(3), int64(1)},
+		[]driver.Value{int64(2), int64(3), int64(2)},
+	)
+
+	nodes := func(graph Graph) string {
+		result := ""
+		for _, node := range graph.Nodes {
+			result += fmt.Sprintf("%d:%d ", node.ID, node.Depth)
+		}
+		return result
+	}
+
+	near := RelationGraph(db, 1, 1)
+	if got := nodes(near); got != "1:0 2:1 3:1 " {
+		t.Errorf("RelationGraph(1) nodes = %s", got)
+	}
+	want := []GraphEdge{{1, 2, "spouse"}, {1, 3, "parent"}}
+	if !reflect.DeepEqual(near.Edges, want) {
+		t.Errorf("RelationGraph(1) edges = %v, want %v", near.Edges, want)
+	}
+
+	full := RelationGraph(db, 1, MaxGraphDepth)
+	if got := nodes(full); got != "1:0 2:1 3:1 5:2 " {
+		t.Errorf("RelationGraph(5) nodes = %s, want the trashed person left out", got)
+	}
+	want = append(want, GraphEdge{3, 5, "colleague"})
+	if !reflect.DeepEqual(full.Edges, want) {
+		t.Errorf("RelationGraph(5) edges = %v, want %v", full.Edges, want)
+	}
+	if node := full.Nodes[2]; node.Status != "Новая" || node.Conclusion != "Отказано" {
+		t.Errorf("node 3 = %+v, want the status and the last conclusion", node)
+	}
+}
@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// Check conclusion reports are filled from the person and the latest check,
+// robot, polygraph and staff records. Admins upload DOCX templates with
+// {{name}} placeholders, without a template the default text is used. Every
+// report is stored in the documents folder of the person.
+
+// Report formats.
+const (
+	ReportDocx = "docx"
+	ReportPdf  = "pdf"
+)
+
+// DefaultReportText is the report used when no template is uploaded.
+var DefaultReportText []string = []string{
+	"Заключение по результатам проверки",
+	"",
+	"Кандидат: {{fullname}}, {{birthday}} г.р.",
+	"Категория: {{category}}, регион: {{region}}",
+	"Должность: {{position}}, подразделение: {{department}}",
+	"",
+	"Проверка от {{check_date}}, исполнитель {{officer}}",
+	"Проверка места работы: {{workplace}}",
+	"Проверка по кадровому учету: {{employee}}",
+	"Проверка документов: {{document}}",
+	"Задолженности: {{debt}}",
+	"Банкротство: {{bankruptcy}}",
+	"Кредитная история: {{bki}}",
+	"Судебные дела: {{courts}}",
+	"Аффилированность: {{affiliation}}",
+	"Перечень террористов: {{terrorist}}",
+	"Розыск МВД: {{mvd}}",
+	"Открытые источники: {{internet}}",
+	"Кронос: {{cronos}}",
+	"Крос: {{cros}}",
+	"{{check_items}}",
+	"",
+	"Полиграф: {{poligraf_theme}}, {{poligraf_date}}",
+	"{{poligraf_results}}",
+	"",
+	"Заключение: {{conclusion}}",
+	"{{comments}}",
+	"",
+	"{{today}}, {{author}}",
+}
+
+// ReportTemplatesPath returns the folder with the uploaded report templates.
+func ReportTemplatesPath() string {
+	return filepath.Join(os.Getenv("BASE_PATH"), ".templates")
+}
+
+// ReportPlaceholders returns the names of the fields a template can use.
+func ReportPlaceholders() []string {
+	names := []string{}
+	for name := range reportFields(models.Person{}, models.Check{}, nil, models.Robot{}, models.Poligraf{}, models.Staff{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReportFields returns the fields of the report on the person and the latest
+// check with a final conclusion they come from. The fields of missing records
+// are empty.
+func ReportFields(db *gorm.DB, person models.Person) (map[string]string, models.Check) {
+	var robot models.Robot
+	var poligraf models.Poligraf
+	var staff models.Staff
+	check := concludedCheck(db, person.ID)
+	db.Where("person_id = ?", person.ID).Order("id DESC").Limit(1).Find(&robot)
+	db.Where("person_id = ?", person.ID).Order("id DESC").Limit(1).Find(&poligraf)
+	db.Where("person_id = ?", person.ID).Order("id DESC").Limit(1).Find(&staff)
+
+	var items []models.CheckItem
+	if check.ID != 0 {
+		items = CheckItems(db, []uint{check.ID})[check.ID]
+	}
+	fields := reportFields(person, check, items, robot, poligraf, staff)
+
+	var category models.Category
+	var region models.Region
+	var conclusion models.Conclusion
+	db.Limit(1).Find(&category, person.CategoryID)
+	db.Limit(1).Find(&region, person.RegionID)
+	if check.ConclusionID != 0 {
+		db.Limit(1).Find(&conclusion, check.ConclusionID)
+	}
+	fields["category"] = category.NameCategory
+	fields["region"] = region.NameRegion
+	fields["conclusion"] = conclusion.Conclusion
+
+	var changes []models.NameChange
+	db.Where("person_id = ?", person.ID).Order("year, id").Find(&changes)
+	previous := []string{}
+	for _, change := range changes {
+		if change.Year != 0 {
+			previous = append(previous, fmt.Sprintf("%s (%d)", change.FullName, change.Year))
+		} else {
+			previous = append(previous, change.FullName)
+		}
+	}
+	fields["previous"] = strings.Join(previous, ", ")
+	return fields, check
+}
+
+// GenerateReport fills the template, the latest uploaded one for a zero
+// templateID, and stores the report in the formats requested. The person has
+// to have a documents folder and a check with an approved final conclusion.
+func GenerateReport(db *gorm.DB, person models.Person, templateID uint, formats []string, author string) ([]models.Report, error) {
+	if person.PathToDocs == "" {
+		return nil, errors.New("у анкеты нет папки документов")
+	}
+	fields, check := ReportFields(db, person)
+	if check.ID == 0 {
+		return nil, errors.New("нет проверки с заключением")
+	}
+	if err := CheckApproved(db, person); err != nil {
+		return nil, err
+	}
+	fields["author"] = author
+
+	var template models.ReportTemplate
+	query := db.Order("id DESC").Limit(1)
+	if templateID != 0 {
+		query = query.Where("id = ?", templateID)
+	}
+	query.Find(&template)
+	if templateID != 0 && template.ID == 0 {
+		return nil, errors.New("шаблон не найден")
+	}
+
+	var source []byte
+	var err error
+	if template.ID != 0 {
+		source, err = os.ReadFile(filepath.Join(ReportTemplatesPath(), template.FileName))
+	} else {
+		source, err = NewDocx(DefaultReportText)
+	}
+	if err != nil {
+		return nil, err
+	}
+	docx, err := FillDocx(source, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := map[string][]byte{ReportDocx: docx}
+	for _, format := range formats {
+		if format == ReportPdf {
+			paragraphs, err := DocxParagraphs(docx)
+			if err != nil {
+				return nil, err
+			}
+			if contents[ReportPdf], err = NewPdf(paragraphs, ReportFont()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	folder := filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs, "reports")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("Заключение_%d_%s", check.ID, time.Now().Format("20060102_150405"))
+	reports := []models.Report{}
+	for _, format := range formats {
+		content := contents[format]
+		fileName := filepath.Join("reports", name+"."+format)
+		if err := os.WriteFile(filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs, fileName), content, 0644); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		reports = append(reports, models.Report{
+			Format:     format,
+			FileName:   fileName,
+			Checksum:   hex.EncodeToString(sum[:]),
+			Size:       int64(len(content)),
+			TemplateID: template.ID,
+			CheckID:    check.ID,
+			CreatedBy:  author,
+			PersonID:   person.ID,
+		})
+	}
+	if err := db.Create(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func reportFields(person models.Person, check models.Check, items []models.CheckItem, robot models.Robot, poligraf models.Poligraf, staff models.Staff) map[string]string {
+	lines := []string{}
+	for _, item := range items {
+		answer := item.Answer
+		switch answer {
+		case CheckYes:
+			answer = "Да"
+		case CheckNo:
+			answer = "Нет"
+		}
+		lines = append(lines, item.Label+": "+answer)
+	}
+
+	fields := map[string]string{
+		"fullname":         person.FullName,
+		"surname":          person.Surname,
+		"firstname":        person.FirstName,
+		"patronymic":       person.Patronymic,
+		"previous":         "",
+		"birthday":         reportDate(person.BirthDate.Time),
+		"birth_place":      person.BirthPlace,
+		"country":          person.Citizen,
+		"category":         "",
+		"region":           "",
+		"position":         staff.Position,
+		"department":       staff.Department,
+		"check_date":       reportDate(check.CreatedAt),
+		"officer":          check.Officer,
+		"workplace":        check.CheckWorkplace,
+		"employee":         check.CheckEmployee,
+		"document":         check.CheckDocument,
+		"inn":              check.CheckInn,
+		"debt":             check.Debt,
+		"bankruptcy":       check.Bankruptcy,
+		"bki":              check.BKI,
+		"courts":           check.Courts,
+		"affiliation":      check.Affiliation,
+		"terrorist":        check.Terrorist,
+		"mvd":              check.MVD,
+		"internet":         check.Internet,
+		"cronos":           check.Cronos,
+		"cros":             check.CROS,
+		"comments":         check.Comments,
+		"addition":         check.Addition,
+		"conclusion":       "",
+		"check_items":      strings.Join(lines, "\n"),
+		"robot_employee":   robot.Employee,
+		"robot_inn":        robot.Inn,
+		"robot_debt":       robot.Debt,
+		"robot_bankruptcy": robot.Bankruptcy,
+		"robot_bki":        robot.BKI,
+		"robot_courts":     robot.Courts,
+		"robot_terrorist":  robot.Terrorist,
+		"robot_mvd":        robot.MVD,
+		"poligraf_theme":   poligraf.Theme,
+		"poligraf_results": poligraf.Results,
+		"poligraf_officer": poligraf.Officer,
+		"poligraf_date":    reportDate(poligraf.CreatedAt),
+		"today":            reportDate(time.Now()),
+		"author":           "",
+	}
+	return fields
+}
+
+func reportDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("02.01.2006")
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// RetentionActions lists what a retention rule can do with an expired person.
+var RetentionActions map[string]string = map[string]string{
+	"anonymize": "Обезличить",
+	"purge":     "Удалить",
+}
+
+type RetentionItem struct {
+	PersonID     uint             `json:"person_id"`
+	RuleID       uint             `json:"rule_id"`
+	Action       string           `json:"action"`
+	LastActivity time.Time        `json:"last_activity"`
+	Records      map[string]int64 `json:"records,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// ExpiredPersons finds persons matching the rule whose last check, or the
+// last update when there are no checks, is older than the retention period.
+func ExpiredPersons(db *gorm.DB, rule models.RetentionRule) ([]RetentionItem, error) {
+	items := []RetentionItem{}
+	rows := []struct {
+		ID           uint
+		LastActivity time.Time
+	}{}
+
+	query := db.
+		Table("people").
+		Select("people.id, COALESCE(MAX(checks.updated_at), people.updated_at) AS last_activity").
+		Joins("LEFT JOIN checks ON checks.person_id = people.id").
+		Where("people.deleted_at IS NULL AND people.anonymized_at IS NULL").
+		Where("people.category_id = ? AND people.status_id = ?", rule.CategoryID, rule.StatusID).
+		Group("people.id").
+		Having("COALESCE(MAX(checks.updated_at), people.updated_at) < ?", time.Now().AddDate(0, -rule.Months, 0))
+
+	if rule.ConclusionID != 0 {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM checks c WHERE c.person_id = people.id AND c.conclusion_id = ?)",
+			rule.ConclusionID,
+		)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return items, err
+	}
+
+	for _, row := range rows {
+		items = append(items, RetentionItem{
+			PersonID:     row.ID,
+			RuleID:       rule.ID,
+			Action:       rule.Action,
+			LastActivity: row.LastActivity,
+		})
+	}
+	return items, nil
+}
+
+// RunRetention applies every retention rule and stores the audit record of the
+// run. With dryRun set it only reports the persons that would be processed.
+func RunRetention(db *gorm.DB, dryRun bool, initiator string) (models.RetentionRun, error) {
+	run := models.RetentionRun{DryRun: dryRun, Initiator: initiator}
+
+	var rules []models.RetentionRule
+	db.Order("id").Find(&rules)
+
+	report := []RetentionItem{}
+	seen := map[uint]bool{}
+	for _, rule := range rules {
+		if _, ok := RetentionActions[rule.Action]; !ok || rule.Months <= 0 {
+			continue
+		}
+		items, err := ExpiredPersons(db, rule)
+		if err != nil {
+			return run, err
+		}
+		for _, item := range items {
+			if seen[item.PersonID] {
+				continue
+			}
+			seen[item.PersonID] = true
+			if !dryRun {
+				if err := applyRetention(db, &item); err != nil {
+					item.Error = err.Error()
+					run.Failed++
+				}
+			}
+			report = append(report, item)
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return run, err
+	}
+	run.Persons = len(report)
+	run.Report = string(data)
+	return run, db.Create(&run).Error
+}
+
+func applyRetention(db *gorm.DB, item *RetentionItem) error {
+	var person models.Person
+	if err := db.First(&person, item.PersonID).Error; err != nil {
+		return err
+	}
+	switch item.Action {
+	case "anonymize":
+		records, err := AnonymizePerson(db, &person)
+		item.Records = records
+		return err
+	case "purge":
+		return PurgePerson(db, &person)
+	}
+	return fmt.Errorf("unknown action %q", item.Action)
+}
+
+// AnonymizePerson removes personal data of the person and its identifying
+// records while keeping checks and their conclusions for statistics. The free
+// text of the checks and the other findings is cleared, and the name is
+// replaced in the messages sent about the person. It returns the number of
+// rows deleted or cleared by table.
+func AnonymizePerson(db *gorm.DB, person *models.Person) (map[string]int64, error) {
+	docsPath := person.PathToDocs
+	records := map[string]int64{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		names := []string{person.FullName, person.PreviousFullName}
+		var previous []string
+		tx.Model(&models.NameChange{}).Where("person_id = ?", person.ID).Pluck("full_name", &previous)
+		names = append(names, previous...)
+
+		for _, model := range []interface{}{
+			&models.Document{}, &models.Address{}, &models.Contact{}, &models.Workplace{},
+			&models.Education{}, &models.NameChange{}, &models.Staff{}, &models.Affilation{},
+			&models.Relation{}, &models.Version{}, &models.Comment{}, &models.Report{},
+			&models.Approval{}, &models.Watch{}, &models.WatchEvent{}, &models.ContactPoint{},
+		} {
+			result := tx.Where("person_id = ?", person.ID).Delete(model)
+			if result.Error != nil {
+				return result.Error
+			}
+			records[tableName(tx, model)] += result.RowsAffected
+		}
+		result := tx.Where("related_id = ?", person.ID).Delete(&models.Relation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		records["relations"] += result.RowsAffected
+
+		for _, blank := range []struct {
+			model   interface{}
+			columns []string
+		}{
+			{&models.Check{}, []string{
+				"check_workplace", "check_employee", "check_document", "check_inn", "debt",
+				"bankruptcy", "bki", "courts", "affiliation", "terrorist", "mvd", "internet",
+				"cronos", "cros", "comments", "addition",
+			}},
+			{&models.Robot{}, []string{
+				"employee", "inn", "debt", "bankruptcy", "bki", "courts", "terrorist", "mvd",
+			}},
+			{&models.Poligraf{}, []string{"theme", "results"}},
+			{&models.Investigation{}, []string{"theme", "info"}},
+			{&models.Inquiry{}, []string{"info"}},
+			{&models.StatusHistory{}, []string{"reason"}},
+		} {
+			values := map[string]interface{}{}
+			for _, column := range blank.columns {
+				values[column] = ""
+			}
+			result := tx.Model(blank.model).Where("person_id = ?", person.ID).UpdateColumns(values)
+			if result.Error != nil {
+				return result.Error
+			}
+			records[tableName(tx, blank.model)] += result.RowsAffected
+		}
+		result = tx.
+			Model(&models.CheckItem{}).
+			Where("check_id IN (SELECT id FROM checks WHERE person_id = ?)", person.ID).
+			UpdateColumn("answer", "")
+		if result.Error != nil {
+			return result.Error
+		}
+		records["check_items"] += result.RowsAffected
+
+		alias := fmt.Sprintf("Обезличено #%d", person.ID)
+		for _, name := range names {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			pattern := "%" + name + "%"
+			result := tx.
+				Model(&models.Message{}).
+				Where("title LIKE ? OR message_content LIKE ?", pattern, pattern).
+				UpdateColumns(map[string]interface{}{
+					"title":           gorm.Expr("REPLACE(title, ?, ?)", name, alias),
+					"message_content": gorm.Expr("REPLACE(message_content, ?, ?)", name, alias),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			records["messages"] += result.RowsAffected
+		}
+
+		now := time.Now()
+		birthYear := models.Date{}
+		if !person.BirthDate.IsZero() {
+			birthYear = models.NewDate(time.Date(person.BirthDate.Year(), 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		result = tx.
+			Model(person).
+			Select(
+				"full_name", "surname", "first_name", "patronymic", "search_name",
+				"previous_full_name", "birth_date", "birth_place", "snils", "inn",
+				"snils_index", "inn_index",
+				"marital_status", "additional_info", "path_to_docs", "anonymized_at",
+			).
+			Updates(&models.Person{
+				FullName:     alias,
+				BirthDate:    birthYear,
+				AnonymizedAt: &now,
+			})
+		records["people"] += result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if docsPath != "" {
+		return records, os.RemoveAll(filepath.Join(os.Getenv("BASE_PATH"), docsPath))
+	}
+	return records, nil
+}
+
+func tableName(db *gorm.DB, model interface{}) string {
+	statement := &gorm.Statement{DB: db}
+	if err := statement.Parse(model); err != nil {
+		return fmt.Sprintf("%T", model)
+	}
+	return statement.Table
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/app/models"
+)
+
+// expiredQuery matches the query of ExpiredPersons.
+const expiredQuery = `FROM "people" LEFT JOIN checks`
+
+func TestExpiredPersons(t *testing.T) {
+	db, fake := newFakeDb(t)
+	activity := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	fake.Answer(expiredQuery, []string{"id", "last_activity"},
+		[]driver.Value{int64(4), activity},
+		[]driver.Value{int64(9), activity},
+	)
+
+	rule := models.RetentionRule{ID: 2, CategoryID: 3, StatusID: 6, Months: 36, Action: "anonymize"}
+	items, err := ExpiredPersons(db, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].PersonID != 4 || items[1].PersonID != 9 {
+		t.Fatalf("ExpiredPersons() = %+v, want persons 4 and 9", items)
+	}
+	for _, item := range items {
+		if item.RuleID != 2 || item.Action != "anonymize" || !item.LastActivity.Equal(activity) {
+			t.Errorf("ExpiredPersons() item = %+v, want rule 2, anonymize, %v", item, activity)
+		}
+	}
+
+	query := fake.Statements(expiredQuery)[0]
+	for _, condition := range []string{
+		"people.deleted_at IS NULL AND people.anonymized_at IS NULL",
+		"people.category_id = $1 AND people.status_id = $2",
+		"HAVING COALESCE(MAX(checks.updated_at), people.updated_at) < $3",
+	} {
+		if !strings.Contains(query.SQL, condition) {
+			t.Errorf("query %q lacks %q", query.SQL, condition)
+		}
+	}
+	if strings.Contains(query.SQL, "conclusion_id") {
+		t.Errorf("query %q filters by conclusion for a rule without one", query.SQL)
+	}
+	if query.Args[0] != int64(3) || query.Args[1] != int64(6) {
+		t.Errorf("query args = %v, want category 3 and status 6", query.Args)
+	}
+	cutoff := query.Args[2].(time.Time)
+	if want := time.Now().AddDate(-3, 0, 0); cutoff.Sub(want).Abs() > time.Minute {
+		t.Errorf("cutoff = %v, want 36 months ago", cutoff)
+	}
+}
+
+func TestExpiredPersonsConclusion(t *testing.T) {
+	db, fake := newFakeDb(t)
+	rule := models.RetentionRule{CategoryID: 3, StatusID: 6, ConclusionID: 2, Months: 12, Action: "purge"}
+	if _, err := ExpiredPersons(db, rule); err != nil {
+		t.Fatal(err)
+	}
+	query := fake.Statements(expiredQuery)[0]
+	if !strings.Contains(query.SQL, "c.conclusion_id = $3") || query.Args[2] != int64(2) {
+		t.Errorf("query %q %v does not filter by conclusion 2", query.SQL, query.Args)
+	}
+}
+
+func TestRunRetentionDryRun(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`FROM "retention_rules"`, []string{"id", "category_id", "status_id", "months", "action"},
+		[]driver.Value{int64(1), int64(3), int64(6), int64(36), "anonymize"},
+		[]driver.Value{int64(2), int64(3), int64(7), int64(0), "purge"},
+		[]driver.Value{int64(3), int64(3), int64(8), int64(12), "archive"},
+		[]driver.Value{int64(4), int64(4), int64(6), int64(12), "purge"},
+	)
+	// Person 4 matches the first and the last rule.
+	fake.AnswerFunc(expiredQuery, []string{"id", "last_activity"}, func(args []driver.Value) [][]driver.Value {
+		activity := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+		if args[0] == int64(3) {
+			return [][]driver.Value{{int64(4), activity}}
+		}
+		return [][]driver.Value{{int64(4), activity}, {int64(5), activity}}
+	})
+
+	run, err := RunRetention(db, true, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries := fake.Statements(expiredQuery); len(queries) != 2 {
+		t.Errorf("RunRetention() queried %d rules, want the 2 valid ones", len(queries))
+	}
+	report := []RetentionItem{}
+	json.Unmarshal([]byte(run.Report), &report)
+	if run.Persons != 2 || len(report) != 2 {
+		t.Fatalf("RunRetention() report = %s, want persons 4 and 5", run.Report)
+	}
+	if report[0].PersonID != 4 || report[0].RuleID != 1 || report[1].PersonID != 5 || report[1].RuleID != 4 {
+		t.Errorf("RunRetention() report = %+v, want person 4 by rule 1 and 5 by rule 4", report)
+	}
+	if !run.DryRun || run.Initiator != "test" {
+		t.Errorf("RunRetention() run = %+v, want a dry run by test", run)
+	}
+	if changes := fake.Statements(`^(UPDATE|DELETE)`); len(changes) != 0 {
+		t.Errorf("dry run changed data: %v", changes[0].SQL)
+	}
+	if len(fake.Statements(`^INSERT INTO "retention_runs"`)) != 1 {
+		t.Errorf("RunRetention() did not store the run")
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"backend/platform/database"
+)
+
+// Schedule runs the job every interval in the background.
+func Schedule(name string, interval time.Duration, job func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := job(); err != nil {
+				log.Printf("job %s: %v", name, err)
+			}
+		}
+	}()
+}
+
+// StartScheduler registers the periodic background jobs of the server.
+func StartScheduler() {
+	Schedule("trash", time.Hour, func() error {
+		_, err := PurgeTrash(database.OpenDb())
+		return err
+	})
+	Schedule("retention", 24*time.Hour, func() error {
+		dryRun := os.Getenv("RETENTION_MODE") != "apply"
+		_, err := RunRetention(database.OpenDb(), dryRun, "scheduler")
+		return err
+	})
+	Schedule("sla", time.Hour, func() error {
+		_, err := NotifyDeadlines(database.OpenDb())
+		return err
+	})
+	Schedule("documents", 24*time.Hour, func() error {
+		_, err := NotifyDocuments(database.OpenDb())
+		return err
+	})
+	Schedule("watch", time.Minute, func() error {
+		_, err := FlushWatchEvents(database.OpenDb())
+		return err
+	})
+}
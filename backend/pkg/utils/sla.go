@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// SlaKinds maps the kinds of work with deadlines to their tables.
+var SlaKinds map[string]string = map[string]string{
+	"check":         "checks",
+	"poligraf":      "poligrafs",
+	"investigation": "investigations",
+	"inquiry":       "inquiries",
+}
+
+type SlaItem struct {
+	Kind     string    `json:"kind"`
+	ID       uint      `json:"id"`
+	PersonID uint      `json:"person_id"`
+	FullName string    `json:"fullname"`
+	Officer  string    `json:"officer"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// SlaRemindDays returns how many days before the deadline officers get a reminder.
+func SlaRemindDays() int {
+	days, err := strconv.Atoi(os.Getenv("SLA_REMIND_DAYS"))
+	if err != nil || days < 0 {
+		return 2
+	}
+	return days
+}
+
+// Deadline calculates the due date of the work on the person started at the
+// given time. The rule for the person's category and region wins over rules
+// for any category or any region. Without a rule there is no deadline.
+func Deadline(db *gorm.DB, kind string, personID uint, start time.Time) *time.Time {
+	var person models.Person
+	db.Unscoped().First(&person, personID)
+	if person.ID == 0 {
+		return nil
+	}
+
+	var rules []models.SlaRule
+	db.
+		Where("kind = ?", kind).
+		Where("category_id = 0 OR category_id = ?", person.CategoryID).
+		Where("region_id = 0 OR region_id = ?", person.RegionID).
+		Find(&rules)
+
+	best, score := models.SlaRule{}, -1
+	for _, rule := range rules {
+		ruleScore := 0
+		if rule.CategoryID != 0 {
+			ruleScore += 2
+		}
+		if rule.RegionID != 0 {
+			ruleScore++
+		}
+		if ruleScore > score {
+			best, score = rule, ruleScore
+		}
+	}
+	if score < 0 || best.Days <= 0 {
+		return nil
+	}
+	deadline := start.AddDate(0, 0, best.Days)
+	return &deadline
+}
+
+// RecalculateDeadlines sets the deadlines of open work that has none, for
+// example after a new rule was added.
+func RecalculateDeadlines(db *gorm.DB) (int, error) {
+	count := 0
+	for kind, table := range SlaKinds {
+		rows := []struct {
+			ID        uint
+			PersonID  uint
+			CreatedAt time.Time
+		}{}
+		err := slaOpen(db.Table(table), kind, table).
+			Select(table + ".id, " + table + ".person_id, " + table + ".created_at").
+			Where(table + ".deadline IS NULL").
+			Scan(&rows).
+			Error
+		if err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			start := row.CreatedAt
+			if start.IsZero() {
+				start = time.Now()
+			}
+			deadline := Deadline(db, kind, row.PersonID, start)
+			if deadline == nil {
+				continue
+			}
+			db.Table(table).Where("id = ?", row.ID).Update("deadline", *deadline)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// slaOpen limits the query to unfinished work of persons that are still in
+// progress. Checks saved as a draft or sent to the polygraph are unfinished.
+func slaOpen(query *gorm.DB, kind string, table string) *gorm.DB {
+	if kind == "check" {
+		query = query.Where("COALESCE(checks.conclusion_id, 0) IN ?", interimConclusions())
+	} else {
+		query = query.Where(table+".closed = ?", false)
+	}
+	return query.
+		Joins("JOIN people ON people.id = "+table+".person_id AND people.deleted_at IS NULL").
+		Where(
+			"people.status_id NOT IN ?",
+			[]uint{models.Status{}.GetID(Statuses["finish"]), models.Status{}.GetID(Statuses["cancel"])},
+		)
+}
+
+// DueItems returns open work with deadlines before the given time, ordered by
+// officer and deadline. An empty officer means all officers.
+func DueItems(db *gorm.DB, before time.Time, officer string) ([]SlaItem, error) {
+	items := []SlaItem{}
+	for kind, table := range SlaKinds {
+		rows := []SlaItem{}
+		query := slaOpen(db.Table(table), kind, table).
+			Select(
+				"? AS kind, "+table+".id, "+table+".person_id, people.full_name, "+table+".officer, "+table+".deadline",
+				kind,
+			).
+			Where(table+".deadline IS NOT NULL AND "+table+".deadline < ?", before)
+		if officer != "" {
+			query = query.Where(table+".officer = ?", officer)
+		}
+		if err := query.Scan(&rows).Error; err != nil {
+			return items, err
+		}
+		items = append(items, rows...)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Officer != items[j].Officer {
+			return items[i].Officer < items[j].Officer
+		}
+		return items[i].Deadline.Before(items[j].Deadline)
+	})
+	return items, nil
+}
+
+// NotifyDeadlines sends reminders for work due within SLA_REMIND_DAYS and
+// escalations for overdue work. Each notice is sent once per deadline.
+func NotifyDeadlines(db *gorm.DB) (int, error) {
+	now := time.Now()
+	items, err := DueItems(db, now.AddDate(0, 0, SlaRemindDays()), "")
+	if err != nil {
+		return 0, err
+	}
+
+	var admins []models.User
+	db.
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name_role = ? AND users.blocked = ? AND users.deleted = ?", "admin", false, false).
+		Find(&admins)
+
+	sent := 0
+	for _, item := range items {
+		notice := "remind"
+		if item.Deadline.Before(now) {
+			notice = "escalate"
+		}
+		var count int64
+		db.
+			Model(&models.SlaNotice{}).
+			Where("kind = ? AND item_id = ? AND notice = ? AND deadline = ?", item.Kind, item.ID, notice, item.Deadline).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		var officer models.User
+		db.Where("full_name = ?", item.Officer).First(&officer)
+		recipients := []uint{}
+		if officer.ID != 0 {
+			recipients = append(recipients, officer.ID)
+		}
+
+		title := "Срок истекает: " + item.FullName
+		content := fmt.Sprintf(
+			"%s #%d, срок %s", WatchItems[item.Kind], item.ID, item.Deadline.Format("02.01.2006"),
+		)
+		if notice == "escalate" {
+			title = "Срок нарушен: " + item.FullName
+			content += ", исполнитель " + item.Officer
+			for _, admin := range admins {
+				if admin.ID != officer.ID {
+					recipients = append(recipients, admin.ID)
+				}
+			}
+		}
+		for _, userID := range recipients {
+			db.Create(&models.Message{
+				Title:          title,
+				MessageContent: content,
+				StatusRead:     "new",
+				UserID:         userID,
+			})
+			sent++
+		}
+		db.Create(&models.SlaNotice{Kind: item.Kind, ItemID: item.ID, Notice: notice, Deadline: item.Deadline})
+	}
+	return sent, nil
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadline(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(personQuery, []string{"id", "category_id", "region_id"}, []driver.Value{int64(5), int64(3), int64(2)})
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "kind", "category_id", "region_id", "days"}
+
+	tests := []struct {
+		name  string
+		rules [][]driver.Value
+		days  int
+	}{
+		{"no rules", nil, 0},
+		{"default", [][]driver.Value{{int64(1), "check", int64(0), int64(0), int64(30)}}, 30},
+		{"category over region", [][]driver.Value{
+			{int64(1), "check", int64(0), int64(0), int64(30)},
+			{int64(2), "check", int64(0), int64(2), int64(20)},
+			{int64(3), "check", int64(3), int64(0), int64(10)},
+		}, 10},
+		{"category and region", [][]driver.Value{
+			{int64(3), "check", int64(3), int64(0), int64(10)},
+			{int64(4), "check", int64(3), int64(2), int64(5)},
+		}, 5},
+		{"no deadline", [][]driver.Value{
+			{int64(1), "check", int64(0), int64(0), int64(30)},
+			{int64(4), "check", int64(3), int64(2), int64(0)},
+		}, 0},
+	}
+	for _, test := range tests {
+		fake.Answer(`FROM "sla_rules"`, columns, test.rules...)
+		deadline := Deadline(db, "check", 5, start)
+		switch {
+		case test.days == 0 && deadline != nil:
+			t.Errorf("%s: Deadline() = %v, want none", test.name, deadline)
+		case test.days != 0 && (deadline == nil || !deadline.Equal(start.AddDate(0, 0, test.days))):
+			t.Errorf("%s: Deadline() = %v, want %d days after the start", test.name, deadline, test.days)
+		}
+	}
+
+	query := fake.Statements(`FROM "sla_rules"`)[0]
+	if query.Args[0] != "check" || query.Args[1] != int64(3) || query.Args[2] != int64(2) {
+		t.Errorf("rules query args = %v, want the kind, category and region of the person", query.Args)
+	}
+	if deadline := Deadline(db, "check", 9, start); deadline != nil {
+		t.Errorf("Deadline() of a missing person = %v, want none", deadline)
+	}
+}
+
+func TestDueItems(t *testing.T) {
+	db, fake := newFakeDb(t)
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	columns := []string{"kind", "id", "person_id", "full_name", "officer", "deadline"}
+	fake.Answer(`FROM "checks"`, columns,
+		[]driver.Value{"check", int64(1), int64(5), "Иванов Иван", "Петров", now.AddDate(0, 0, -1)},
+		[]driver.Value{"check", int64(2), int64(6), "Сидоров Сидор", "Андреев", now.AddDate(0, 0, 1)},
+	)
+	fake.Answer(`FROM "poligrafs"`, columns,
+		[]driver.Value{"poligraf", int64(3), int64(5), "Иванов Иван", "Петров", now.AddDate(0, 0, -2)},
+	)
+
+	items, err := DueItems(db, now, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Андреев check 2", "Петров poligraf 3", "Петров check 1"}
+	if len(items) != len(want) {
+		t.Fatalf("DueItems() = %+v, want %v", items, want)
+	}
+	for i, item := range items {
+		if got := fmt.Sprintf("%s %s %d", item.Officer, item.Kind, item.ID); got != want[i] {
+			t.Errorf("DueItems()[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+
+	if _, err := DueItems(db, now, "Петров"); err != nil {
+		t.Fatal(err)
+	}
+	for kind, table := range SlaKinds {
+		queries := fake.Statements(`FROM "` + table + `"`)
+		if len(queries) != 2 {
+			t.Fatalf("%s was queried %d times, want 2", table, len(queries))
+		}
+		if strings.Contains(queries[0].SQL, ".officer =") {
+			t.Errorf("%s query %q filters by officer without one", kind, queries[0].SQL)
+		}
+		query := queries[1]
+		conditions := []string{
+			"JOIN people ON people.id = " + table + ".person_id AND people.deleted_at IS NULL",
+			"people.status_id NOT IN",
+			table + ".deadline IS NOT NULL AND " + table + ".deadline <",
+			table + ".officer =",
+		}
+		if kind == "check" {
+			conditions = append(conditions, "COALESCE(checks.conclusion_id, 0) IN")
+		} else {
+			conditions = append(conditions, table+".closed =")
+		}
+		for _, condition := range conditions {
+			if !strings.Contains(query.SQL, condition) {
+				t.Errorf("%s query %q lacks %q", kind, query.SQL, condition)
+			}
+		}
+	}
+}
+
+func TestMigrateDeadlines(t *testing.T) {
+	db, fake := newFakeDb(t)
+	if err := migrateDeadlines(db); err != nil {
+		t.Fatal(err)
+	}
+	statements := fake.Statements(`^UPDATE`)
+	if len(statements) != 8 {
+		t.Fatalf("migrateDeadlines() ran %d updates, want 8", len(statements))
+	}
+	// The creation dates are backfilled before the deadlines are cleared.
+	for i, statement := range statements {
+		clears := strings.Contains(statement.SQL, "SET deadline = NULL")
+		if clears != (i >= 4) {
+			t.Errorf("update %d = %q is out of order", i, statement.SQL)
+		}
+	}
+	if !strings.Contains(statements[3].SQL, "checks SET created_at = COALESCE(created_at, deadline)") {
+		t.Errorf("update %q does not backfill the checks", statements[3].SQL)
+	}
+
+	db, fake = newFakeDb(t)
+	fake.Fail(`^UPDATE checks`, errors.New("checks are locked"))
+	if err := migrateDeadlines(db); err == nil {
+		t.Fatal("migrateDeadlines() returned no error")
+	}
+	if clears := fake.Statements(`SET deadline = NULL`); len(clears) != 0 {
+		t.Errorf("migrateDeadlines() cleared deadlines after a failed backfill")
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+var tagColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ValidateTag checks the name, the colour and the group restriction of a tag.
+func ValidateTag(db *gorm.DB, tag *models.Tag) FieldErrors {
+	errs := FieldErrors{}
+	tag.Name = strings.Join(strings.Fields(tag.Name), " ")
+	if tag.Name == "" {
+		errs["name"] = "не указано название"
+	}
+	if !tagColor.MatchString(tag.Color) {
+		errs["color"] = "цвет должен быть в формате #RRGGBB"
+	}
+	if tag.GroupID != 0 {
+		var group models.Group
+		db.First(&group, tag.GroupID)
+		if group.ID == 0 {
+			errs["group_id"] = "группа не найдена"
+		}
+	}
+	return errs
+}
+
+// TagAllowed reports whether the user may see and use the tag. Tags without
+// a group are available to everyone.
+func TagAllowed(db *gorm.DB, tag models.Tag, user *middlewares.TokenMetadata) bool {
+	if tag.GroupID == 0 {
+		return true
+	}
+	var group models.Group
+	db.First(&group, tag.GroupID)
+	for _, name := range user.Groups {
+		if name == group.NameGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableTags returns the tags the user may attach.
+func AvailableTags(db *gorm.DB, user *middlewares.TokenMetadata) []models.Tag {
+	var tags []models.Tag
+	db.Order("name").Find(&tags)
+	available := []models.Tag{}
+	for _, tag := range tags {
+		if TagAllowed(db, tag, user) {
+			available = append(available, tag)
+		}
+	}
+	return available
+}
+
+// visibleTagCondition selects the tags available to the groups given as its
+// parameter, the same ones TagAllowed permits.
+const visibleTagCondition = "COALESCE(tags.group_id, 0) = 0 OR tags.group_id IN (SELECT id FROM groups WHERE name_group IN ?)"
+
+// PersonTags returns the tags attached to the person that the user may see.
+func PersonTags(db *gorm.DB, personID uint, user *middlewares.TokenMetadata) []models.Tag {
+	tags := []models.Tag{}
+	db.
+		Joins("JOIN person_tags ON person_tags.tag_id = tags.id").
+		Where("person_tags.person_id = ?", personID).
+		Where(visibleTagCondition, user.Groups).
+		Order("tags.name").
+		Find(&tags)
+	return tags
+}
+
+// TaggedWith limits a persons query to those having any of the tags that the
+// user may see. Tags of other groups match no one.
+func TaggedWith(tagIDs []uint, user *middlewares.TokenMetadata) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(tagIDs) == 0 {
+			return db
+		}
+		return db.Where(
+			"EXISTS (SELECT 1 FROM person_tags JOIN tags ON tags.id = person_tags.tag_id "+
+				"WHERE person_tags.person_id = people.id AND person_tags.tag_id IN ? AND ("+visibleTagCondition+"))",
+			tagIDs, user.Groups,
+		)
+	}
+}
+
+// AttachTag attaches the tag to the person and records it in the history.
+func AttachTag(db *gorm.DB, personID uint, tagID uint, user *middlewares.TokenMetadata) error {
+	var tag models.Tag
+	db.First(&tag, tagID)
+	if tag.ID == 0 {
+		return errors.New("метка не найдена")
+	}
+	if !TagAllowed(db, tag, user) {
+		return errors.New("метка недоступна")
+	}
+
+	var personTag models.PersonTag
+	db.Where("person_id = ? AND tag_id = ?", personID, tagID).First(&personTag)
+	if personTag.ID != 0 {
+		return nil
+	}
+	personTag = models.PersonTag{PersonID: personID, TagID: tagID}
+	if err := db.Create(&personTag).Error; err != nil {
+		return err
+	}
+	return RecordVersion(db, "person_tag", nil, &personTag, user)
+}
+
+// DetachTag removes the tag from the person and records it in the history.
+func DetachTag(db *gorm.DB, personID uint, tagID uint, user *middlewares.TokenMetadata) error {
+	var tag models.Tag
+	db.First(&tag, tagID)
+	if tag.ID != 0 && !TagAllowed(db, tag, user) {
+		return errors.New("метка недоступна")
+	}
+
+	var personTag models.PersonTag
+	db.Where("person_id = ? AND tag_id = ?", personID, tagID).First(&personTag)
+	if personTag.ID == 0 {
+		return nil
+	}
+	if err := db.Delete(&personTag).Error; err != nil {
+		return err
+	}
+	return RecordVersion(db, "person_tag", &personTag, nil, user)
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+func TestValidateTag(t *testing.T) {
+	db, _ := newFakeDb(t)
+	tag := models.Tag{Name: "  Особый \t контроль ", Color: "#A0b1C2", GroupID: lookupID(Groups, "staffsec")}
+	if errs := ValidateTag(db, &tag); len(errs) != 0 {
+		t.Errorf("ValidateTag() = %v, want no errors", errs)
+	}
+	if tag.Name != "Особый контроль" {
+		t.Errorf("ValidateTag() name = %q, want the spaces collapsed", tag.Name)
+	}
+
+	errs := ValidateTag(db, &models.Tag{Name: " ", Color: "red", GroupID: 99})
+	for _, field := range []string{"name", "color", "group_id"} {
+		if _, ok := errs[field]; !ok {
+			t.Errorf("ValidateTag() = %v, want an error for %s", errs, field)
+		}
+	}
+}
+
+func TestTaggedWith(t *testing.T) {
+	db, fake := newFakeDb(t)
+	user := &middlewares.TokenMetadata{Groups: []string{"staffsec"}}
+	var persons []models.Person
+	db.Model(&models.Person{}).Scopes(TaggedWith(nil, user)).Find(&persons)
+	db.Model(&models.Person{}).Scopes(TaggedWith([]uint{1, 2}, user)).Find(&persons)
+
+	queries := fake.Statements(`FROM "people"`)
+	if len(queries) != 2 {
+		t.Fatalf("ran %d queries, want 2", len(queries))
+	}
+	if strings.Contains(queries[0].SQL, "person_tags") {
+		t.Errorf("query %q filters by tags without any", queries[0].SQL)
+	}
+	query := queries[1]
+	for _, condition := range []string{
+		"person_tags.tag_id IN ($1,$2)",
+		"COALESCE(tags.group_id, 0) = 0 OR tags.group_id IN (SELECT id FROM groups WHERE name_group IN ($3))",
+	} {
+		if !strings.Contains(query.SQL, condition) {
+			t.Errorf("query %q lacks %q", query.SQL, condition)
+		}
+	}
+	if len(query.Args) != 3 || query.Args[2] != "staffsec" {
+		t.Errorf("query args = %v, want the tags and the groups of the user", query.Args)
+	}
+}
+
+func TestAttachTag(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`FROM "tags" WHERE "tags"."id" = \$1`, []string{"id", "name", "group_id"},
+		[]driver.Value{int64(6), "Особый контроль", int64(lookupID(Groups, "vip_reviewers"))},
+	)
+
+	outsider := &middlewares.TokenMetadata{FullName: "Проверяющий", Groups: []string{"staffsec"}}
+	if err := AttachTag(db, 5, 6, outsider); err == nil {
+		t.Errorf("AttachTag() attached a tag of another group")
+	}
+	if len(fake.Statements(`^INSERT`)) != 0 {
+		t.Fatalf("AttachTag() stored a tag of another group")
+	}
+
+	member := &middlewares.TokenMetadata{FullName: "Руководитель", Groups: []string{"vip_reviewers"}}
+	if err := AttachTag(db, 5, 6, member); err != nil {
+		t.Fatal(err)
+	}
+	inserts := fake.Statements(`^INSERT INTO "person_tags"`)
+	if len(inserts) != 1 {
+		t.Fatalf("AttachTag() inserted %d person tags, want 1", len(inserts))
+	}
+	if tagID, _ := inserts[0].Arg("tag_id"); tagID != int64(6) {
+		t.Errorf("attached tag_id = %v, want 6", tagID)
+	}
+	if len(fake.Statements(`^INSERT INTO "versions"`)) != 1 {
+		t.Errorf("AttachTag() did not record the version")
+	}
+
+	// A tag that is already attached is left as it is.
+	fake.Answer(`FROM "person_tags" WHERE person_id = \$1 AND tag_id = \$2`, []string{"id", "person_id", "tag_id"},
+		[]driver.Value{int64(1), int64(5), int64(6)},
+	)
+	if err := AttachTag(db, 5, 6, member); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Statements(`^INSERT INTO "person_tags"`)) != 1 {
+		t.Errorf("AttachTag() attached the tag twice")
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+)
+
+// TimelineEntry is a workplace of the person on the employment timeline. The
+// end of the current job is today.
+type TimelineEntry struct {
+	WorkplaceID uint        `json:"workplace_id"`
+	Workplace   string      `json:"workplace"`
+	Position    string      `json:"position"`
+	StartDate   models.Date `json:"start_date"`
+	EndDate     models.Date `json:"end_date"`
+	Current     bool        `json:"current"`
+	Days        int         `json:"days"`
+}
+
+// TimelineIssue is a finding of the timeline analysis. Kind is "gap" for a
+// period without employment, "overlap" for jobs held at the same time,
+// "short" for a short tenure and "undated" for a workplace without dates.
+type TimelineIssue struct {
+	Kind         string      `json:"kind"`
+	From         models.Date `json:"from"`
+	To           models.Date `json:"to"`
+	Days         int         `json:"days"`
+	WorkplaceIDs []uint      `json:"workplace_ids"`
+}
+
+type Timeline struct {
+	GapDays     int                 `json:"gap_days"`
+	ShortDays   int                 `json:"short_days"`
+	Entries     []TimelineEntry     `json:"entries"`
+	Issues      []TimelineIssue     `json:"issues"`
+	NameChanges []models.NameChange `json:"name_changes"`
+}
+
+// EmploymentGapDays returns the length of a gap between jobs that needs an
+// explanation.
+func EmploymentGapDays() int {
+	return envDays("EMPLOYMENT_GAP_DAYS", 90)
+}
+
+// EmploymentShortDays returns the tenure below which a finished job is
+// reported as implausibly short.
+func EmploymentShortDays() int {
+	return envDays("EMPLOYMENT_SHORT_DAYS", 90)
+}
+
+// PersonTimeline analyses the workplaces of the person and adds the changes
+// of the name by year.
+func PersonTimeline(db *gorm.DB, personID uint, gapDays int, shortDays int) Timeline {
+	var workplaces []models.Workplace
+	db.Where("person_id = ?", personID).Order("start_date, id").Find(&workplaces)
+	timeline := EmploymentTimeline(workplaces, time.Now(), gapDays, shortDays)
+	db.Where("person_id = ?", personID).Order("year, id").Find(&timeline.NameChanges)
+	return timeline
+}
+
+// EmploymentTimeline orders the workplaces by their start and reports the
+// gaps longer than gapDays, the overlapping jobs and the finished jobs
+// shorter than shortDays. A gap after the last job ends today.
+func EmploymentTimeline(workplaces []models.Workplace, now time.Time, gapDays int, shortDays int) Timeline {
+	today := models.NewDate(now)
+	timeline := Timeline{
+		GapDays:     gapDays,
+		ShortDays:   shortDays,
+		Entries:     []TimelineEntry{},
+		Issues:      []TimelineIssue{},
+		NameChanges: []models.NameChange{},
+	}
+
+	for _, workplace := range workplaces {
+		entry := TimelineEntry{
+			WorkplaceID: workplace.ID,
+			Workplace:   workplace.Workplace,
+			Position:    workplace.Position,
+			StartDate:   workplace.StartDate,
+			EndDate:     workplace.EndDate,
+			Current:     workplace.Current,
+		}
+		if entry.EndDate.IsZero() && entry.Current {
+			entry.EndDate = today
+		}
+		if entry.StartDate.IsZero() || entry.EndDate.IsZero() {
+			timeline.Issues = append(timeline.Issues, TimelineIssue{
+				Kind: "undated", WorkplaceIDs: []uint{workplace.ID},
+			})
+			continue
+		}
+		entry.Days = daysBetween(entry.StartDate, entry.EndDate)
+		timeline.Entries = append(timeline.Entries, entry)
+	}
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		return timeline.Entries[i].StartDate.Before(timeline.Entries[j].StartDate.Time)
+	})
+
+	// last is the entry that ends latest among the entries seen so far.
+	var last *TimelineEntry
+	for i := range timeline.Entries {
+		entry := &timeline.Entries[i]
+		if !entry.Current && entry.Days < shortDays {
+			timeline.Issues = append(timeline.Issues, TimelineIssue{
+				Kind: "short", From: entry.StartDate, To: entry.EndDate, Days: entry.Days,
+				WorkplaceIDs: []uint{entry.WorkplaceID},
+			})
+		}
+		if last != nil {
+			if entry.StartDate.Before(last.EndDate.Time) {
+				to := last.EndDate
+				if entry.EndDate.Before(to.Time) {
+					to = entry.EndDate
+				}
+				timeline.Issues = append(timeline.Issues, TimelineIssue{
+					Kind: "overlap", From: entry.StartDate, To: to, Days: daysBetween(entry.StartDate, to),
+					WorkplaceIDs: []uint{last.WorkplaceID, entry.WorkplaceID},
+				})
+			} else if days := daysBetween(last.EndDate, entry.StartDate) - 1; days > gapDays {
+				timeline.Issues = append(timeline.Issues, TimelineIssue{
+					Kind: "gap", From: last.EndDate, To: entry.StartDate, Days: days,
+					WorkplaceIDs: []uint{last.WorkplaceID, entry.WorkplaceID},
+				})
+			}
+		}
+		if last == nil || last.EndDate.Before(entry.EndDate.Time) {
+			last = entry
+		}
+	}
+	if last != nil {
+		if days := daysBetween(last.EndDate, today) - 1; days > gapDays {
+			timeline.Issues = append(timeline.Issues, TimelineIssue{
+				Kind: "gap", From: last.EndDate, To: today, Days: days,
+				WorkplaceIDs: []uint{last.WorkplaceID},
+			})
+		}
+	}
+	return timeline
+}
+
+func daysBetween(from models.Date, to models.Date) int {
+	return int(to.Sub(from.Time).Hours() / 24)
+}
+
+func envDays(name string, fallback int) int {
+	days, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || days < 0 {
+		return fallback
+	}
+	return days
+}
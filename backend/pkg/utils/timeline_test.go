@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/app/models"
+)
+
+func TestEmploymentTimeline(t *testing.T) {
+	date := func(value string) models.Date {
+		parsed, err := time.Parse(models.DateLayout, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return models.NewDate(parsed)
+	}
+	job := func(id uint, start string, end string) models.Workplace {
+		workplace := models.Workplace{ID: id, Workplace: fmt.Sprintf("job %d", id)}
+		if start != "" {
+			workplace.StartDate = date(start)
+		}
+		if end == "now" {
+			workplace.Current = true
+		} else if end != "" {
+			workplace.EndDate = date(end)
+		}
+		return workplace
+	}
+	now := time.Date(2024, 6, 30, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		workplaces []models.Workplace
+		entries    string
+		issues     string
+	}{
+		{
+			"continuous",
+			[]models.Workplace{job(1, "2015-01-01", "2019-12-31"), job(2, "2020-01-01", "now")},
+			"1 2", "",
+		},
+		{
+			"unsorted",
+			[]models.Workplace{job(2, "2020-01-01", "now"), job(1, "2015-01-01", "2019-12-31")},
+			"1 2", "",
+		},
+		{
+			"gap between jobs",
+			[]models.Workplace{job(1, "2015-01-01", "2018-12-31"), job(2, "2019-06-01", "now")},
+			"1 2", "gap 151 [1 2]",
+		},
+		{
+			"gap within the limit",
+			[]models.Workplace{job(1, "2015-01-01", "2018-12-31"), job(2, "2019-03-31", "now")},
+			"1 2", "",
+		},
+		{
+			"overlap",
+			[]models.Workplace{job(1, "2015-01-01", "2020-12-31"), job(2, "2020-06-01", "now")},
+			"1 2", "overlap 213 [1 2]",
+		},
+		{
+			"nested job",
+			[]models.Workplace{job(1, "2015-01-01", "now"), job(2, "2016-01-01", "2017-01-01"), job(3, "2018-01-01", "2019-01-01")},
+			"1 2 3", "overlap 366 [1 2], overlap 365 [1 3]",
+		},
+		{
+			"short tenure",
+			[]models.Workplace{job(1, "2015-01-01", "2015-02-15"), job(2, "2015-03-01", "now")},
+			"1 2", "short 45 [1]",
+		},
+		{
+			"gap until today",
+			[]models.Workplace{job(1, "2015-01-01", "2023-12-31")},
+			"1", "gap 181 [1]",
+		},
+		{
+			"undated",
+			[]models.Workplace{job(1, "", "2019-12-31"), job(2, "2020-01-01", "now")},
+			"2", "undated 0 [1]",
+		},
+		{"no workplaces", nil, "", ""},
+	}
+	for _, test := range tests {
+		timeline := EmploymentTimeline(test.workplaces, now, 90, 90)
+		entries := []string{}
+		for _, entry := range timeline.Entries {
+			entries = append(entries, fmt.Sprint(entry.WorkplaceID))
+		}
+		issues := []string{}
+		for _, issue := range timeline.Issues {
+			issues = append(issues, fmt.Sprintf("%s %d %v", issue.Kind, issue.Days, issue.WorkplaceIDs))
+		}
+		if got := strings.Join(entries, " "); got != test.entries {
+			t.Errorf("%s: entries = %q, want %q", test.name, got, test.entries)
+		}
+		if got := strings.Join(issues, ", "); got != test.issues {
+			t.Errorf("%s: issues = %q, want %q", test.name, got, test.issues)
+		}
+	}
+}
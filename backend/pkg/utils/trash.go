@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// TrashPath returns the folder that keeps documents of deleted persons.
+func TrashPath() string {
+	return filepath.Join(os.Getenv("BASE_PATH"), ".trash")
+}
+
+// TrashPerson soft deletes the person and moves the documents folder to the
+// trash. The folder is moved last, and moved back if the transaction fails to
+// commit, so the person and the folder stay together.
+func TrashPerson(db *gorm.DB, person *models.Person, user *middlewares.TokenMetadata) error {
+	before := *person
+
+	docsPath := filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs)
+	trashPath := ""
+	stat, err := os.Stat(docsPath)
+	if person.PathToDocs != "" && err == nil && stat.IsDir() {
+		if err := os.MkdirAll(TrashPath(), 0755); err != nil {
+			return err
+		}
+		trashPath = fmt.Sprintf("%d-%s", person.ID, time.Now().Format("20060102150405"))
+	}
+
+	person.TrashPath = trashPath
+	person.DeletedBy = "system"
+	if user != nil {
+		person.DeletedBy = user.FullName
+	}
+	moved := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(person).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(person).Error; err != nil {
+			return err
+		}
+		if err := RecordVersion(tx, "person", &before, nil, user); err != nil {
+			return err
+		}
+		if trashPath == "" {
+			return nil
+		}
+		if err := os.Rename(docsPath, filepath.Join(TrashPath(), trashPath)); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	if err != nil {
+		if moved {
+			os.Rename(filepath.Join(TrashPath(), trashPath), docsPath)
+		}
+		person.TrashPath, person.DeletedBy, person.DeletedAt = before.TrashPath, before.DeletedBy, before.DeletedAt
+		return err
+	}
+	return nil
+}
+
+// RestorePerson returns a trashed person and its documents folder back. Like
+// in TrashPerson the folder is moved last.
+func RestorePerson(db *gorm.DB, person *models.Person, user *middlewares.TokenMetadata) error {
+	trashPath := person.TrashPath
+	docsPath := filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs)
+	if trashPath != "" {
+		if _, err := os.Stat(docsPath); err == nil {
+			return fmt.Errorf("folder %s already exists", person.PathToDocs)
+		}
+		if err := os.MkdirAll(filepath.Dir(docsPath), 0755); err != nil {
+			return err
+		}
+	}
+
+	moved := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Unscoped().
+			Model(person).
+			Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": "", "trash_path": ""}).
+			Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(person, person.ID).Error; err != nil {
+			return err
+		}
+		if err := RecordVersion(tx, "person", nil, person, user); err != nil {
+			return err
+		}
+		if trashPath == "" {
+			return nil
+		}
+		if err := os.Rename(filepath.Join(TrashPath(), trashPath), docsPath); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	if err != nil && moved {
+		os.Rename(docsPath, filepath.Join(TrashPath(), trashPath))
+	}
+	return err
+}
+
+// PurgePerson permanently removes a person with its records and documents.
+func PurgePerson(db *gorm.DB, person *models.Person) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Where("check_id IN (SELECT id FROM checks WHERE person_id = ?)", person.ID).
+			Delete(&models.CheckItem{}).
+			Error
+		if err != nil {
+			return err
+		}
+		for item, newModel := range VersionModels {
+			if item == "person" {
+				continue
+			}
+			if err := tx.Where("person_id = ?", person.ID).Delete(newModel()).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.StatusHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Version{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Watch{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.WatchEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Approval{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("related_id = ?", person.ID).Delete(&models.Relation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.ContactPoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(person).Error
+	})
+	if err != nil {
+		return err
+	}
+	if person.PathToDocs != "" {
+		if err := os.RemoveAll(filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs)); err != nil {
+			return err
+		}
+	}
+	if person.TrashPath != "" {
+		return os.RemoveAll(filepath.Join(TrashPath(), person.TrashPath))
+	}
+	return nil
+}
+
+// PurgeTrash purges persons kept in the trash longer than TRASH_RETENTION_DAYS.
+func PurgeTrash(db *gorm.DB) (int, error) {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return 0, nil
+	}
+
+	var persons []models.Person
+	db.
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().AddDate(0, 0, -days)).
+		Find(&persons)
+
+	for i := range persons {
+		if err := PurgePerson(db, &persons[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(persons), nil
+}
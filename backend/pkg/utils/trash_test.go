@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// docsFolder creates the documents folder of the person under a temporary
+// BASE_PATH.
+func docsFolder(t *testing.T, person *models.Person) string {
+	t.Setenv("BASE_PATH", t.TempDir())
+	person.PathToDocs = filepath.Join("И", "5-Иванов Иван")
+	path := filepath.Join(os.Getenv("BASE_PATH"), person.PathToDocs)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "anketa.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTrashPerson(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{ID: 5, FullName: "Иванов Иван"}
+	docsPath := docsFolder(t, &person)
+	user := &middlewares.TokenMetadata{UserID: 3, FullName: "Проверяющий"}
+
+	if err := TrashPerson(db, &person, user); err != nil {
+		t.Fatal(err)
+	}
+	if person.TrashPath == "" || person.DeletedBy != user.FullName {
+		t.Fatalf("TrashPerson() left trash path %q, deleted by %q", person.TrashPath, person.DeletedBy)
+	}
+	if _, err := os.Stat(docsPath); !os.IsNotExist(err) {
+		t.Errorf("documents folder is still in place")
+	}
+	if _, err := os.Stat(filepath.Join(TrashPath(), person.TrashPath, "anketa.json")); err != nil {
+		t.Errorf("documents are not in the trash: %v", err)
+	}
+
+	saves := fake.Statements(`^UPDATE "people" SET .*"trash_path"`)
+	if len(saves) != 1 {
+		t.Fatalf("TrashPerson() saved the person %d times, want once", len(saves))
+	}
+	if trashPath, _ := saves[0].Arg("trash_path"); trashPath != person.TrashPath {
+		t.Errorf("saved trash_path = %v, want %q", trashPath, person.TrashPath)
+	}
+	if len(fake.Statements(`^UPDATE "people" SET "deleted_at"`)) != 1 {
+		t.Errorf("TrashPerson() did not soft delete the person")
+	}
+	if len(fake.Statements(`^COMMIT`)) != 1 {
+		t.Errorf("TrashPerson() did not commit")
+	}
+}
+
+func TestTrashPersonRollback(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Fail(`^INSERT INTO "versions"`, errors.New("versions are not writable"))
+	person := models.Person{ID: 5, FullName: "Иванов Иван"}
+	docsPath := docsFolder(t, &person)
+
+	if err := TrashPerson(db, &person, nil); err == nil {
+		t.Fatal("TrashPerson() returned no error")
+	}
+	if _, err := os.Stat(filepath.Join(docsPath, "anketa.json")); err != nil {
+		t.Errorf("documents left their folder after a failed delete: %v", err)
+	}
+	if entries, _ := os.ReadDir(TrashPath()); len(entries) != 0 {
+		t.Errorf("trash has %d entries after a failed delete", len(entries))
+	}
+	if person.TrashPath != "" || person.DeletedBy != "" {
+		t.Errorf("person keeps trash path %q, deleted by %q", person.TrashPath, person.DeletedBy)
+	}
+	if len(fake.Statements(`^ROLLBACK`)) != 1 {
+		t.Errorf("TrashPerson() did not roll back")
+	}
+}
+
+func TestRestorePerson(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`SELECT \* FROM "people" WHERE "people"."id" = \$1`,
+		[]string{"id", "full_name"},
+		[]driver.Value{int64(5), "Иванов Иван"},
+	)
+	person := models.Person{ID: 5, FullName: "Иванов Иван"}
+	docsPath := docsFolder(t, &person)
+	if err := TrashPerson(db, &person, nil); err != nil {
+		t.Fatal(err)
+	}
+	trashPath := person.TrashPath
+
+	if err := RestorePerson(db, &person, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(docsPath, "anketa.json")); err != nil {
+		t.Errorf("documents are not back in their folder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(TrashPath(), trashPath)); !os.IsNotExist(err) {
+		t.Errorf("trash still has the folder")
+	}
+	restores := fake.Statements(`^UPDATE "people" SET "deleted_at"=\$1,"deleted_by"=\$2,"trash_path"=\$3`)
+	if len(restores) != 1 {
+		t.Fatalf("RestorePerson() updated the person %d times, want once", len(restores))
+	}
+	if deletedAt, _ := restores[0].Arg("deleted_at"); deletedAt != nil {
+		t.Errorf("deleted_at = %v, want NULL", deletedAt)
+	}
+	if versions := fake.Statements(`^INSERT INTO "versions"`); len(versions) != 2 {
+		t.Errorf("trash and restore recorded %d versions, want 2", len(versions))
+	}
+}
+
+func TestRestorePersonRollback(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{ID: 5, FullName: "Иванов Иван"}
+	docsPath := docsFolder(t, &person)
+	if err := TrashPerson(db, &person, nil); err != nil {
+		t.Fatal(err)
+	}
+	trashPath := person.TrashPath
+
+	// The person is not found after the update, so the restore fails.
+	if err := RestorePerson(db, &person, nil); err == nil {
+		t.Fatal("RestorePerson() returned no error")
+	}
+	if _, err := os.Stat(filepath.Join(TrashPath(), trashPath, "anketa.json")); err != nil {
+		t.Errorf("documents left the trash after a failed restore: %v", err)
+	}
+	if _, err := os.Stat(docsPath); !os.IsNotExist(err) {
+		t.Errorf("documents folder was restored after a failed restore")
+	}
+	if len(fake.Statements(`^ROLLBACK`)) != 1 {
+		t.Errorf("RestorePerson() did not roll back")
+	}
+}
+
+func TestRestorePersonFolderTaken(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{ID: 5, FullName: "Иванов Иван", TrashPath: "5-20240101000000"}
+	docsFolder(t, &person)
+
+	if err := RestorePerson(db, &person, nil); err == nil {
+		t.Fatal("RestorePerson() returned no error for an existing folder")
+	}
+	if statements := fake.Statements(""); len(statements) != 0 {
+		t.Errorf("RestorePerson() ran %d statements", len(statements))
+	}
+}
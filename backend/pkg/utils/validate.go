@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/app/models"
+)
+
+// FieldErrors maps a field name to the reason it failed validation.
+type FieldErrors map[string]string
+
+const PassportView = "Паспорт РФ"
+
+var (
+	passportSeriesRe = regexp.MustCompile(`^\d{4}$`)
+	passportNumberRe = regexp.MustCompile(`^\d{6}$`)
+	departmentCodeRe = regexp.MustCompile(`^\d{3}-\d{3}$`)
+	dateLayouts      = []string{"2006-01-02", "02.01.2006", "2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05"}
+	monthLayouts     = []string{"2006-01", "01.2006", "01/2006"}
+)
+
+// NormalizeDigits removes spaces and dashes that are used to group digits.
+func NormalizeDigits(value string) string {
+	return strings.NewReplacer(" ", "", "-", "", "\u00a0", "").Replace(strings.TrimSpace(value))
+}
+
+// ParseDate parses a date in one of the formats used by anketas and the UI.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// ParsePeriodDate parses a date of an employment period, which anketas often
+// give to the month or to the year only. With end set such a date is rounded
+// to the last day of the month or the year.
+func ParsePeriodDate(value string, end bool) (time.Time, error) {
+	if date, err := ParseDate(value); err == nil {
+		return date, nil
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range monthLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			if end {
+				date = date.AddDate(0, 1, -1)
+			}
+			return date, nil
+		}
+	}
+	date, err := time.Parse("2006", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	if end {
+		date = date.AddDate(1, 0, -1)
+	}
+	return date, nil
+}
+
+// ValidateInn checks the control digits of a 10-digit company or 12-digit personal INN.
+func ValidateInn(inn string) error {
+	digits, ok := toDigits(inn)
+	if !ok {
+		return errors.New("ИНН должен состоять из цифр")
+	}
+	switch len(digits) {
+	case 10:
+		if checksum(digits[:9], []int{2, 4, 10, 3, 5, 9, 4, 6, 8}) != digits[9] {
+			return errors.New("неверное контрольное число ИНН")
+		}
+	case 12:
+		if checksum(digits[:10], []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) != digits[10] ||
+			checksum(digits[:11], []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) != digits[11] {
+			return errors.New("неверное контрольное число ИНН")
+		}
+	default:
+		return errors.New("ИНН должен содержать 10 или 12 цифр")
+	}
+	return nil
+}
+
+// ValidateSnils checks the control number of a SNILS.
+func ValidateSnils(snils string) error {
+	digits, ok := toDigits(snils)
+	if !ok || len(digits) != 11 {
+		return errors.New("СНИЛС должен содержать 11 цифр")
+	}
+	number := 0
+	sum := 0
+	for i := 0; i < 9; i++ {
+		number = number*10 + digits[i]
+		sum += digits[i] * (9 - i)
+	}
+	// Control numbers are only defined for numbers above 001-001-998.
+	if number <= 1001998 {
+		return nil
+	}
+	control := sum % 101
+	if control == 100 {
+		control = 0
+	}
+	if control != digits[9]*10+digits[10] {
+		return errors.New("неверное контрольное число СНИЛС")
+	}
+	return nil
+}
+
+func ValidatePassportSeries(series string) error {
+	if !passportSeriesRe.MatchString(series) {
+		return errors.New("серия паспорта должна содержать 4 цифры")
+	}
+	return nil
+}
+
+func ValidatePassportNumber(number string) error {
+	if !passportNumberRe.MatchString(number) {
+		return errors.New("номер паспорта должен содержать 6 цифр")
+	}
+	return nil
+}
+
+func ValidateDepartmentCode(code string) error {
+	if !departmentCodeRe.MatchString(code) {
+		return errors.New("код подразделения должен иметь формат 000-000")
+	}
+	return nil
+}
+
+// ValidatePastDate checks that the date is not in the future.
+func ValidatePastDate(date time.Time) error {
+	if date.After(time.Now()) {
+		return errors.New("дата не может быть в будущем")
+	}
+	return nil
+}
+
+// ValidateBirthDate checks that the birth date gives an age between 14 and 100 years.
+func ValidateBirthDate(date time.Time) error {
+	now := time.Now()
+	if date.After(now.AddDate(-14, 0, 0)) || date.Before(now.AddDate(-100, 0, 0)) {
+		return errors.New("неправдоподобная дата рождения")
+	}
+	return nil
+}
+
+// ValidatePerson normalizes and validates identifiers and dates of the person.
+func ValidatePerson(person *models.Person) FieldErrors {
+	errs := FieldErrors{}
+	if person.Inn != "" {
+		person.Inn = NormalizeDigits(person.Inn)
+		errs.add("inn", ValidateInn(person.Inn))
+	}
+	if person.Snils != "" {
+		person.Snils = NormalizeDigits(person.Snils)
+		errs.add("snils", ValidateSnils(person.Snils))
+	}
+	if !person.BirthDate.IsZero() {
+		errs.add("birthday", ValidateBirthDate(person.BirthDate.Time))
+	}
+	return errs
+}
+
+// ValidateDocument normalizes and validates a document, passport formats are
+// only enforced for the Russian passport.
+func ValidateDocument(document *models.Document) FieldErrors {
+	errs := FieldErrors{}
+	if document.View == PassportView {
+		if document.Series != "" {
+			document.Series = NormalizeDigits(document.Series)
+			errs.add("series", ValidatePassportSeries(document.Series))
+		}
+		if document.Number != "" {
+			document.Number = NormalizeDigits(document.Number)
+			errs.add("number", ValidatePassportNumber(document.Number))
+		}
+		if document.Code != "" {
+			document.Code = strings.TrimSpace(document.Code)
+			errs.add("code", ValidateDepartmentCode(document.Code))
+		}
+	}
+	if !document.Issue.IsZero() {
+		errs.add("issue", ValidatePastDate(document.Issue))
+	}
+	if document.Expiry != nil && !document.Issue.IsZero() && document.Expiry.Before(document.Issue) {
+		errs["expiry"] = "срок действия истекает раньше даты выдачи"
+	}
+	return errs
+}
+
+// ValidateWorkplace checks the employment period. The end date of the
+// current job is cleared.
+func ValidateWorkplace(workplace *models.Workplace) FieldErrors {
+	errs := FieldErrors{}
+	if workplace.Current {
+		workplace.EndDate = models.Date{}
+	}
+	if !workplace.StartDate.IsZero() {
+		errs.add("start_date", ValidatePastDate(workplace.StartDate.Time))
+	}
+	if !workplace.StartDate.IsZero() && !workplace.EndDate.IsZero() && workplace.EndDate.Before(workplace.StartDate.Time) {
+		errs["end_date"] = "дата увольнения раньше даты приема"
+	}
+	return errs
+}
+
+// ValidateNameChange normalizes a previous name and checks the year of the
+// change.
+func ValidateNameChange(change *models.NameChange) FieldErrors {
+	errs := FieldErrors{}
+	NormalizeNameChange(change)
+	if change.FullName == "" {
+		errs["fullname"] = "не указано прежнее имя"
+	}
+	if change.Year != 0 && (change.Year < 1900 || change.Year > time.Now().Year()) {
+		errs["year"] = "неверный год изменения"
+	}
+	return errs
+}
+
+// ValidateEducation checks the years of study.
+func ValidateEducation(education *models.Education) FieldErrors {
+	errs := FieldErrors{}
+	education.View = strings.TrimSpace(education.View)
+	education.Institution = strings.TrimSpace(education.Institution)
+	maxYear := time.Now().Year() + 10
+	if education.BeginYear != 0 && (education.BeginYear < 1900 || education.BeginYear > maxYear) {
+		errs["begin_year"] = "неверный год начала обучения"
+	}
+	if education.EndYear != 0 && (education.EndYear < 1900 || education.EndYear > maxYear) {
+		errs["end_year"] = "неверный год окончания обучения"
+	}
+	if education.BeginYear != 0 && education.EndYear != 0 && education.EndYear < education.BeginYear {
+		errs["end_year"] = "год окончания раньше года начала"
+	}
+	return errs
+}
+
+func ValidateAffilation(affilation *models.Affilation) FieldErrors {
+	errs := FieldErrors{}
+	if affilation.Inn != "" {
+		affilation.Inn = NormalizeDigits(affilation.Inn)
+		errs.add("inn", ValidateInn(affilation.Inn))
+	}
+	return errs
+}
+
+// ValidateAnketa validates the parsed anketa with the same rules as the REST handlers.
+func ValidateAnketa(anketa Anketa) FieldErrors {
+	errs := FieldErrors{}
+
+	person := models.Person{
+		Inn:   anketa.Resume["inn"],
+		Snils: anketa.Resume["snils"],
+	}
+	birthday, err := ParseDate(anketa.Resume["birthday"])
+	if err != nil {
+		errs.add("resume.birthday", errors.New("неверный формат даты"))
+	}
+	person.BirthDate = models.NewDate(birthday)
+	errs.merge("resume", ValidatePerson(&person))
+	anketa.Resume["inn"], anketa.Resume["snils"] = person.Inn, person.Snils
+
+	document := models.Document{
+		View:   anketa.Document["view"],
+		Series: anketa.Document["series"],
+		Number: anketa.Document["number"],
+	}
+	if anketa.Document["issue"] != "" {
+		issue, err := ParseDate(anketa.Document["issue"])
+		if err != nil {
+			errs.add("document.issue", errors.New("неверный формат даты"))
+		}
+		document.Issue = issue
+	}
+	errs.merge("document", ValidateDocument(&document))
+	anketa.Document["series"], anketa.Document["number"] = document.Series, document.Number
+
+	for i, item := range anketa.Affilations {
+		affilation := models.Affilation{Inn: item["inn"]}
+		errs.merge(fmt.Sprintf("affilations[%d]", i), ValidateAffilation(&affilation))
+		item["inn"] = affilation.Inn
+	}
+	return errs
+}
+
+func (errs FieldErrors) add(field string, err error) {
+	if err != nil {
+		errs[field] = err.Error()
+	}
+}
+
+func (errs FieldErrors) merge(prefix string, other FieldErrors) {
+	for field, msg := range other {
+		errs[prefix+"."+field] = msg
+	}
+}
+
+func toDigits(value string) ([]int, bool) {
+	digits := make([]int, 0, len(value))
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return nil, false
+		}
+		digits = append(digits, int(r-'0'))
+	}
+	return digits, len(digits) > 0
+}
+
+func checksum(digits []int, weights []int) int {
+	sum := 0
+	for i, weight := range weights {
+		sum += digits[i] * weight
+	}
+	return sum % 11 % 10
+}
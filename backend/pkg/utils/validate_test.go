@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateInn(t *testing.T) {
+	tests := []struct {
+		inn   string
+		valid bool
+	}{
+		{"7707083893", true},
+		{"7707083894", false},
+		{"500100732259", true},
+		{"500100732258", false},
+		{"500100732269", false},
+		{"123456789047", true},
+		{"123456789012", false},
+		{"12345", false},
+		{"77070838931", false},
+		{"77070A3893", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if err := ValidateInn(test.inn); (err == nil) != test.valid {
+			t.Errorf("ValidateInn(%q) = %v, want valid %v", test.inn, err, test.valid)
+		}
+	}
+}
+
+func TestValidateSnils(t *testing.T) {
+	tests := []struct {
+		snils string
+		valid bool
+	}{
+		{"11223344595", true},
+		{"11223344596", false},
+		{"12345678964", true},
+		{"12345678901", false},
+		{"00100199800", true},
+		{"00100199812", true},
+		{"00100199965", true},
+		{"00100199900", false},
+		{"1122334459", false},
+		{"112233445950", false},
+		{"1122334459A", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if err := ValidateSnils(test.snils); (err == nil) != test.valid {
+			t.Errorf("ValidateSnils(%q) = %v, want valid %v", test.snils, err, test.valid)
+		}
+	}
+}
+
+func TestValidatePassportFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) error
+		value string
+		valid bool
+	}{
+		{"series", ValidatePassportSeries, "4510", true},
+		{"series", ValidatePassportSeries, "451", false},
+		{"series", ValidatePassportSeries, "45 10", false},
+		{"series", ValidatePassportSeries, "45AB", false},
+		{"number", ValidatePassportNumber, "123456", true},
+		{"number", ValidatePassportNumber, "12345", false},
+		{"number", ValidatePassportNumber, "1234567", false},
+		{"department code", ValidateDepartmentCode, "770-001", true},
+		{"department code", ValidateDepartmentCode, "770001", false},
+		{"department code", ValidateDepartmentCode, "770-01", false},
+		{"department code", ValidateDepartmentCode, "77O-001", false},
+	}
+	for _, test := range tests {
+		if err := test.check(test.value); (err == nil) != test.valid {
+			t.Errorf("%s %q: error %v, want valid %v", test.name, test.value, err, test.valid)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		valid bool
+	}{
+		{"1990-05-17", time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), true},
+		{"17.05.1990", time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), true},
+		{" 17.05.1990 ", time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), true},
+		{"1990-05-17T00:00:00Z", time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), true},
+		{"1990-05-17 10:30:00", time.Date(1990, 5, 17, 10, 30, 0, 0, time.UTC), true},
+		{"17/05/1990", time.Time{}, false},
+		{"31.02.1990", time.Time{}, false},
+		{"05.1990", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseDate(%q) error = %v, want valid %v", test.value, err, test.valid)
+			continue
+		}
+		if test.valid && !got.Equal(test.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
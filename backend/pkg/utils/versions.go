@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// VersionModels maps a versioned item name to a constructor of its model.
+var VersionModels map[string]func() interface{} = map[string]func() interface{}{
+	"person":        func() interface{} { return &models.Person{} },
+	"staff":         func() interface{} { return &models.Staff{} },
+	"document":      func() interface{} { return &models.Document{} },
+	"address":       func() interface{} { return &models.Address{} },
+	"contact":       func() interface{} { return &models.Contact{} },
+	"workplace":     func() interface{} { return &models.Workplace{} },
+	"education":     func() interface{} { return &models.Education{} },
+	"name_change":   func() interface{} { return &models.NameChange{} },
+	"affilation":    func() interface{} { return &models.Affilation{} },
+	"relation":      func() interface{} { return &models.Relation{} },
+	"check":         func() interface{} { return &models.Check{} },
+	"robot":         func() interface{} { return &models.Robot{} },
+	"poligraf":      func() interface{} { return &models.Poligraf{} },
+	"investigation": func() interface{} { return &models.Investigation{} },
+	"inquiry":       func() interface{} { return &models.Inquiry{} },
+	"person_tag":    func() interface{} { return &models.PersonTag{} },
+}
+
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// RecordVersion stores the diff between two states of a record. A nil before
+// means the record was created and a nil after means it was deleted.
+func RecordVersion(db *gorm.DB, item string, before interface{}, after interface{}, user *middlewares.TokenMetadata) error {
+	return recordVersion(db, item, "", before, after, user)
+}
+
+func recordVersion(db *gorm.DB, item string, action string, before interface{}, after interface{}, user *middlewares.TokenMetadata) error {
+	current := after
+	if action == "" {
+		switch {
+		case isNil(before):
+			action = "create"
+		case isNil(after):
+			action = "delete"
+			current = before
+		default:
+			action = "update"
+		}
+	}
+
+	oldFields, newFields := fieldsMap(before), fieldsMap(after)
+	diff := Diff(oldFields, newFields)
+	if action == "update" && len(diff) == 0 {
+		return nil
+	}
+
+	diffJson, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(fieldsMap(current))
+	if err != nil {
+		return err
+	}
+
+	version := models.Version{
+		Item:     item,
+		Action:   action,
+		Diff:     string(diffJson),
+		Snapshot: string(snapshot),
+		UserName: "system",
+	}
+	version.RecordID, version.PersonID = recordIDs(item, current)
+	if user != nil {
+		version.UserID = user.UserID
+		version.UserName = user.FullName
+	}
+	if err := db.Create(&version).Error; err != nil {
+		return err
+	}
+	if item == "contact" || item == "address" {
+		if err := IndexContactPoints(db, version.PersonID); err != nil {
+			return err
+		}
+	}
+	return NotifyWatchers(db, version.PersonID, item, action, user)
+}
+
+// Diff returns the changed fields between two field maps.
+func Diff(before map[string]interface{}, after map[string]interface{}) map[string]Change {
+	diff := map[string]Change{}
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			diff[key] = Change{Old: before[key], New: value}
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			diff[key] = Change{Old: value, New: nil}
+		}
+	}
+	return diff
+}
+
+// RevertVersion restores the record to the state stored in the version. The
+// status of a person is left as it is, since it only changes through the
+// workflow.
+func RevertVersion(db *gorm.DB, version models.Version, user *middlewares.TokenMetadata) error {
+	newModel, ok := VersionModels[version.Item]
+	if !ok {
+		return fmt.Errorf("unknown item %q", version.Item)
+	}
+
+	current := newModel()
+	err := db.First(current, version.RecordID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var before interface{}
+	if err == nil {
+		before = current
+	}
+
+	restored := newModel()
+	if err := json.Unmarshal([]byte(version.Snapshot), restored); err != nil {
+		return err
+	}
+	if person, ok := restored.(*models.Person); ok {
+		var stored models.Person
+		err := db.Unscoped().Select("id", "status_id").First(&stored, version.RecordID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if stored.ID != 0 {
+			person.StatusID = stored.StatusID
+		}
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(restored).Error; err != nil {
+			return err
+		}
+		return recordVersion(tx, version.Item, "revert", before, restored, user)
+	})
+}
+
+// fieldsMap converts a model into its plain json fields without associations.
+func fieldsMap(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if isNil(value) {
+		return fields
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	for key, field := range fields {
+		switch field.(type) {
+		case nil, []interface{}, map[string]interface{}:
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+func recordIDs(item string, value interface{}) (uint, uint) {
+	model := reflect.Indirect(reflect.ValueOf(value))
+	recordID := uint(model.FieldByName("ID").Uint())
+	if item == "person" {
+		return recordID, recordID
+	}
+	personID := model.FieldByName("PersonID")
+	if !personID.IsValid() {
+		return recordID, 0
+	}
+	return recordID, uint(personID.Uint())
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+	"backend/platform/encryption"
+)
+
+func TestDiff(t *testing.T) {
+	before := fieldsMap(&models.Contact{ID: 1, View: "Телефон", Contact: "111", PersonID: 7})
+	after := fieldsMap(&models.Contact{ID: 1, View: "Телефон", Contact: "222", PersonID: 7})
+	diff := Diff(before, after)
+	if len(diff) != 1 {
+		t.Fatalf("Diff() = %v, want only the contact changed", diff)
+	}
+	if change := diff["contact"]; change.Old != "111" || change.New != "222" {
+		t.Errorf("Diff()[contact] = %v, want 111 -> 222", change)
+	}
+
+	created := Diff(fieldsMap(nil), after)
+	if change, ok := created["contact"]; !ok || change.Old != nil {
+		t.Errorf("Diff() of a new record = %v, want every field added", created)
+	}
+	deleted := Diff(before, fieldsMap(nil))
+	if change, ok := deleted["view"]; !ok || change.New != nil {
+		t.Errorf("Diff() of a deleted record = %v, want every field removed", deleted)
+	}
+}
+
+func TestRecordIDs(t *testing.T) {
+	tests := []struct {
+		item     string
+		value    interface{}
+		recordID uint
+		personID uint
+	}{
+		{"person", &models.Person{ID: 5}, 5, 5},
+		{"contact", &models.Contact{ID: 3, PersonID: 5}, 3, 5},
+		{"person_tag", &models.PersonTag{ID: 2, PersonID: 5}, 2, 5},
+	}
+	for _, test := range tests {
+		recordID, personID := recordIDs(test.item, test.value)
+		if recordID != test.recordID || personID != test.personID {
+			t.Errorf("recordIDs(%q) = %d, %d, want %d, %d",
+				test.item, recordID, personID, test.recordID, test.personID)
+		}
+	}
+}
+
+func TestRevertVersionKeepsStatus(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`SELECT \* FROM "people" WHERE "people"."id" = \$1`,
+		[]string{"id", "full_name", "status_id"},
+		[]driver.Value{int64(5), "Петров Петр", int64(9)},
+	)
+	fake.Answer(`SELECT "id","status_id" FROM "people"`,
+		[]string{"id", "status_id"},
+		[]driver.Value{int64(5), int64(9)},
+	)
+
+	snapshot, _ := json.Marshal(models.Person{ID: 5, FullName: "Иванов Иван", StatusID: 2})
+	version := models.Version{Item: "person", RecordID: 5, PersonID: 5, Snapshot: string(snapshot)}
+	user := &middlewares.TokenMetadata{UserID: 3, FullName: "Проверяющий"}
+	if err := RevertVersion(db, version, user); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := fake.Statements(`^UPDATE "people"`)
+	if len(updates) != 1 {
+		t.Fatalf("RevertVersion() updated the person %d times, want once", len(updates))
+	}
+	if name, _ := updates[0].Arg("full_name"); name != "Иванов Иван" {
+		t.Errorf("full_name = %v, want the value of the version", name)
+	}
+	if status, _ := updates[0].Arg("status_id"); status != int64(9) {
+		t.Errorf("status_id = %v, want the current status 9", status)
+	}
+
+	inserts := fake.Statements(`^INSERT INTO "versions"`)
+	if len(inserts) != 1 {
+		t.Fatalf("RevertVersion() recorded %d versions, want 1", len(inserts))
+	}
+	if action, _ := inserts[0].Arg("action"); action != "revert" {
+		t.Errorf("version action = %v, want revert", action)
+	}
+	stored, _ := inserts[0].Arg("diff")
+	diff, err := encryption.Decrypt(stored.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := map[string]Change{}
+	json.Unmarshal([]byte(diff), &changes)
+	if _, ok := changes["status_id"]; ok {
+		t.Errorf("revert diff %s changes the status", diff)
+	}
+	if len(fake.Statements(`^COMMIT`)) != 1 {
+		t.Errorf("RevertVersion() did not commit its transaction")
+	}
+}
+
+func TestRevertVersionUnknownItem(t *testing.T) {
+	db, fake := newFakeDb(t)
+	if err := RevertVersion(db, models.Version{Item: "unknown", RecordID: 1}, nil); err == nil {
+		t.Errorf("RevertVersion() returned no error for an unknown item")
+	}
+	if statements := fake.Statements(""); len(statements) != 0 {
+		t.Errorf("RevertVersion() ran %d statements for an unknown item", len(statements))
+	}
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// WatchItems names the changed records in watch notifications.
+var WatchItems map[string]string = map[string]string{
+	"person":        "анкета",
+	"staff":         "должность",
+	"document":      "документ",
+	"address":       "адрес",
+	"contact":       "контакт",
+	"workplace":     "работа",
+	"education":     "образование",
+	"name_change":   "смена имени",
+	"affilation":    "аффилированность",
+	"relation":      "связь",
+	"check":         "проверка",
+	"robot":         "робот",
+	"poligraf":      "полиграф",
+	"investigation": "расследование",
+	"inquiry":       "запрос",
+	"approval":      "согласование",
+	"report":        "отчет",
+	"person_tag":    "метка",
+	"file":          "файл",
+	"comment":       "комментарий",
+}
+
+// watchMaxDelay limits how long a steady stream of changes can postpone the
+// notification.
+const watchMaxDelay = time.Hour
+
+// NotifyWatchers queues a change of the person for its watchers.
+func NotifyWatchers(db *gorm.DB, personID uint, item string, action string, user *middlewares.TokenMetadata) error {
+	if personID == 0 {
+		return nil
+	}
+	var count int64
+	db.Model(&models.Watch{}).Where("person_id = ?", personID).Count(&count)
+	if count == 0 {
+		return nil
+	}
+
+	event := models.WatchEvent{PersonID: personID, Item: item, Action: action, UserName: "system"}
+	if user != nil {
+		event.UserID = user.UserID
+		event.UserName = user.FullName
+	}
+	return db.Create(&event).Error
+}
+
+// FlushWatchEvents collapses the queued changes of every person into one
+// message per watcher once the changes stop for WATCH_QUIET_MINUTES.
+func FlushWatchEvents(db *gorm.DB) (int, error) {
+	quiet, err := strconv.Atoi(os.Getenv("WATCH_QUIET_MINUTES"))
+	if err != nil || quiet < 0 {
+		quiet = 10
+	}
+
+	personIDs := []uint{}
+	err = db.
+		Model(&models.WatchEvent{}).
+		Group("person_id").
+		Having(
+			"MAX(created_at) < ? OR MIN(created_at) < ?",
+			time.Now().Add(-time.Duration(quiet)*time.Minute),
+			time.Now().Add(-watchMaxDelay),
+		).
+		Pluck("person_id", &personIDs).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, personID := range personIDs {
+		var events []models.WatchEvent
+		db.Where("person_id = ?", personID).Order("id").Find(&events)
+		if len(events) == 0 {
+			continue
+		}
+		var watches []models.Watch
+		db.Where("person_id = ?", personID).Find(&watches)
+
+		var person models.Person
+		db.Unscoped().First(&person, personID)
+
+		for _, watch := range watches {
+			content := watchSummary(events, watch.UserID)
+			if content == "" {
+				continue
+			}
+			db.Create(&models.Message{
+				Title:          "Изменения: " + person.FullName,
+				MessageContent: content,
+				StatusRead:     "new",
+				UserID:         watch.UserID,
+			})
+			sent++
+		}
+		db.Where("person_id = ? AND id <= ?", personID, events[len(events)-1].ID).Delete(&models.WatchEvent{})
+	}
+	return sent, nil
+}
+
+// watchSummary counts the changes made by others than the watcher per item.
+func watchSummary(events []models.WatchEvent, userID uint) string {
+	counts := map[string]int{}
+	total := 0
+	for _, event := range events {
+		if event.UserID == userID {
+			continue
+		}
+		name, ok := WatchItems[event.Item]
+		if !ok {
+			name = event.Item
+		}
+		counts[name]++
+		total++
+	}
+	if total == 0 {
+		return ""
+	}
+
+	parts := []string{}
+	for name, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, count))
+	}
+	sort.Strings(parts)
+	content := fmt.Sprintf("Изменений: %d — %s", total, strings.Join(parts, ", "))
+	if runes := []rune(content); len(runes) > 256 {
+		content = string(runes[:253]) + "..."
+	}
+	return content
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+func TestWatchSummary(t *testing.T) {
+	events := []models.WatchEvent{
+		{Item: "contact", UserID: 2},
+		{Item: "contact", UserID: 2},
+		{Item: "check", UserID: 3},
+		{Item: "custom", UserID: 2},
+	}
+	if summary := watchSummary(events, 1); summary != "Изменений: 4 — custom (1), контакт (2), проверка (1)" {
+		t.Errorf("watchSummary() = %q", summary)
+	}
+	if summary := watchSummary(events, 2); summary != "Изменений: 1 — проверка (1)" {
+		t.Errorf("watchSummary() = %q, want the own changes left out", summary)
+	}
+	if summary := watchSummary(events[2:3], 3); summary != "" {
+		t.Errorf("watchSummary() = %q, want nothing for own changes only", summary)
+	}
+}
+
+func TestNotifyWatchers(t *testing.T) {
+	db, fake := newFakeDb(t)
+	user := &middlewares.TokenMetadata{UserID: 3, FullName: "Проверяющий"}
+	if err := NotifyWatchers(db, 5, "contact", "update", user); err != nil {
+		t.Fatal(err)
+	}
+	if err := NotifyWatchers(db, 0, "contact", "update", user); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Statements(`^INSERT`)) != 0 {
+		t.Fatalf("NotifyWatchers() queued an event of a person without watchers")
+	}
+
+	fake.Answer(`SELECT count\(\*\) FROM "watches"`, []string{"count"}, []driver.Value{int64(2)})
+	if err := NotifyWatchers(db, 5, "contact", "update", user); err != nil {
+		t.Fatal(err)
+	}
+	inserts := fake.Statements(`^INSERT INTO "watch_events"`)
+	if len(inserts) != 1 {
+		t.Fatalf("NotifyWatchers() queued %d events, want 1", len(inserts))
+	}
+	for column, want := range map[string]driver.Value{"person_id": int64(5), "item": "contact", "user_name": "Проверяющий"} {
+		if value, _ := inserts[0].Arg(column); value != want {
+			t.Errorf("event %s = %v, want %v", column, value, want)
+		}
+	}
+}
+
+func TestFlushWatchEvents(t *testing.T) {
+	db, fake := newFakeDb(t)
+	fake.Answer(`SELECT "person_id" FROM "watch_events"`, []string{"person_id"}, []driver.Value{int64(5)})
+	fake.Answer(`SELECT \* FROM "watch_events"`, []string{"id", "person_id", "item", "user_id", "created_at"},
+		[]driver.Value{int64(10), int64(5), "contact", int64(2), time.Now()},
+		[]driver.Value{int64(11), int64(5), "check", int64(2), time.Now()},
+	)
+	fake.Answer(`FROM "watches"`, []string{"id", "person_id", "user_id"},
+		[]driver.Value{int64(1), int64(5), int64(1)},
+		[]driver.Value{int64(2), int64(5), int64(2)},
+	)
+	fake.Answer(personQuery, []string{"id", "full_name"}, []driver.Value{int64(5), "Иванов Иван"})
+
+	sent, err := FlushWatchEvents(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second watcher made every change and gets no message.
+	messages := fake.Statements(`^INSERT INTO "messages"`)
+	if sent != 1 || len(messages) != 1 {
+		t.Fatalf("FlushWatchEvents() sent %d messages, want 1", sent)
+	}
+	if userID, _ := messages[0].Arg("user_id"); userID != int64(1) {
+		t.Errorf("message user_id = %v, want the other watcher", userID)
+	}
+	if title, _ := messages[0].Arg("title"); title != "Изменения: Иванов Иван" {
+		t.Errorf("message title = %v", title)
+	}
+	deletes := fake.Statements(`^DELETE FROM "watch_events"`)
+	if len(deletes) != 1 || !strings.Contains(deletes[0].SQL, "id <= $2") || deletes[0].Args[1] != int64(11) {
+		t.Errorf("FlushWatchEvents() deletes = %v, want the events up to 11", deletes)
+	}
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+// Transitions maps a status key from Statuses to the keys a person may move to next.
+// The approval status is only entered by RequestApproval.
+var Transitions map[string][]string = map[string][]string{
+	"new":      {"manual", "robot", "finish", "update", "cancel"},
+	"repeat":   {"manual", "robot", "finish", "update", "cancel"},
+	"update":   {"manual", "robot", "finish", "cancel"},
+	"manual":   {"save", "poligraf", "finish", "update", "cancel"},
+	"save":     {"manual", "poligraf", "finish", "update", "cancel"},
+	"robot":    {"reply", "error", "update", "cancel"},
+	"reply":    {"manual", "finish", "update", "cancel"},
+	"poligraf": {"finish", "update", "cancel"},
+	"approval": {"finish", "manual", "save", "poligraf", "update", "cancel"},
+	"finish":   {"repeat", "update"},
+	"cancel":   {"repeat", "update"},
+	"error":    {"robot", "update", "cancel"},
+}
+
+type Transition struct {
+	Status string `json:"status"`
+	Name   string `json:"name"`
+}
+
+// StatusKey returns the Statuses key for a status id or an empty string.
+func StatusKey(statusID uint) string {
+	if statusID == 0 {
+		return ""
+	}
+	name := models.Status{}.GetName(statusID)
+	for key, value := range Statuses {
+		if value == name {
+			return key
+		}
+	}
+	return ""
+}
+
+// ConclusionKey returns the Conclusions key for a conclusion id or an empty
+// string.
+func ConclusionKey(conclusionID uint) string {
+	if conclusionID == 0 {
+		return ""
+	}
+	name := models.Conclusion{}.GetName(conclusionID)
+	for key, value := range Conclusions {
+		if value == name {
+			return key
+		}
+	}
+	return ""
+}
+
+// InterimConclusion reports whether the conclusion key leaves the check in
+// progress: no conclusion yet, saved as a draft or sent to the polygraph.
+func InterimConclusion(key string) bool {
+	return key == "" || key == "saved" || key == "pfo"
+}
+
+// CanTransit reports whether a person in status from may be moved to status to.
+// A person without a known status may only become new.
+func CanTransit(from string, to string) bool {
+	if _, ok := Statuses[to]; !ok {
+		return false
+	}
+	if from == "" {
+		return to == "new"
+	}
+	if from == to {
+		return true
+	}
+	for _, next := range Transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses lists the transitions available for the person's current status.
+func NextStatuses(person models.Person) []Transition {
+	transitions := []Transition{}
+	for _, next := range Transitions[StatusKey(person.StatusID)] {
+		transitions = append(transitions, Transition{Status: next, Name: Statuses[next]})
+	}
+	return transitions
+}
+
+// ChangeStatus moves the person to the status key and records the transition.
+func ChangeStatus(db *gorm.DB, person *models.Person, to string, user *middlewares.TokenMetadata, reason string) error {
+	from := StatusKey(person.StatusID)
+	if !CanTransit(from, to) {
+		return fmt.Errorf("transition from %q to %q is not allowed", from, to)
+	}
+	if from == to {
+		return nil
+	}
+	return moveStatus(db, person, from, to, user, reason)
+}
+
+func moveStatus(db *gorm.DB, person *models.Person, from string, to string, user *middlewares.TokenMetadata, reason string) error {
+	if to == "finish" {
+		if err := CheckApproved(db, *person); err != nil {
+			return err
+		}
+	}
+	if from == "approval" {
+		if err := WithdrawApprovals(db, person.ID); err != nil {
+			return err
+		}
+	}
+
+	statusID := models.Status{}.GetID(Statuses[to])
+	if statusID == 0 {
+		return fmt.Errorf("status %q is not in the database, run migrate", to)
+	}
+
+	before := *person
+	person.StatusID = statusID
+	if err := db.Save(person).Error; err != nil {
+		return err
+	}
+	if err := RecordVersion(db, "person", &before, person, user); err != nil {
+		return err
+	}
+
+	history := models.StatusHistory{
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		UserName:   "system",
+		PersonID:   person.ID,
+	}
+	if user != nil {
+		history.UserID = user.UserID
+		history.UserName = user.FullName
+	}
+	return db.Create(&history).Error
+}
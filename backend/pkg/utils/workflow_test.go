@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"backend/app/models"
+	"backend/pkg/middlewares"
+)
+
+func TestCanTransit(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"", "new", true},
+		{"", "manual", false},
+		{"", "finish", false},
+		{"", "unknown", false},
+		{"new", "manual", true},
+		{"new", "robot", true},
+		{"new", "new", true},
+		{"new", "reply", false},
+		{"new", "unknown", false},
+		{"robot", "reply", true},
+		{"robot", "finish", false},
+		{"manual", "save", true},
+		{"manual", "finish", true},
+		{"manual", "approval", false},
+		{"save", "approval", false},
+		{"reply", "approval", false},
+		{"poligraf", "approval", false},
+		{"approval", "approval", true},
+		{"approval", "finish", true},
+		{"approval", "manual", true},
+		{"finish", "repeat", true},
+		{"finish", "manual", false},
+		{"cancel", "finish", false},
+		{"error", "robot", true},
+		{"unknown", "manual", false},
+	}
+	for _, test := range tests {
+		if got := CanTransit(test.from, test.to); got != test.want {
+			t.Errorf("CanTransit(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestTransitionsKnownStatuses(t *testing.T) {
+	for from, targets := range Transitions {
+		if _, ok := Statuses[from]; !ok {
+			t.Errorf("Transitions has unknown status %q", from)
+		}
+		for _, to := range targets {
+			if _, ok := Statuses[to]; !ok {
+				t.Errorf("Transitions[%q] has unknown status %q", from, to)
+			}
+			if to == "approval" {
+				t.Errorf("Transitions[%q] allows approval, which is only entered by RequestApproval", from)
+			}
+		}
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	db, fake := newFakeDb(t)
+	person := models.Person{ID: 5, FullName: "Иванов Иван", StatusID: lookupID(Statuses, "manual")}
+	user := &middlewares.TokenMetadata{UserID: 3, FullName: "Проверяющий"}
+
+	if err := ChangeStatus(db, &person, "reply", user, ""); err == nil {
+		t.Errorf("ChangeStatus() moved the person from manual to reply")
+	}
+	if err := ChangeStatus(db, &person, "manual", user, ""); err != nil {
+		t.Errorf("ChangeStatus() to the same status = %v, want no error", err)
+	}
+	if len(fake.Statements(`^(UPDATE|INSERT)`)) != 0 {
+		t.Fatalf("ChangeStatus() changed the person without a transition")
+	}
+
+	if err := ChangeStatus(db, &person, "save", user, "Черновик"); err != nil {
+		t.Fatal(err)
+	}
+	if person.StatusID != lookupID(Statuses, "save") {
+		t.Errorf("ChangeStatus() status = %d, want save", person.StatusID)
+	}
+	saves := fake.Statements(`^UPDATE "people"`)
+	if status, _ := saves[0].Arg("status_id"); len(saves) != 1 || status != int64(person.StatusID) {
+		t.Errorf("ChangeStatus() saved status %v, want %d", status, person.StatusID)
+	}
+	histories := fake.Statements(`^INSERT INTO "status_histories"`)
+	if len(histories) != 1 {
+		t.Fatalf("ChangeStatus() recorded %d transitions, want 1", len(histories))
+	}
+	for column, want := range map[string]driver.Value{
+		"from_status": "manual", "to_status": "save", "reason": "Черновик", "user_name": "Проверяющий",
+	} {
+		if value, _ := histories[0].Arg(column); value != want {
+			t.Errorf("transition %s = %v, want %v", column, value, want)
+		}
+	}
+	if len(fake.Statements(`^INSERT INTO "versions"`)) != 1 {
+		t.Errorf("ChangeStatus() did not record the version")
+	}
+}
@@ -0,0 +1,284 @@
+// Package databasetest provides a fake database for tests of code that needs
+// one. The fake records the statements it receives and answers the queries
+// with the rows registered for them, so no server is needed.
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"backend/platform/database"
+)
+
+// Fake is a database answering with registered rows. Queries nobody answers
+// return no rows, inserts get new ids and other statements affect one row.
+type Fake struct {
+	mu         sync.Mutex
+	answers    []answer
+	failures   []failure
+	statements []Statement
+	lastID     int64
+}
+
+// Statement is a statement the fake received with its arguments. BEGIN,
+// COMMIT and ROLLBACK are recorded as statements too.
+type Statement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+var columnRe = regexp.MustCompile(`"(\w+)"=\$(\d+)`)
+
+// Arg returns the value the statement sets the column to, in the SET list of
+// an UPDATE or the column list of an INSERT of a single row.
+func (statement Statement) Arg(column string) (driver.Value, bool) {
+	for _, match := range columnRe.FindAllStringSubmatch(statement.SQL, -1) {
+		if match[1] == column {
+			n, _ := strconv.Atoi(match[2])
+			return statement.Args[n-1], true
+		}
+	}
+	if match := insertRe.FindStringSubmatch(statement.SQL); match != nil {
+		for i, name := range strings.Split(match[1], ",") {
+			if strings.Trim(name, `" `) == column && i < len(statement.Args) {
+				return statement.Args[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+type answer struct {
+	pattern *regexp.Regexp
+	columns []string
+	rows    func(args []driver.Value) [][]driver.Value
+}
+
+type failure struct {
+	pattern *regexp.Regexp
+	err     error
+}
+
+// New opens a connection to a new fake and makes database.OpenDb return it
+// until the end of the test.
+func New(t testing.TB) (*gorm.DB, *Fake) {
+	t.Helper()
+	fake := &Fake{}
+	db, err := gorm.Open(
+		postgres.New(postgres.Config{Conn: sql.OpenDB(connector{fake})}),
+		&gorm.Config{Logger: logger.Discard},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Override(db)
+	t.Cleanup(func() { database.Override(nil) })
+	return db, fake
+}
+
+// Answer registers the rows returned for the queries matching the pattern.
+// Answers registered later take precedence.
+func (fake *Fake) Answer(pattern string, columns []string, rows ...[]driver.Value) {
+	fake.AnswerFunc(pattern, columns, func([]driver.Value) [][]driver.Value { return rows })
+}
+
+// AnswerFunc registers a function building the rows for the queries matching
+// the pattern from their arguments.
+func (fake *Fake) AnswerFunc(pattern string, columns []string, rows func(args []driver.Value) [][]driver.Value) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.answers = append(fake.answers, answer{regexp.MustCompile(pattern), columns, rows})
+}
+
+// Fail makes the statements matching the pattern return the error.
+func (fake *Fake) Fail(pattern string, err error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.failures = append(fake.failures, failure{regexp.MustCompile(pattern), err})
+}
+
+// Statements returns the received statements matching the pattern in order.
+func (fake *Fake) Statements(pattern string) []Statement {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	re := regexp.MustCompile(pattern)
+	statements := []Statement{}
+	for _, statement := range fake.statements {
+		if re.MatchString(statement.SQL) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (fake *Fake) record(query string, args []driver.NamedValue) ([]driver.Value, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	fake.statements = append(fake.statements, Statement{query, values})
+	for i := len(fake.failures) - 1; i >= 0; i-- {
+		if fake.failures[i].pattern.MatchString(query) {
+			return values, fake.failures[i].err
+		}
+	}
+	return values, nil
+}
+
+func (fake *Fake) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := fake.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for i := len(fake.answers) - 1; i >= 0; i-- {
+		if answer := fake.answers[i]; answer.pattern.MatchString(query) {
+			return &rows{columns: answer.columns, rows: answer.rows(values)}, nil
+		}
+	}
+	if strings.HasPrefix(query, "INSERT") {
+		return fake.inserted(query, values), nil
+	}
+	return &rows{}, nil
+}
+
+var insertRe = regexp.MustCompile(`^INSERT INTO "?\w+"? \((.*?)\) VALUES .* RETURNING (.*)$`)
+
+// inserted returns the RETURNING columns of the inserted rows with new ids,
+// or the ids given in the statement.
+func (fake *Fake) inserted(query string, values []driver.Value) driver.Rows {
+	match := insertRe.FindStringSubmatch(query)
+	if match == nil {
+		return &rows{}
+	}
+	columns := strings.Split(match[1], ",")
+	given := -1
+	for i, column := range columns {
+		if strings.Trim(column, `" `) == "id" {
+			given = i
+		}
+	}
+	returning := strings.Split(match[2], ",")
+	for i := range returning {
+		returning[i] = strings.Trim(returning[i], `" `)
+	}
+
+	result := &rows{columns: returning}
+	for offset := 0; offset+len(columns) <= len(values); offset += len(columns) {
+		row := make([]driver.Value, len(returning))
+		for i, column := range returning {
+			if column != "id" {
+				continue
+			}
+			if given >= 0 {
+				row[i] = values[offset+given]
+			} else {
+				fake.lastID++
+				row[i] = fake.lastID
+			}
+		}
+		result.rows = append(result.rows, row)
+	}
+	return result
+}
+
+func (fake *Fake) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := fake.record(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type connector struct{ fake *Fake }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrSkip }
+
+type conn struct{ fake *Fake }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.fake, query}, nil }
+func (c conn) Close() error                              { return nil }
+
+func (c conn) Begin() (driver.Tx, error) {
+	c.fake.record("BEGIN", nil)
+	return tx(c), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.fake.query(query, args)
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.fake.exec(query, args)
+}
+
+type tx struct{ fake *Fake }
+
+func (t tx) Commit() error {
+	_, err := t.fake.record("COMMIT", nil)
+	return err
+}
+
+func (t tx) Rollback() error {
+	_, err := t.fake.record("ROLLBACK", nil)
+	return err
+}
+
+type stmt struct {
+	fake  *Fake
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.fake.exec(s.query, named(args))
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.fake.query(s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
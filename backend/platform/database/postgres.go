@@ -9,7 +9,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// override is returned by OpenDb instead of a new connection when set.
+var override *gorm.DB
+
+// Override makes OpenDb return db, so tests can run code that opens the
+// database itself. A nil db restores the connection from the environment.
+func Override(db *gorm.DB) {
+	override = db
+}
+
 func OpenDb() *gorm.DB {
+	if override != nil {
+		return override
+	}
 	dbhost := os.Getenv("DBHOST")
 	dbusr := os.Getenv("DBUSER")
 	dbpwd := os.Getenv("DBPASSWORD")
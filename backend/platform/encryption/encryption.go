@@ -0,0 +1,226 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// Prefix marks encrypted values. A stored value looks like
+// "enc:<key id>:<wrapped data key>:<ciphertext>" so that rows encrypted with
+// older master keys can still be read and found for rotation.
+const Prefix = "enc:"
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Keys returns the master keys from ENCRYPTION_KEYS, a comma separated list of
+// "id:secret" pairs. Every secret is stretched to an AES-256 key.
+func Keys() (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(os.Getenv("ENCRYPTION_KEYS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+		key := sha256.Sum256([]byte(secret))
+		keys[id] = key[:]
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("ENCRYPTION_KEYS is not set")
+	}
+	return keys, nil
+}
+
+// ActiveKey returns the id of the master key used for new values.
+func ActiveKey() (string, []byte, error) {
+	keys, err := Keys()
+	if err != nil {
+		return "", nil, err
+	}
+	id := os.Getenv("ENCRYPTION_KEY_ID")
+	key, ok := keys[id]
+	if !ok {
+		return "", nil, fmt.Errorf("encryption key %q is not configured", id)
+	}
+	return id, key, nil
+}
+
+// Encrypt seals the value with a fresh data key and wraps the data key with
+// the active master key. Empty values are stored as is.
+func Encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	id, master, err := ActiveKey()
+	if err != nil {
+		return "", err
+	}
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(master, dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + id + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without the prefix are
+// legacy plain text and returned unchanged.
+func Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, Prefix) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	keys, err := Keys()
+	if err != nil {
+		return "", err
+	}
+	master, ok := keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("encryption key %q is not configured", parts[0])
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := open(master, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(dataKey, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// BlindKey returns the key of the blind indexes from BLIND_INDEX_KEY.
+func BlindKey() ([]byte, error) {
+	key := os.Getenv("BLIND_INDEX_KEY")
+	if key == "" {
+		return nil, errors.New("BLIND_INDEX_KEY is not set")
+	}
+	return []byte(key), nil
+}
+
+// BlindIndex returns a keyed hash of the normalized value used for exact
+// match lookups on encrypted columns. The kind separates indexes of different
+// identifiers with equal values. Empty values have an empty index.
+func BlindIndex(kind string, value string) (string, error) {
+	value = normalize(value)
+	if value == "" {
+		return "", nil
+	}
+	key, err := BlindKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func normalize(value string) string {
+	value = strings.ToLower(value)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\u00a0', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func seal(key []byte, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(key []byte, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Serializer encrypts string fields tagged with `gorm:"serializer:encrypted"`.
+type Serializer struct{}
+
+// Scan implements schema.SerializerInterface.
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("cannot decrypt %T value", dbValue)
+	}
+	plain, err := Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plain)
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface.
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot encrypt %T value", field.Name, fieldValue)
+	}
+	return Encrypt(value)
+}
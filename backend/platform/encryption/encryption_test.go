@@ -0,0 +1,97 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEYS", "old:first,new:second")
+	t.Setenv("ENCRYPTION_KEY_ID", "old")
+	old, err := Encrypt("12345678901")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(old, Prefix+"old:") || strings.Contains(old, "12345678901") {
+		t.Fatalf("Encrypt() = %q, want a value sealed with key old", old)
+	}
+	again, _ := Encrypt("12345678901")
+	if again == old {
+		t.Errorf("Encrypt() returned the same value twice")
+	}
+
+	// Values of a former key are still read after rotation.
+	t.Setenv("ENCRYPTION_KEY_ID", "new")
+	for _, value := range []string{old, again} {
+		if plain, err := Decrypt(value); err != nil || plain != "12345678901" {
+			t.Errorf("Decrypt() = %q, %v, want the plain value", plain, err)
+		}
+	}
+	if value, _ := Encrypt("12345678901"); !strings.HasPrefix(value, Prefix+"new:") {
+		t.Errorf("Encrypt() = %q, want a value sealed with key new", value)
+	}
+
+	if value, err := Encrypt(""); value != "" || err != nil {
+		t.Errorf("Encrypt(\"\") = %q, %v, want an empty value", value, err)
+	}
+	if plain, err := Decrypt("legacy text"); plain != "legacy text" || err != nil {
+		t.Errorf("Decrypt() of plain text = %q, %v, want it unchanged", plain, err)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEYS", "old:first")
+	t.Setenv("ENCRYPTION_KEY_ID", "old")
+	value, err := Encrypt("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := value[:len(value)-2] + "AA"
+	if strings.HasSuffix(value, "AA") {
+		tampered = value[:len(value)-2] + "BB"
+	}
+
+	tests := []struct {
+		name  string
+		keys  string
+		value string
+	}{
+		{"malformed", "old:first", Prefix + "old:abc"},
+		{"unknown key", "other:first", value},
+		{"wrong secret", "old:second", value},
+		{"tampered", "old:first", tampered},
+		{"no keys", "", value},
+	}
+	for _, test := range tests {
+		t.Setenv("ENCRYPTION_KEYS", test.keys)
+		if _, err := Decrypt(test.value); err == nil {
+			t.Errorf("%s: Decrypt() returned no error", test.name)
+		}
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	t.Setenv("BLIND_INDEX_KEY", "first")
+	index, err := BlindIndex("snils", "112-233-445 95")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := BlindIndex("snils", "11223344595"); same != index {
+		t.Errorf("BlindIndex() differs for the same number in another format")
+	}
+	if other, _ := BlindIndex("inn", "11223344595"); other == index {
+		t.Errorf("BlindIndex() is equal for different kinds")
+	}
+	if empty, err := BlindIndex("snils", " - "); empty != "" || err != nil {
+		t.Errorf("BlindIndex() of an empty value = %q, %v, want an empty index", empty, err)
+	}
+
+	t.Setenv("BLIND_INDEX_KEY", "second")
+	if rotated, _ := BlindIndex("snils", "11223344595"); rotated == index {
+		t.Errorf("BlindIndex() does not depend on BLIND_INDEX_KEY")
+	}
+	t.Setenv("BLIND_INDEX_KEY", "")
+	if _, err := BlindIndex("snils", "11223344595"); err == nil {
+		t.Errorf("BlindIndex() returned no error without BLIND_INDEX_KEY")
+	}
+}